@@ -23,6 +23,7 @@ func main() {
 	lgr := logger.New(agentCfg.Logger.Level, os.Stdout)
 	lgr.Info(fmt.Sprintf("%+v", *agentCfg))
 
+	agent.BuildVersion = buildVersion
 	agent.Run(agentCfg, lgr)
 }
 
@@ -3,22 +3,50 @@
 package configs
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"net"
 	"os"
+	"os/signal"
+	"path"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
+	logger "github.com/vladislaoramos/alemetric/pkg/log"
 )
 
+// ErrInvalidConfig is returned by the validation of a config.
+var ErrInvalidConfig = errors.New("invalid config")
+
 // Config stores agent, server, and logger configurations.
 type Config struct {
-	Agent    `json:"agent" yaml:"agent"`
-	Server   `json:"server" yaml:"server"`
-	Logger   `yaml:"logger"`
-	Database `yaml:"database"`
+	Agent     `json:"agent" yaml:"agent"`
+	Server    `json:"server" yaml:"server"`
+	Logger    `yaml:"logger"`
+	Database  `yaml:"database"`
+	Reloading `yaml:"reloading"`
+
+	source *source
+}
+
+// Reloading stores the attributes of the config reloading.
+// The config is reloaded on SIGHUP and, if WatchInterval is not zero, on the modification of the JSON config file
+// checked every WatchInterval.
+type Reloading struct {
+	WatchInterval time.Duration `yaml:"watchInterval" env:"CONFIG_WATCH_INTERVAL"`
+}
+
+// source stores what the config is loaded from, so that it can be reloaded.
+type source struct {
+	app      string
+	jsonPath string
+	flags    *Config
 }
 
 // Logger stores the attribute of the logger level.
@@ -35,7 +63,7 @@ type Database struct {
 }
 
 // Agent stores the attributes of the agent.
-// Among them: Address, PollInterval, ReportInterval, RateLimit, Key.
+// Among them: Address, PollInterval, ReportInterval, RateLimit, Key, Collectors, Processes.
 // Attribute values are filled in from environment variables or flags.
 // If neither is specified, the default values are applied.
 type Agent struct {
@@ -43,22 +71,148 @@ type Agent struct {
 	PollInterval   time.Duration `json:"poll_interval" yaml:"pollInterval" env:"POLL_INTERVAL"`
 	ReportInterval time.Duration `json:"report_interval" yaml:"reportInterval" env:"REPORT_INTERVAL"`
 	ServerURL      string        `json:"address" yaml:"serverURL" env:"ADDRESS"`
-	MetricsNames   []string      `json:"metrics_names" yaml:"metricsNames"`
-	Key            string        `json:"key" env:"KEY"`
-	RateLimit      uint          `json:"rate_limit" env:"RATE_LIMIT" env-default:"1"`
-	CryptoKey      string        `json:"crypto_key" env:"CRYPTO_KEY"`
+
+	// MetricsNames is the allowlist of the metrics of the built-in collectors sent to the server,
+	// the runtime metrics by default. The other built-in metrics, e.g. of the host or the processes,
+	// are sent only if their names are added to it.
+	MetricsNames []string `json:"metrics_names" yaml:"metricsNames"`
+
+	Key       string `json:"key" env:"KEY"`
+	RateLimit uint   `json:"rate_limit" env:"RATE_LIMIT" env-default:"1"`
+	CryptoKey string `json:"crypto_key" env:"CRYPTO_KEY"`
+
+	// Collectors stores the settings of the collectors by their names.
+	Collectors map[string]Collector `json:"collectors" yaml:"collectors"`
+
+	// ChangesOnly sets the sending of the metrics changed by more than ChangeEpsilon only.
+	// All metrics are sent every HeartbeatReports reports anyway.
+	ChangesOnly      bool    `json:"changes_only" yaml:"changesOnly" env:"CHANGES_ONLY"`
+	ChangeEpsilon    float64 `json:"change_epsilon" yaml:"changeEpsilon" env:"CHANGE_EPSILON"`
+	HeartbeatReports uint    `json:"heartbeat_reports" yaml:"heartbeatReports" env:"HEARTBEAT_REPORTS"`
+
+	// GaugeAggregates lists the aggregates of the gauges over the report interval
+	// sent with the gauges: min, max, avg.
+	GaugeAggregates []string `json:"gauge_aggregates" yaml:"gaugeAggregates" env:"GAUGE_AGGREGATES" env-separator:","`
+
+	// Processes selects the processes monitored by the procstat collector.
+	Processes []Process `json:"processes" yaml:"processes"`
+
+	// StatsDAddress and StatsDSocket are the UDP address and the unix socket path
+	// the agent receives the StatsD metrics on. The listeners are not started if they are empty.
+	// The received metrics are sent regardless of MetricsNames.
+	StatsDAddress string `json:"statsd_address" yaml:"statsdAddress" env:"STATSD_ADDRESS"`
+	StatsDSocket  string `json:"statsd_socket" yaml:"statsdSocket" env:"STATSD_SOCKET"`
+
+	// PushAddress is the address of the local endpoint the applications push their metrics to,
+	// e.g. localhost:8081. The endpoint is not started if the address is empty.
+	// The endpoint has no authentication, so the address must be a loopback one.
+	// The pushed metrics are sent regardless of MetricsNames.
+	PushAddress string `json:"push_address" yaml:"pushAddress" env:"PUSH_ADDRESS"`
+
+	// StatusAddress is the address of the local /healthz and /status endpoints of the agent.
+	// The endpoints are not started if the address is empty.
+	StatusAddress string `json:"status_address" yaml:"statusAddress" env:"STATUS_ADDRESS"`
+
+	// Scrape lists the Prometheus endpoints scraped by the prometheus collector.
+	Scrape []ScrapeTarget `json:"scrape" yaml:"scrape"`
+
+	// Logs lists the log files followed by the logtail collector.
+	// The derived metrics are sent regardless of MetricsNames.
+	// LogOffsets is the file the offsets in the log files are saved to between the agent restarts.
+	Logs       []LogFile `json:"logs" yaml:"logs"`
+	LogOffsets string    `json:"log_offsets" yaml:"logOffsets" env:"LOG_OFFSETS"`
+
+	// Commands lists the commands run by the agent to collect metrics from their output.
+	// The metrics of the output and the ExecErrors counters are sent regardless of MetricsNames.
+	Commands []Command `json:"commands" yaml:"commands"`
+
+	// Group is the group of the agent the server selects the configuration of the agent by.
+	// RemoteConfigInterval is the interval the agent requests its configuration from the server with;
+	// the configuration is not requested if it is zero. The served poll and report intervals,
+	// metrics names and rate limit override the local ones.
+	Group                string        `json:"group" yaml:"group" env:"AGENT_GROUP"`
+	RemoteConfigInterval time.Duration `json:"remote_config_interval" yaml:"remoteConfigInterval" env:"REMOTE_CONFIG_INTERVAL"`
+}
+
+// Command is a shell command printing metrics to stdout.
+// The command is run every interval, by default every poll interval, and is killed after the timeout.
+type Command struct {
+	Name     string        `json:"name" yaml:"name"`
+	Command  string        `json:"command" yaml:"command"`
+	Interval time.Duration `json:"interval" yaml:"interval"`
+	Timeout  time.Duration `json:"timeout" yaml:"timeout"`
+}
+
+// LogFile stores the path of a followed log file and the rules deriving metrics from its lines.
+type LogFile struct {
+	Path  string    `json:"path" yaml:"path"`
+	Rules []LogRule `json:"rules" yaml:"rules"`
+}
+
+// LogRule derives metrics from the log lines matching the pattern:
+// Counter is incremented on every matching line, Gauge is set to the number from the capture group.
+type LogRule struct {
+	Pattern string `json:"pattern" yaml:"pattern"`
+	Counter string `json:"counter" yaml:"counter"`
+	Gauge   string `json:"gauge" yaml:"gauge"`
+}
+
+// ScrapeTarget stores the settings of a scraped Prometheus endpoint.
+// Allow lists the glob patterns of the scraped metrics names; with the empty list all metrics are scraped.
+// The scraped metrics are sent regardless of the metrics names of the agent.
+// Rename maps the scraped names to the names the metrics are sent with.
+// Labels are added to the labels of all metrics of the endpoint.
+type ScrapeTarget struct {
+	URL    string            `json:"url" yaml:"url"`
+	Allow  []string          `json:"allow" yaml:"allow"`
+	Rename map[string]string `json:"rename" yaml:"rename"`
+	Labels map[string]string `json:"labels" yaml:"labels"`
+}
+
+// Process selects the processes monitored by the agent: by PID, by the PID read from the PID file
+// or by the glob pattern matching the process name.
+// Name labels the metrics of the selected processes; by default the process name is used.
+type Process struct {
+	Name    string `json:"name" yaml:"name"`
+	PID     int32  `json:"pid" yaml:"pid"`
+	PIDFile string `json:"pid_file" yaml:"pidFile"`
+	Pattern string `json:"pattern" yaml:"pattern"`
+}
+
+// Collector stores the settings of an agent collector.
+// A collector without settings is enabled and collects metrics every poll interval.
+type Collector struct {
+	Disabled bool          `json:"disabled" yaml:"disabled"`
+	Interval time.Duration `json:"interval" yaml:"interval"`
 }
 
 type jsonAgent struct {
 	Agent
-	PollInterval   string `json:"poll_interval" yaml:"pollInterval" env:"POLL_INTERVAL"`
-	ReportInterval string `json:"report_interval" yaml:"reportInterval" env:"REPORT_INTERVAL"`
+	PollInterval   string                   `json:"poll_interval" yaml:"pollInterval" env:"POLL_INTERVAL"`
+	ReportInterval string                   `json:"report_interval" yaml:"reportInterval" env:"REPORT_INTERVAL"`
+	Collectors     map[string]jsonCollector `json:"collectors" yaml:"collectors"`
+	Commands       []jsonCommand            `json:"commands" yaml:"commands"`
+
+	RemoteConfigInterval string `json:"remote_config_interval" yaml:"remoteConfigInterval" env:"REMOTE_CONFIG_INTERVAL"`
+}
+
+type jsonCommand struct {
+	Command
+	Interval string `json:"interval" yaml:"interval"`
+	Timeout  string `json:"timeout" yaml:"timeout"`
+}
+
+type jsonCollector struct {
+	Collector
+	Interval string `json:"interval" yaml:"interval"`
 }
 
 // Server stores the attributes of the server.
-// Among them: Address, StoreInterval, StoreFile, Restore, Key.
+// Among them: Address, StoreInterval, StoreFile, Restore, Key, GaugeTTL, CounterTTL, SweepInterval, AlertRules, AlertWebhooks,
+// RollupInterval, the retentions of the history resolutions, AgentAbsentAfter and AgentConfigs.
 // Attribute values are filled in from environment variables or flags.
 // If neither is specified, the default values are applied.
+// A metrics type without TTL is never evicted, a history resolution without retention is kept forever.
 type Server struct {
 	Name          string        `json:"name" yaml:"name" env:"NAME"`
 	Address       string        `json:"address" yaml:"address" env:"ADDRESS"`
@@ -67,11 +221,40 @@ type Server struct {
 	Restore       bool          `json:"restore" yaml:"restore" env:"RESTORE"`
 	Key           string        `json:"key" env:"KEY"`
 	CryptoKey     string        `json:"crypto_key" env:"CRYPTO_KEY"`
+	GaugeTTL      time.Duration `json:"gauge_ttl" yaml:"gaugeTTL" env:"GAUGE_TTL"`
+	CounterTTL    time.Duration `json:"counter_ttl" yaml:"counterTTL" env:"COUNTER_TTL"`
+	SweepInterval time.Duration `json:"sweep_interval" yaml:"sweepInterval" env:"SWEEP_INTERVAL"`
+	AlertRules    string        `json:"alert_rules" yaml:"alertRules" env:"ALERT_RULES"`
+	AlertWebhooks []string      `json:"alert_webhooks" yaml:"alertWebhooks" env:"ALERT_WEBHOOKS" env-separator:","`
+
+	RollupInterval  time.Duration `json:"rollup_interval" yaml:"rollupInterval" env:"ROLLUP_INTERVAL"`
+	RawRetention    time.Duration `json:"raw_retention" yaml:"rawRetention" env:"RAW_RETENTION"`
+	MinuteRetention time.Duration `json:"minute_retention" yaml:"minuteRetention" env:"MINUTE_RETENTION"`
+	HourRetention   time.Duration `json:"hour_retention" yaml:"hourRetention" env:"HOUR_RETENTION"`
+	DayRetention    time.Duration `json:"day_retention" yaml:"dayRetention" env:"DAY_RETENTION"`
+
+	// AgentAbsentAfter is the silence period after which an agent is reported as absent.
+	AgentAbsentAfter time.Duration `json:"agent_absent_after" yaml:"agentAbsentAfter" env:"AGENT_ABSENT_AFTER"`
+
+	// AgentConfigs is the YAML file of the configurations served to the agents.
+	// The configurations are not served if it is empty.
+	AgentConfigs string `json:"agent_configs" yaml:"agentConfigs" env:"AGENT_CONFIGS"`
 }
 
 type jsonServer struct {
 	Server
 	StoreInterval string `json:"store_interval" yaml:"storeInterval" env:"STORE_INTERVAL"`
+	GaugeTTL      string `json:"gauge_ttl" yaml:"gaugeTTL" env:"GAUGE_TTL"`
+	CounterTTL    string `json:"counter_ttl" yaml:"counterTTL" env:"COUNTER_TTL"`
+	SweepInterval string `json:"sweep_interval" yaml:"sweepInterval" env:"SWEEP_INTERVAL"`
+
+	RollupInterval  string `json:"rollup_interval" yaml:"rollupInterval" env:"ROLLUP_INTERVAL"`
+	RawRetention    string `json:"raw_retention" yaml:"rawRetention" env:"RAW_RETENTION"`
+	MinuteRetention string `json:"minute_retention" yaml:"minuteRetention" env:"MINUTE_RETENTION"`
+	HourRetention   string `json:"hour_retention" yaml:"hourRetention" env:"HOUR_RETENTION"`
+	DayRetention    string `json:"day_retention" yaml:"dayRetention" env:"DAY_RETENTION"`
+
+	AgentAbsentAfter string `json:"agent_absent_after" yaml:"agentAbsentAfter" env:"AGENT_ABSENT_AFTER"`
 }
 
 const (
@@ -87,6 +270,17 @@ const (
 	storeFile      = "/tmp/devops-metrics-db.json"
 	restoreFlag    = true
 	rateLimit      = 1
+	sweepInterval  = time.Minute
+
+	agentAbsentAfter = 5 * time.Minute
+
+	heartbeatReports = 10
+
+	rollupInterval  = time.Minute
+	rawRetention    = 2 * time.Hour
+	minuteRetention = 24 * time.Hour
+	hourRetention   = 30 * 24 * time.Hour
+	dayRetention    = 365 * 24 * time.Hour
 
 	agentName  = "alemetric-agent"
 	serverName = "alemetric-server"
@@ -129,7 +323,13 @@ var metricsNames = []string{
 	"PollCount",
 	"TotalMemory",
 	"FreeMemory",
-	"CPUutilization1",
+	"CPUutilization*",
+	"LoadAverage*",
+	"Swap*",
+	"Process*",
+	"Disk*",
+	"Net*",
+	"Agent*",
 }
 
 func defaultServerCfg() *Config {
@@ -140,6 +340,15 @@ func defaultServerCfg() *Config {
 			StoreInterval: storeInterval,
 			StoreFile:     storeFile,
 			Restore:       restoreFlag,
+			SweepInterval: sweepInterval,
+
+			RollupInterval:  rollupInterval,
+			RawRetention:    rawRetention,
+			MinuteRetention: minuteRetention,
+			HourRetention:   hourRetention,
+			DayRetention:    dayRetention,
+
+			AgentAbsentAfter: agentAbsentAfter,
 		},
 		Logger: Logger{Level: loggerDefaultLevel},
 	}
@@ -154,6 +363,8 @@ func defaultAgentCfg() *Config {
 			ServerURL:      serverURL,
 			MetricsNames:   metricsNames,
 			RateLimit:      rateLimit,
+
+			HeartbeatReports: heartbeatReports,
 		},
 		Logger: Logger{Level: loggerDefaultLevel},
 	}
@@ -180,17 +391,92 @@ func (c *Config) updateAgentConfigs(v *Config) {
 		c.Level = v.Level
 	}
 
+	if v.WatchInterval.String() != "0s" && c.WatchInterval != v.WatchInterval {
+		c.WatchInterval = v.WatchInterval
+	}
+
 	if v.Agent.Key != "" && c.Agent.Key != v.Agent.Key {
 		c.Agent.Key = v.Agent.Key
 	}
 
-	if v.RateLimit != 1 && c.RateLimit != v.RateLimit {
+	if v.RateLimit != 0 && v.RateLimit != 1 && c.RateLimit != v.RateLimit {
 		c.RateLimit = v.RateLimit
 	}
 
 	if v.Agent.CryptoKey != "" && c.Agent.CryptoKey != v.Agent.CryptoKey {
 		c.Agent.CryptoKey = v.Agent.CryptoKey
 	}
+
+	if len(v.MetricsNames) != 0 {
+		c.MetricsNames = v.MetricsNames
+	}
+
+	if v.StatsDAddress != "" && c.StatsDAddress != v.StatsDAddress {
+		c.StatsDAddress = v.StatsDAddress
+	}
+
+	if v.StatsDSocket != "" && c.StatsDSocket != v.StatsDSocket {
+		c.StatsDSocket = v.StatsDSocket
+	}
+
+	if v.PushAddress != "" && c.PushAddress != v.PushAddress {
+		c.PushAddress = v.PushAddress
+	}
+
+	if v.StatusAddress != "" && c.StatusAddress != v.StatusAddress {
+		c.StatusAddress = v.StatusAddress
+	}
+
+	if v.ChangesOnly && c.ChangesOnly != v.ChangesOnly {
+		c.ChangesOnly = v.ChangesOnly
+	}
+
+	if v.ChangeEpsilon != 0 && c.ChangeEpsilon != v.ChangeEpsilon {
+		c.ChangeEpsilon = v.ChangeEpsilon
+	}
+
+	if v.HeartbeatReports != 0 && c.HeartbeatReports != v.HeartbeatReports {
+		c.HeartbeatReports = v.HeartbeatReports
+	}
+
+	if len(v.GaugeAggregates) != 0 {
+		c.GaugeAggregates = v.GaugeAggregates
+	}
+
+	if len(v.Commands) != 0 {
+		c.Commands = v.Commands
+	}
+
+	if len(v.Logs) != 0 {
+		c.Logs = v.Logs
+	}
+
+	if v.LogOffsets != "" && c.LogOffsets != v.LogOffsets {
+		c.LogOffsets = v.LogOffsets
+	}
+
+	if len(v.Scrape) != 0 {
+		c.Scrape = v.Scrape
+	}
+
+	if len(v.Processes) != 0 {
+		c.Processes = v.Processes
+	}
+
+	if v.Group != "" && c.Group != v.Group {
+		c.Group = v.Group
+	}
+
+	if v.RemoteConfigInterval.String() != "0s" && c.RemoteConfigInterval != v.RemoteConfigInterval {
+		c.RemoteConfigInterval = v.RemoteConfigInterval
+	}
+
+	for name, collector := range v.Collectors {
+		if c.Collectors == nil {
+			c.Collectors = make(map[string]Collector)
+		}
+		c.Collectors[name] = collector
+	}
 }
 
 func (c *Config) updateServerConfigs(v *Config) {
@@ -218,6 +504,10 @@ func (c *Config) updateServerConfigs(v *Config) {
 		c.Level = v.Level
 	}
 
+	if v.WatchInterval.String() != "0s" && c.WatchInterval != v.WatchInterval {
+		c.WatchInterval = v.WatchInterval
+	}
+
 	if v.Database.URL != "" && c.Database.URL != v.Database.URL {
 		c.Database.URL = v.Database.URL
 	}
@@ -229,36 +519,148 @@ func (c *Config) updateServerConfigs(v *Config) {
 	if v.Server.CryptoKey != "" && c.Server.CryptoKey != v.Server.CryptoKey {
 		c.Server.CryptoKey = v.Server.CryptoKey
 	}
+
+	if v.GaugeTTL.String() != "0s" && c.GaugeTTL != v.GaugeTTL {
+		c.GaugeTTL = v.GaugeTTL
+	}
+
+	if v.CounterTTL.String() != "0s" && c.CounterTTL != v.CounterTTL {
+		c.CounterTTL = v.CounterTTL
+	}
+
+	if v.SweepInterval.String() != "0s" && c.SweepInterval != v.SweepInterval {
+		c.SweepInterval = v.SweepInterval
+	}
+
+	if v.AlertRules != "" && c.AlertRules != v.AlertRules {
+		c.AlertRules = v.AlertRules
+	}
+
+	if len(v.AlertWebhooks) != 0 {
+		c.AlertWebhooks = v.AlertWebhooks
+	}
+
+	if v.RollupInterval.String() != "0s" && c.RollupInterval != v.RollupInterval {
+		c.RollupInterval = v.RollupInterval
+	}
+
+	if v.RawRetention.String() != "0s" && c.RawRetention != v.RawRetention {
+		c.RawRetention = v.RawRetention
+	}
+
+	if v.MinuteRetention.String() != "0s" && c.MinuteRetention != v.MinuteRetention {
+		c.MinuteRetention = v.MinuteRetention
+	}
+
+	if v.HourRetention.String() != "0s" && c.HourRetention != v.HourRetention {
+		c.HourRetention = v.HourRetention
+	}
+
+	if v.DayRetention.String() != "0s" && c.DayRetention != v.DayRetention {
+		c.DayRetention = v.DayRetention
+	}
+
+	if v.AgentAbsentAfter.String() != "0s" && c.AgentAbsentAfter != v.AgentAbsentAfter {
+		c.AgentAbsentAfter = v.AgentAbsentAfter
+	}
+
+	if v.AgentConfigs != "" && c.AgentConfigs != v.AgentConfigs {
+		c.AgentConfigs = v.AgentConfigs
+	}
 }
 
-func (c *Config) parseFlags(app string) string {
+// parseFlags parses the command line arguments of the app.
+// The returned config holds only the flags set explicitly, so that the flag defaults,
+// the same as the ones of the default config, do not override the JSON config file.
+func parseFlags(fs *flag.FlagSet, app string, args []string) (*Config, string, error) {
 	var jsonConfigPath string
+	parsed := new(Config)
+	parsed.defineFlags(fs, app, &jsonConfigPath)
+	if err := fs.Parse(args); err != nil {
+		return nil, "", err
+	}
+
+	flags := new(Config)
+	explicit := flag.NewFlagSet(app, flag.ContinueOnError)
+	flags.defineFlags(explicit, app, new(string))
+	// the defaults of the explicit flags are reset, the flags stay bound to the fields
+	*flags = Config{}
+
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		if setErr := explicit.Set(f.Name, f.Value.String()); setErr != nil && err == nil {
+			err = setErr
+		}
+	})
 
+	return flags, jsonConfigPath, err
+}
+
+// defineFlags defines the flags of the app setting the fields of the config.
+func (c *Config) defineFlags(fs *flag.FlagSet, app string, jsonConfigPath *string) {
 	switch app {
 	case AgentConfig:
-		flag.StringVar(&c.Agent.ServerURL, "a", serverURL, "server address")
-		flag.DurationVar(&c.Agent.ReportInterval, "r", reportInterval, "report interval")
-		flag.DurationVar(&c.Agent.PollInterval, "p", pollInterval, "poll interval")
-		flag.StringVar(&c.Agent.Key, "k", "", "encryption key")
-		flag.UintVar(&c.RateLimit, "l", rateLimit, "rate limit")
-		flag.StringVar(&c.Agent.CryptoKey, "crypto-key", "", "public crypto key for https requests")
-		flag.StringVar(&jsonConfigPath, "c", "", "json agent config path")
-		flag.StringVar(&jsonConfigPath, "config", "", "json agent config path")
+		fs.StringVar(&c.Agent.ServerURL, "a", serverURL, "server address")
+		fs.DurationVar(&c.Agent.ReportInterval, "r", reportInterval, "report interval")
+		fs.DurationVar(&c.Agent.PollInterval, "p", pollInterval, "poll interval")
+		fs.StringVar(&c.Agent.Key, "k", "", "encryption key")
+		fs.UintVar(&c.RateLimit, "l", rateLimit, "rate limit")
+		fs.StringVar(&c.Agent.CryptoKey, "crypto-key", "", "public crypto key for https requests")
+		fs.StringVar(&c.Agent.StatsDAddress, "statsd-address", "", "statsd udp address")
+		fs.StringVar(&c.Agent.StatsDSocket, "statsd-socket", "", "statsd unix socket path")
+		fs.StringVar(&c.Agent.PushAddress, "push-address", "", "local push endpoint address")
+		fs.StringVar(&c.Agent.StatusAddress, "status-address", "", "local status endpoint address")
+		fs.StringVar(&c.Agent.LogOffsets, "log-offsets", "", "file of the offsets in the followed logs")
+		fs.BoolVar(&c.Agent.ChangesOnly, "changes-only", false, "send changed metrics only")
+		fs.Float64Var(&c.Agent.ChangeEpsilon, "change-epsilon", 0, "minimal change of gauge to send")
+		fs.UintVar(&c.Agent.HeartbeatReports, "heartbeat-reports", 0, "number of reports between sending all metrics")
+		fs.StringVar(&c.Agent.Group, "group", "", "agent group")
+		fs.DurationVar(&c.Agent.RemoteConfigInterval, "remote-config-interval", 0, "interval of requesting config from server")
+		fs.Var(listValue{&c.Agent.GaugeAggregates}, "gauge-aggregates", "comma separated gauge aggregates: min, max, avg")
+		fs.StringVar(jsonConfigPath, "c", "", "json agent config path")
+		fs.StringVar(jsonConfigPath, "config", "", "json agent config path")
+		fs.DurationVar(&c.WatchInterval, "config-watch-interval", 0, "interval of checking json config for changes")
 	case ServerConfig:
-		flag.StringVar(&c.Server.Address, "a", "", "server address")
-		flag.BoolVar(&c.Server.Restore, "r", true, "restore data from file")
-		flag.DurationVar(&c.Server.StoreInterval, "i", 0, "store interval")
-		flag.StringVar(&c.Server.StoreFile, "f", "", "store file")
-		flag.StringVar(&c.Server.Key, "k", "", "encryption key")
-		flag.StringVar(&c.Database.URL, "d", "", "database")
-		flag.StringVar(&c.Server.CryptoKey, "crypto-key", "", "private crypto key for tls")
-		flag.StringVar(&jsonConfigPath, "c", "", "json agent config path")
-		flag.StringVar(&jsonConfigPath, "config", "", "json agent config path")
+		fs.StringVar(&c.Server.Address, "a", "", "server address")
+		fs.BoolVar(&c.Server.Restore, "r", true, "restore data from file")
+		fs.DurationVar(&c.Server.StoreInterval, "i", 0, "store interval")
+		fs.StringVar(&c.Server.StoreFile, "f", "", "store file")
+		fs.StringVar(&c.Server.Key, "k", "", "encryption key")
+		fs.StringVar(&c.Database.URL, "d", "", "database")
+		fs.StringVar(&c.Server.CryptoKey, "crypto-key", "", "private crypto key for tls")
+		fs.DurationVar(&c.Server.GaugeTTL, "gauge-ttl", 0, "gauge metrics ttl")
+		fs.DurationVar(&c.Server.CounterTTL, "counter-ttl", 0, "counter metrics ttl")
+		fs.DurationVar(&c.Server.SweepInterval, "sweep-interval", 0, "stale metrics sweep interval")
+		fs.StringVar(&c.Server.AlertRules, "alert-rules", "", "alerting rules file")
+		fs.Var(listValue{&c.Server.AlertWebhooks}, "alert-webhooks", "comma separated alert webhook urls")
+		fs.DurationVar(&c.Server.RollupInterval, "rollup-interval", 0, "history rollup interval")
+		fs.DurationVar(&c.Server.RawRetention, "raw-retention", 0, "raw history retention")
+		fs.DurationVar(&c.Server.MinuteRetention, "minute-retention", 0, "1m history retention")
+		fs.DurationVar(&c.Server.HourRetention, "hour-retention", 0, "1h history retention")
+		fs.DurationVar(&c.Server.DayRetention, "day-retention", 0, "1d history retention")
+		fs.DurationVar(&c.Server.AgentAbsentAfter, "agent-absent-after", 0, "silence period of absent agent")
+		fs.StringVar(&c.Server.AgentConfigs, "agent-configs", "", "file of the configurations served to agents")
+		fs.StringVar(jsonConfigPath, "c", "", "json agent config path")
+		fs.StringVar(jsonConfigPath, "config", "", "json agent config path")
+		fs.DurationVar(&c.WatchInterval, "config-watch-interval", 0, "interval of checking json config for changes")
 	}
+}
 
-	flag.Parse()
+// listValue is the flag value of a comma separated list.
+type listValue struct {
+	list *[]string
+}
+
+func (v listValue) String() string {
+	if v.list == nil {
+		return ""
+	}
+	return strings.Join(*v.list, ",")
+}
 
-	return jsonConfigPath
+func (v listValue) Set(s string) error {
+	*v.list = strings.Split(s, ",")
+	return nil
 }
 
 func loadAgentJSONConfig(path string) (*Config, error) {
@@ -300,6 +702,43 @@ func loadAgentJSONConfig(path string) (*Config, error) {
 		return nil, fmt.Errorf("could not parse poll interval from config file: %w", err)
 	}
 
+	if agent.RemoteConfigInterval != "" {
+		config.RemoteConfigInterval, err = time.ParseDuration(agent.RemoteConfigInterval)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse remote config interval from config file: %w", err)
+		}
+	}
+
+	config.Collectors = make(map[string]Collector, len(agent.Collectors))
+	for name, c := range agent.Collectors {
+		collector := c.Collector
+		if c.Interval != "" {
+			collector.Interval, err = time.ParseDuration(c.Interval)
+			if err != nil {
+				return nil, fmt.Errorf("could not parse interval of collector %s from config file: %w", name, err)
+			}
+		}
+		config.Collectors[name] = collector
+	}
+
+	config.Commands = make([]Command, 0, len(agent.Commands))
+	for _, c := range agent.Commands {
+		command := c.Command
+		if c.Interval != "" {
+			command.Interval, err = time.ParseDuration(c.Interval)
+			if err != nil {
+				return nil, fmt.Errorf("could not parse interval of command %s from config file: %w", command.Name, err)
+			}
+		}
+		if c.Timeout != "" {
+			command.Timeout, err = time.ParseDuration(c.Timeout)
+			if err != nil {
+				return nil, fmt.Errorf("could not parse timeout of command %s from config file: %w", command.Name, err)
+			}
+		}
+		config.Commands = append(config.Commands, command)
+	}
+
 	return &config, nil
 }
 
@@ -336,6 +775,48 @@ func loadServerJSONConfig(path string) (*Config, error) {
 		return nil, fmt.Errorf("could not parse store interval from config file: %w", err)
 	}
 
+	if srv.GaugeTTL != "" {
+		config.GaugeTTL, err = time.ParseDuration(srv.GaugeTTL)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse gauge ttl from config file: %w", err)
+		}
+	}
+
+	if srv.CounterTTL != "" {
+		config.CounterTTL, err = time.ParseDuration(srv.CounterTTL)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse counter ttl from config file: %w", err)
+		}
+	}
+
+	if srv.SweepInterval != "" {
+		config.SweepInterval, err = time.ParseDuration(srv.SweepInterval)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse sweep interval from config file: %w", err)
+		}
+	}
+
+	for _, d := range []struct {
+		name  string
+		value string
+		dst   *time.Duration
+	}{
+		{name: "rollup interval", value: srv.RollupInterval, dst: &config.RollupInterval},
+		{name: "raw retention", value: srv.RawRetention, dst: &config.RawRetention},
+		{name: "minute retention", value: srv.MinuteRetention, dst: &config.MinuteRetention},
+		{name: "hour retention", value: srv.HourRetention, dst: &config.HourRetention},
+		{name: "day retention", value: srv.DayRetention, dst: &config.DayRetention},
+		{name: "agent absent after", value: srv.AgentAbsentAfter, dst: &config.AgentAbsentAfter},
+	} {
+		if d.value == "" {
+			continue
+		}
+		*d.dst, err = time.ParseDuration(d.value)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse %s from config file: %w", d.name, err)
+		}
+	}
+
 	return &config, nil
 }
 
@@ -344,54 +825,209 @@ func loadServerJSONConfig(path string) (*Config, error) {
 // Preference is given to environment variables.
 // If neither flags nor environment variables were passed, the default config is returned.
 func NewConfig(app string) *Config {
+	flags, jsonConfigPath, _ := parseFlags(flag.CommandLine, app, os.Args[1:])
+	envJSONConfigPath := os.Getenv("CONFIG")
+	if envJSONConfigPath != "" && envJSONConfigPath != jsonConfigPath {
+		jsonConfigPath = envJSONConfigPath
+	}
+
+	cfg, _ := load(&source{app: app, jsonPath: jsonConfigPath, flags: flags})
+	return cfg
+}
+
+// Reload creates the configuration object again from the JSON config file, the flags and the environment variables
+// the config was created from by NewConfig, and validates it.
+// Unlike NewConfig, it fails if the JSON config file cannot be loaded.
+func (c *Config) Reload() (*Config, error) {
+	if c.source == nil {
+		return nil, fmt.Errorf("%w: the config is not created by NewConfig", ErrInvalidConfig)
+	}
+
+	cfg, err := load(c.source)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Validate checks the config of the mode it was created for.
+func (c *Config) Validate() error {
+	switch strings.ToLower(c.Level) {
+	case "", "debug", "info", "warn", "error":
+	default:
+		return fmt.Errorf("%w: unknown logger level %q", ErrInvalidConfig, c.Level)
+	}
+
+	if c.source == nil {
+		return nil
+	}
+
+	switch c.source.app {
+	case AgentConfig:
+		if c.Agent.ServerURL == "" {
+			return fmt.Errorf("%w: empty server address", ErrInvalidConfig)
+		}
+		if c.PollInterval <= 0 {
+			return fmt.Errorf("%w: non-positive poll interval %s", ErrInvalidConfig, c.PollInterval)
+		}
+		if c.ReportInterval <= 0 {
+			return fmt.Errorf("%w: non-positive report interval %s", ErrInvalidConfig, c.ReportInterval)
+		}
+		if c.RateLimit == 0 {
+			return fmt.Errorf("%w: zero rate limit", ErrInvalidConfig)
+		}
+		for _, name := range c.MetricsNames {
+			if _, err := path.Match(name, ""); err != nil {
+				return fmt.Errorf("%w: bad metrics name pattern %q", ErrInvalidConfig, name)
+			}
+		}
+		if c.PushAddress != "" {
+			if err := CheckLoopbackAddress(c.PushAddress); err != nil {
+				return fmt.Errorf("push address: %w", err)
+			}
+		}
+	case ServerConfig:
+		if c.Server.Address == "" {
+			return fmt.Errorf("%w: empty server address", ErrInvalidConfig)
+		}
+		if c.StoreInterval < 0 {
+			return fmt.Errorf("%w: negative store interval %s", ErrInvalidConfig, c.StoreInterval)
+		}
+	}
+
+	return nil
+}
+
+// CheckLoopbackAddress checks that the address is on the loopback interface, e.g. localhost:8081 or 127.0.0.1:8081.
+func CheckLoopbackAddress(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("%w: bad address %q: %s", ErrInvalidConfig, addr, err)
+	}
+
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+
+	return fmt.Errorf("%w: not loopback address %q", ErrInvalidConfig, addr)
+}
+
+// Watch notifies about the modifications of the JSON config file, checking it every watch interval,
+// until the context is done. The file is not watched if there is no file or the watch interval is zero.
+func (c *Config) Watch(ctx context.Context, changes chan<- struct{}) {
+	if c.source == nil || c.source.jsonPath == "" || c.WatchInterval <= 0 {
+		return
+	}
+
+	modTime := func() time.Time {
+		info, err := os.Stat(c.source.jsonPath)
+		if err != nil {
+			return time.Time{}
+		}
+		return info.ModTime()
+	}
+
+	ticker := time.NewTicker(c.WatchInterval)
+	defer ticker.Stop()
+
+	last := modTime()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if cur := modTime(); !cur.Equal(last) {
+			last = cur
+			select {
+			case changes <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// ReloadOnChange reloads the config on SIGHUP and on the modifications of the JSON config file
+// until the context is done, and applies the reloaded config.
+// An invalid config, or the one apply fails on, is not applied and the error is logged.
+func (c *Config) ReloadOnChange(ctx context.Context, l logger.LogInterface, apply func(next *Config) error) {
+	changes := make(chan struct{}, 1)
+	go c.Watch(ctx, changes)
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+		case <-changes:
+		}
+
+		next, err := c.Reload()
+		if err == nil {
+			err = apply(next)
+		}
+		if err != nil {
+			l.Error(fmt.Sprintf("config is not reloaded: %s", err))
+			continue
+		}
+		l.Info("Config is reloaded")
+	}
+}
+
+// load creates the configuration object from the source.
+// The error of loading the JSON config file is returned with the config made without the file.
+func load(src *source) (*Config, error) {
 	var (
-		cfg   *Config
-		envs  *Config
-		flags *Config
+		cfg        *Config
+		jsonConfig *Config
+		err        error
 	)
 
-	switch app {
+	switch src.app {
 	case AgentConfig:
 		cfg = defaultAgentCfg()
 
-		flags = new(Config)
-		jsonConfigPath := flags.parseFlags(AgentConfig)
-		envJSONConfigPath := os.Getenv("CONFIG")
-		if envJSONConfigPath != "" && envJSONConfigPath != jsonConfigPath {
-			jsonConfigPath = envJSONConfigPath
-		}
-
-		jsonConfig, _ := loadAgentJSONConfig(jsonConfigPath)
+		jsonConfig, err = loadAgentJSONConfig(src.jsonPath)
 		if jsonConfig != nil {
-			cfg.updateServerConfigs(jsonConfig)
+			cfg.updateAgentConfigs(jsonConfig)
 		}
 
-		cfg.updateAgentConfigs(flags)
+		cfg.updateAgentConfigs(src.flags)
 
-		envs = new(Config)
+		envs := new(Config)
 		_ = cleanenv.ReadEnv(envs)
 		cfg.updateAgentConfigs(envs)
 	case ServerConfig:
 		cfg = defaultServerCfg()
 
-		flags = new(Config)
-		jsonConfigPath := flags.parseFlags(ServerConfig)
-		envJSONConfigPath := os.Getenv("CONFIG")
-		if envJSONConfigPath != "" && envJSONConfigPath != jsonConfigPath {
-			jsonConfigPath = envJSONConfigPath
-		}
-
-		jsonConfig, _ := loadServerJSONConfig(jsonConfigPath)
+		jsonConfig, err = loadServerJSONConfig(src.jsonPath)
 		if jsonConfig != nil {
 			cfg.updateServerConfigs(jsonConfig)
 		}
 
-		cfg.updateServerConfigs(flags)
+		cfg.updateServerConfigs(src.flags)
 
-		envs = new(Config)
+		envs := new(Config)
 		_ = cleanenv.ReadEnv(envs)
 		cfg.updateServerConfigs(envs)
 	}
 
-	return cfg
+	if cfg != nil {
+		cfg.source = src
+	}
+
+	return cfg, err
 }
@@ -1,13 +1,18 @@
 package configs
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"github.com/ilyakaznacheev/cleanenv"
+	"io"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
+	logger "github.com/vladislaoramos/alemetric/pkg/log"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -151,6 +156,10 @@ func TestServerJSONConfig(t *testing.T) {
 		require.Equal(t, true, cfg.Server.Restore)
 		require.Equal(t, "/path/to/file.db", cfg.Server.StoreFile)
 		require.Equal(t, "/path/to/key.pem", cfg.Server.CryptoKey)
+		require.Equal(t, time.Hour*24, cfg.Server.GaugeTTL)
+		require.Equal(t, time.Hour*168, cfg.Server.CounterTTL)
+		require.Equal(t, []string{"http://localhost:9093/alerts"}, cfg.Server.AlertWebhooks)
+		require.Equal(t, time.Hour*3, cfg.Server.RawRetention)
 	})
 
 	t.Run("flags with json", func(t *testing.T) {
@@ -233,9 +242,177 @@ func TestAgentJSONConfig(t *testing.T) {
 		require.Equal(t, "0.0.0.0:8888", cfg.Agent.ServerURL)
 		require.Equal(t, "/path/to/key.pem", cfg.Agent.CryptoKey)
 		require.Equal(t, "key", cfg.Agent.Key)
+		require.Equal(t, map[string]Collector{
+			"gopsutil": {Interval: time.Second * 10},
+			"random":   {Disabled: true},
+		}, cfg.Agent.Collectors)
+		require.Equal(t, []Process{
+			{Name: "service", PIDFile: "/var/run/service.pid"},
+		}, cfg.Agent.Processes)
+		require.Equal(t, []Command{
+			{Name: "queue", Command: `echo "QueueSize gauge 0"`, Interval: time.Second * 30, Timeout: time.Second * 5},
+		}, cfg.Agent.Commands)
+		require.Equal(t, "web", cfg.Agent.Group)
+		require.Equal(t, time.Minute, cfg.Agent.RemoteConfigInterval)
 	})
 }
 
+func testFlags(t *testing.T, app string, args ...string) *Config {
+	flags, _, err := parseFlags(flag.NewFlagSet(app, flag.ContinueOnError), app, args)
+	require.NoError(t, err)
+	return flags
+}
+
+func TestParseFlags(t *testing.T) {
+	flags, path, err := parseFlags(flag.NewFlagSet(AgentConfig, flag.ContinueOnError), AgentConfig,
+		[]string{"-c", "agent.json", "-r", "5s", "-gauge-aggregates", "min,max"})
+	require.NoError(t, err)
+	require.Equal(t, "agent.json", path)
+
+	// only the flags set explicitly are kept
+	require.Equal(t, &Config{Agent: Agent{ReportInterval: 5 * time.Second, GaugeAggregates: []string{"min", "max"}}}, flags)
+}
+
+func TestConfig_Reload(t *testing.T) {
+	require.NoError(t, cleanEnvs())
+
+	path := filepath.Join(t.TempDir(), "agent.json")
+	write := func(data string) {
+		require.NoError(t, os.WriteFile(path, []byte(data), 0600))
+	}
+
+	write(`{"poll_interval": "1s", "report_interval": "2s", "metrics_names": ["Alloc"]}`)
+	cfg, err := load(&source{app: AgentConfig, jsonPath: path, flags: testFlags(t, AgentConfig)})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Alloc"}, cfg.MetricsNames)
+
+	// the flag defaults do not override the JSON config file
+	write(`{"address": "10.0.0.2:9000", "poll_interval": "5s", "report_interval": "7s", "metrics_names": ["Alloc", "Sys"]}`)
+	next, err := cfg.Reload()
+	require.NoError(t, err)
+	require.Equal(t, []string{"Alloc", "Sys"}, next.MetricsNames)
+	require.Equal(t, "10.0.0.2:9000", next.Agent.ServerURL)
+	require.Equal(t, 5*time.Second, next.PollInterval)
+	require.Equal(t, 7*time.Second, next.ReportInterval)
+
+	// the flags set explicitly do
+	explicit, err := load(&source{app: AgentConfig, jsonPath: path, flags: testFlags(t, AgentConfig, "-p", "3s")})
+	require.NoError(t, err)
+	require.Equal(t, 3*time.Second, explicit.PollInterval)
+	require.Equal(t, 7*time.Second, explicit.ReportInterval)
+
+	write(`{"poll_interval": "soon", "report_interval": "2s"}`)
+	_, err = cfg.Reload()
+	require.Error(t, err)
+
+	write(`{"poll_interval": "1s", "report_interval": "2s", "metrics_names": ["Heap["]}`)
+	_, err = cfg.Reload()
+	require.ErrorIs(t, err, ErrInvalidConfig)
+
+	_, err = defaultAgentCfg().Reload()
+	require.ErrorIs(t, err, ErrInvalidConfig)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	agent := func(update func(c *Config)) *Config {
+		c := defaultAgentCfg()
+		c.source = &source{app: AgentConfig}
+		update(c)
+		return c
+	}
+	server := func(update func(c *Config)) *Config {
+		c := defaultServerCfg()
+		c.source = &source{app: ServerConfig}
+		update(c)
+		return c
+	}
+
+	require.NoError(t, agent(func(c *Config) {}).Validate())
+	require.NoError(t, server(func(c *Config) {}).Validate())
+	require.NoError(t, agent(func(c *Config) { c.PushAddress = "localhost:8081" }).Validate())
+	require.NoError(t, agent(func(c *Config) { c.PushAddress = "[::1]:8081" }).Validate())
+
+	for _, c := range []*Config{
+		agent(func(c *Config) { c.Level = "verbose" }),
+		agent(func(c *Config) { c.Agent.ServerURL = "" }),
+		agent(func(c *Config) { c.PollInterval = 0 }),
+		agent(func(c *Config) { c.ReportInterval = -time.Second }),
+		agent(func(c *Config) { c.RateLimit = 0 }),
+		agent(func(c *Config) { c.PushAddress = ":9999" }),
+		agent(func(c *Config) { c.PushAddress = "0.0.0.0:9999" }),
+		agent(func(c *Config) { c.PushAddress = "localhost" }),
+		server(func(c *Config) { c.Server.Address = "" }),
+		server(func(c *Config) { c.StoreInterval = -time.Second }),
+	} {
+		require.ErrorIs(t, c.Validate(), ErrInvalidConfig)
+	}
+}
+
+func TestConfig_Watch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{}`), 0600))
+
+	cfg := defaultServerCfg()
+	cfg.source = &source{app: ServerConfig, jsonPath: path}
+	cfg.WatchInterval = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes := make(chan struct{}, 1)
+	go cfg.Watch(ctx, changes)
+
+	modTime := time.Now().Add(time.Hour)
+	require.Eventually(t, func() bool {
+		// the file is modified after the watching starts
+		require.NoError(t, os.Chtimes(path, modTime, modTime))
+		select {
+		case <-changes:
+			return true
+		default:
+			return false
+		}
+	}, time.Second, 20*time.Millisecond)
+}
+
+func TestConfig_ReloadOnChange(t *testing.T) {
+	require.NoError(t, cleanEnvs())
+
+	path := filepath.Join(t.TempDir(), "agent.json")
+	write := func(data string, modTime time.Time) {
+		require.NoError(t, os.WriteFile(path, []byte(data), 0600))
+		require.NoError(t, os.Chtimes(path, modTime, modTime))
+	}
+
+	write(`{"poll_interval": "1s", "report_interval": "2s", "metrics_names": ["Alloc"]}`, time.Now())
+	cfg, err := load(&source{app: AgentConfig, jsonPath: path, flags: testFlags(t, AgentConfig)})
+	require.NoError(t, err)
+	cfg.WatchInterval = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	applied := make(chan []string, 10)
+	go cfg.ReloadOnChange(ctx, logger.New("error", io.Discard), func(next *Config) error {
+		applied <- next.MetricsNames
+		return nil
+	})
+
+	// the invalid config is not applied
+	write(`{"poll_interval": "1s", "report_interval": "2s", "metrics_names": ["Heap["]}`, time.Now().Add(time.Hour))
+	require.Never(t, func() bool {
+		return len(applied) != 0
+	}, 100*time.Millisecond, 10*time.Millisecond)
+
+	write(`{"poll_interval": "1s", "report_interval": "2s", "metrics_names": ["Alloc", "Sys"]}`, time.Now().Add(2*time.Hour))
+	select {
+	case names := <-applied:
+		require.Equal(t, []string{"Alloc", "Sys"}, names)
+	case <-time.After(time.Second):
+		t.Fatal("the modified config is not applied")
+	}
+}
+
 func cleanEnvs() error {
 	stringEnvs := []string{
 		"NAME", "ADDRESS", "STORE_INTERVAL", "STORE_FILE",
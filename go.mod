@@ -16,6 +16,7 @@ require (
 	github.com/sirupsen/logrus v1.9.0
 	github.com/stretchr/testify v1.8.2
 	golang.org/x/tools v0.4.1-0.20221208213631-3f74d914ae6d
+	gopkg.in/yaml.v3 v3.0.1
 	honnef.co/go/tools v0.4.3
 )
 
@@ -49,6 +50,5 @@ require (
 	golang.org/x/net v0.6.0 // indirect
 	golang.org/x/sys v0.8.0 // indirect
 	golang.org/x/text v0.7.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 	olympos.io/encoding/edn v0.0.0-20201019073823-d3554ca0b0a3 // indirect
 )
@@ -0,0 +1,188 @@
+// Package agentconfig provides the configurations of the agents served by the server.
+package agentconfig
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/vladislaoramos/alemetric/internal/entity"
+	logger "github.com/vladislaoramos/alemetric/pkg/log"
+)
+
+var ErrInvalidConfig = errors.New("invalid agent config")
+
+// Rule is the configuration of the agents with the names matching one of the glob patterns of Agents
+// or belonging to one of Groups. A rule without agents and groups applies to all agents.
+type Rule struct {
+	Agents []string `yaml:"agents"`
+	Groups []string `yaml:"groups"`
+
+	entity.AgentConfig `yaml:",inline"`
+}
+
+type rulesFile struct {
+	Configs []Rule `yaml:"configs"`
+}
+
+// Source serves the agent configurations from the YAML file.
+// The file is read again when it is modified; a modified file with an invalid configuration
+// is ignored and the previous configuration is served.
+type Source struct {
+	path string
+	l    logger.LogInterface
+
+	mu      sync.Mutex
+	modTime time.Time
+	rules   []Rule
+}
+
+// Load loads the agent configurations from the YAML file.
+//
+//	configs:
+//	  - pollInterval: 2s
+//	    reportInterval: 10s
+//	  - groups: [db]
+//	    metricsNames: [HeapAlloc, Disk*]
+//	  - agents: [web-*]
+//	    rateLimit: 4
+func Load(path string, l logger.LogInterface) (*Source, error) {
+	s := &Source{path: path, l: l}
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Config returns the configuration of the agent with the name and the group.
+// The matching rules are applied in the order of the file, so the later rules override the earlier ones.
+func (s *Source) Config(name, group string) entity.AgentConfig {
+	if err := s.reload(); err != nil {
+		s.l.Error(fmt.Sprintf("agent configs are not reloaded: %s", err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var cfg entity.AgentConfig
+	for _, r := range s.rules {
+		if r.matches(name, group) {
+			cfg = Merge(cfg, r.AgentConfig)
+		}
+	}
+
+	return cfg
+}
+
+// Merge overrides the fields of the configuration with the non-empty fields of the other one.
+func Merge(cfg, other entity.AgentConfig) entity.AgentConfig {
+	if other.PollInterval != "" {
+		cfg.PollInterval = other.PollInterval
+	}
+	if other.ReportInterval != "" {
+		cfg.ReportInterval = other.ReportInterval
+	}
+	if len(other.MetricsNames) != 0 {
+		cfg.MetricsNames = other.MetricsNames
+	}
+	if other.RateLimit != 0 {
+		cfg.RateLimit = other.RateLimit
+	}
+	return cfg
+}
+
+// Validate checks the intervals and the metrics name patterns of the configuration.
+func Validate(cfg entity.AgentConfig) error {
+	for _, d := range []struct {
+		name  string
+		value string
+	}{
+		{name: "poll interval", value: cfg.PollInterval},
+		{name: "report interval", value: cfg.ReportInterval},
+	} {
+		if d.value == "" {
+			continue
+		}
+		v, err := time.ParseDuration(d.value)
+		if err != nil {
+			return fmt.Errorf("%w: cannot parse %s %q", ErrInvalidConfig, d.name, d.value)
+		}
+		if v <= 0 {
+			return fmt.Errorf("%w: non-positive %s %q", ErrInvalidConfig, d.name, d.value)
+		}
+	}
+
+	for _, name := range cfg.MetricsNames {
+		if _, err := path.Match(name, ""); err != nil {
+			return fmt.Errorf("%w: bad metrics name pattern %q", ErrInvalidConfig, name)
+		}
+	}
+
+	return nil
+}
+
+func (s *Source) reload() error {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return fmt.Errorf("could not stat agent configs file: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.modTime.IsZero() && info.ModTime().Equal(s.modTime) {
+		return nil
+	}
+	// the modified file is read once, even if it is invalid
+	s.modTime = info.ModTime()
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("could not read agent configs file: %w", err)
+	}
+
+	var f rulesFile
+	if err = yaml.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("error unmarshalling agent configs file: %w", err)
+	}
+
+	for i, r := range f.Configs {
+		if err = Validate(r.AgentConfig); err != nil {
+			return fmt.Errorf("config %d: %w", i+1, err)
+		}
+		for _, pattern := range r.Agents {
+			if _, err = path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("config %d: %w: bad agent pattern %q", i+1, ErrInvalidConfig, pattern)
+			}
+		}
+	}
+
+	s.rules = f.Configs
+
+	return nil
+}
+
+func (r Rule) matches(name, group string) bool {
+	if len(r.Agents) == 0 && len(r.Groups) == 0 {
+		return true
+	}
+
+	for _, pattern := range r.Agents {
+		if ok, err := path.Match(pattern, name); err == nil && ok {
+			return true
+		}
+	}
+
+	for _, g := range r.Groups {
+		if group != "" && g == group {
+			return true
+		}
+	}
+
+	return false
+}
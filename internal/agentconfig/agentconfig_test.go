@@ -0,0 +1,87 @@
+package agentconfig
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/vladislaoramos/alemetric/internal/entity"
+	logger "github.com/vladislaoramos/alemetric/pkg/log"
+)
+
+const configs = `
+configs:
+  - pollInterval: 2s
+    reportInterval: 10s
+  - groups: [db]
+    metricsNames: [HeapAlloc, Disk*]
+  - agents: [web-*]
+    rateLimit: 4
+    reportInterval: 5s
+`
+
+func writeConfigs(t *testing.T, path, data string, modTime time.Time) {
+	require.NoError(t, os.WriteFile(path, []byte(data), 0600))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestSource_Config(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agents.yml")
+	writeConfigs(t, path, configs, time.Now())
+
+	s, err := Load(path, logger.New("error", io.Discard))
+	require.NoError(t, err)
+
+	require.Equal(t, entity.AgentConfig{PollInterval: "2s", ReportInterval: "10s"}, s.Config("agent", ""))
+	require.Equal(t, entity.AgentConfig{
+		PollInterval:   "2s",
+		ReportInterval: "10s",
+		MetricsNames:   []string{"HeapAlloc", "Disk*"},
+	}, s.Config("agent", "db"))
+	require.Equal(t, entity.AgentConfig{
+		PollInterval:   "2s",
+		ReportInterval: "5s",
+		MetricsNames:   []string{"HeapAlloc", "Disk*"},
+		RateLimit:      4,
+	}, s.Config("web-1", "db"))
+}
+
+func TestSource_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agents.yml")
+	modTime := time.Now().Add(-time.Hour)
+	writeConfigs(t, path, configs, modTime)
+
+	s, err := Load(path, logger.New("error", io.Discard))
+	require.NoError(t, err)
+
+	modTime = modTime.Add(time.Minute)
+	writeConfigs(t, path, "configs:\n  - pollInterval: 3s\n", modTime)
+	require.Equal(t, entity.AgentConfig{PollInterval: "3s"}, s.Config("web-1", ""))
+
+	// the invalid configuration is ignored
+	modTime = modTime.Add(time.Minute)
+	writeConfigs(t, path, "configs:\n  - pollInterval: soon\n", modTime)
+	require.Equal(t, entity.AgentConfig{PollInterval: "3s"}, s.Config("web-1", ""))
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	for _, data := range []string{
+		"configs:\n  - pollInterval: soon\n",
+		"configs:\n  - reportInterval: -1s\n",
+		"configs:\n  - metricsNames: ['Heap[']\n",
+		"configs:\n  - agents: ['web-[']\n",
+	} {
+		path := filepath.Join(t.TempDir(), "agents.yml")
+		writeConfigs(t, path, data, time.Now())
+
+		_, err := Load(path, logger.New("error", io.Discard))
+		require.ErrorIs(t, err, ErrInvalidConfig, data)
+	}
+
+	_, err := Load(filepath.Join(t.TempDir(), "missing.yml"), logger.New("error", io.Discard))
+	require.Error(t, err)
+}
@@ -0,0 +1,285 @@
+package alert
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/vladislaoramos/alemetric/internal/entity"
+)
+
+const (
+	defaultEvalInterval = 10 * time.Second
+	// defaultStaleAfter is the period after which the last value of a metrics is no data.
+	defaultStaleAfter = 5 * time.Minute
+)
+
+type sample struct {
+	value float64
+	at    time.Time
+}
+
+type ruleState struct {
+	rule  Rule
+	alert entity.Alert
+}
+
+// Engine evaluates the alerting rules against the observed metrics.
+// The rules are evaluated on every observed metrics and periodically by Run,
+// so that the alerts fire after their duration even without new metrics.
+type Engine struct {
+	mu        sync.Mutex
+	rules     []*ruleState
+	values    map[string]sample
+	samples   map[string][]sample
+	windows   map[string]time.Duration // the longest rate window of each metrics
+	stale     time.Duration
+	now       func() time.Time
+	notifiers []Notifier
+}
+
+// NewEngine creates an engine for the alerting rules.
+func NewEngine(rules []Rule) *Engine {
+	e := &Engine{
+		values:  make(map[string]sample),
+		samples: make(map[string][]sample),
+		windows: make(map[string]time.Duration),
+		stale:   defaultStaleAfter,
+		now:     time.Now,
+	}
+
+	for _, r := range rules {
+		if r.window > e.windows[r.metrics] {
+			e.windows[r.metrics] = r.window
+		}
+		e.rules = append(e.rules, &ruleState{
+			rule: r,
+			alert: entity.Alert{
+				Name:  r.Name,
+				Expr:  r.Expr,
+				For:   formatFor(r.For),
+				State: entity.AlertInactive,
+			},
+		})
+	}
+
+	return e
+}
+
+// Notify adds the notifier about the changes of the alert states.
+func (e *Engine) Notify(n Notifier) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.notifiers = append(e.notifiers, n)
+}
+
+// Observe records the value of the metrics and evaluates the rules.
+func (e *Engine) Observe(m entity.Metrics) {
+	var value float64
+	switch {
+	case m.Value != nil:
+		value = float64(*m.Value)
+	case m.Delta != nil:
+		value = float64(*m.Delta)
+	default:
+		return
+	}
+
+	e.mu.Lock()
+	now := e.now()
+	s := sample{value: value, at: now}
+	e.values[m.ID] = s
+	if _, ok := e.windows[m.ID]; ok && m.Delta != nil {
+		e.samples[m.ID] = append(e.samples[m.ID], s)
+	}
+
+	changed, notifiers := e.evaluate(now)
+	e.mu.Unlock()
+
+	notify(notifiers, changed)
+}
+
+// Run evaluates the rules every interval until the context is done.
+func (e *Engine) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultEvalInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			e.mu.Lock()
+			changed, notifiers := e.evaluate(e.now())
+			e.mu.Unlock()
+
+			notify(notifiers, changed)
+		}
+	}
+}
+
+// Alerts returns the states of all alerting rules sorted by name.
+func (e *Engine) Alerts() []entity.Alert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	res := make([]entity.Alert, 0, len(e.rules))
+	for _, rs := range e.rules {
+		res = append(res, rs.alert)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+
+	return res
+}
+
+// evaluate evaluates the rules and returns the alerts which states are changed
+// with the notifiers to notify about them. The pending and firing alerts of the rules
+// without data, e.g. the ones with a stale value, are resolved.
+// The notifiers are called by the caller after releasing the lock.
+func (e *Engine) evaluate(now time.Time) ([]entity.Alert, []Notifier) {
+	for name := range e.samples {
+		e.pruneSamples(name, now)
+	}
+
+	var changed []entity.Alert
+	for _, rs := range e.rules {
+		state := rs.alert.State
+		if value, ok := e.value(rs.rule, now); ok {
+			v := value
+			rs.alert.Value = &v
+			e.transit(rs, rs.rule.holds(value), now)
+		} else {
+			rs.alert.Value = nil
+			e.transit(rs, false, now)
+		}
+		if rs.alert.State != state {
+			t := now
+			rs.alert.ChangedAt = &t
+			changed = append(changed, rs.alert)
+		}
+	}
+
+	if len(changed) == 0 {
+		return nil, nil
+	}
+
+	notifiers := make([]Notifier, len(e.notifiers))
+	copy(notifiers, e.notifiers)
+
+	return changed, notifiers
+}
+
+func notify(notifiers []Notifier, alerts []entity.Alert) {
+	for _, a := range alerts {
+		for _, n := range notifiers {
+			n.Notify(a)
+		}
+	}
+}
+
+// transit changes the state of the alert according to its condition.
+func (e *Engine) transit(rs *ruleState, holds bool, now time.Time) {
+	a := &rs.alert
+	t := now
+
+	switch a.State {
+	case entity.AlertInactive, entity.AlertResolved:
+		if !holds {
+			return
+		}
+		a.State = entity.AlertPending
+		a.ActiveAt = &t
+		a.FiredAt = nil
+		a.ResolvedAt = nil
+		if rs.rule.For == 0 {
+			a.State = entity.AlertFiring
+			a.FiredAt = &t
+		}
+	case entity.AlertPending:
+		if !holds {
+			a.State = entity.AlertInactive
+			a.ActiveAt = nil
+			return
+		}
+		if now.Sub(*a.ActiveAt) >= rs.rule.For {
+			a.State = entity.AlertFiring
+			a.FiredAt = &t
+		}
+	case entity.AlertFiring:
+		if !holds {
+			a.State = entity.AlertResolved
+			a.ResolvedAt = &t
+		}
+	}
+}
+
+// value evaluates the left part of the rule expression.
+// It returns false if there is no data for the metrics: a value is no data after the stale period,
+// the rate needs the last sample within the window and an earlier sample as its base.
+// The rate of an observed counter without samples within the window is zero.
+func (e *Engine) value(r Rule, now time.Time) (float64, bool) {
+	if r.fn != fnRate {
+		s, ok := e.values[r.metrics]
+		if !ok || now.Sub(s.at) > e.stale {
+			return 0, false
+		}
+		return s.value, true
+	}
+
+	samples := e.samples[r.metrics]
+	border := now.Add(-r.window)
+	if len(samples) == 0 {
+		return 0, false
+	}
+	if samples[len(samples)-1].at.Before(border) {
+		return 0, true
+	}
+	if len(samples) < 2 {
+		return 0, false
+	}
+
+	first, last := samples[baseSample(samples, border)], samples[len(samples)-1]
+	if !last.at.After(first.at) {
+		return 0, false
+	}
+
+	increase := last.value - first.value
+	if increase < 0 {
+		// the counter was reset
+		increase = last.value
+	}
+
+	return increase / last.at.Sub(first.at).Seconds(), true
+}
+
+// pruneSamples removes the samples older than the longest rate window of the metrics
+// except the last one of them used as the base of the rate.
+func (e *Engine) pruneSamples(name string, now time.Time) {
+	samples := e.samples[name]
+	e.samples[name] = samples[baseSample(samples, now.Add(-e.windows[name])):]
+}
+
+// baseSample returns the index of the last sample before the border,
+// or of the first sample if there are no earlier samples.
+func baseSample(samples []sample, border time.Time) int {
+	i := 0
+	for i < len(samples)-1 && samples[i+1].at.Before(border) {
+		i++
+	}
+	return i
+}
+
+func formatFor(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
@@ -0,0 +1,237 @@
+package alert
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vladislaoramos/alemetric/internal/entity"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func testEngine(t *testing.T, rules ...Rule) (*Engine, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)}
+	e := NewEngine(rules)
+	e.now = clock.Now
+	return e, clock
+}
+
+func gauge(id string, v float64) entity.Metrics {
+	value := entity.Gauge(v)
+	return entity.Metrics{ID: id, MType: "gauge", Value: &value}
+}
+
+func counter(id string, v int64) entity.Metrics {
+	delta := entity.Counter(v)
+	return entity.Metrics{ID: id, MType: "counter", Delta: &delta}
+}
+
+func evaluate(e *Engine) {
+	e.mu.Lock()
+	changed, notifiers := e.evaluate(e.now())
+	e.mu.Unlock()
+	notify(notifiers, changed)
+}
+
+type notifierFunc func(a entity.Alert)
+
+func (f notifierFunc) Notify(a entity.Alert) {
+	f(a)
+}
+
+func TestEngine_Threshold(t *testing.T) {
+	rule, err := NewRule("HighHeap", "HeapAlloc > 100", 2*time.Minute)
+	require.NoError(t, err)
+
+	e, clock := testEngine(t, rule)
+	require.Equal(t, entity.AlertInactive, e.Alerts()[0].State)
+
+	e.Observe(gauge("HeapAlloc", 200))
+	require.Equal(t, entity.AlertPending, e.Alerts()[0].State)
+
+	clock.Add(time.Minute)
+	evaluate(e)
+	require.Equal(t, entity.AlertPending, e.Alerts()[0].State)
+
+	clock.Add(time.Minute)
+	evaluate(e)
+	a := e.Alerts()[0]
+	require.Equal(t, entity.AlertFiring, a.State)
+	require.Equal(t, 200.0, *a.Value)
+	require.NotNil(t, a.FiredAt)
+
+	e.Observe(gauge("HeapAlloc", 50))
+	a = e.Alerts()[0]
+	require.Equal(t, entity.AlertResolved, a.State)
+	require.NotNil(t, a.ResolvedAt)
+
+	e.Observe(gauge("HeapAlloc", 500))
+	require.Equal(t, entity.AlertPending, e.Alerts()[0].State)
+
+	e.Observe(gauge("HeapAlloc", 5))
+	require.Equal(t, entity.AlertInactive, e.Alerts()[0].State)
+}
+
+func TestEngine_Rate(t *testing.T) {
+	stalled, err := NewRule("Stalled", "rate(PollCount) == 0", 5*time.Minute)
+	require.NoError(t, err)
+
+	fast, err := NewRule("Fast", "rate(PollCount) > 1", 0)
+	require.NoError(t, err)
+
+	e, clock := testEngine(t, stalled, fast)
+
+	e.Observe(counter("PollCount", 10))
+	clock.Add(10 * time.Second)
+	e.Observe(counter("PollCount", 30))
+
+	alerts := e.Alerts()
+	require.Equal(t, "Fast", alerts[0].Name)
+	require.Equal(t, entity.AlertFiring, alerts[0].State)
+	require.Equal(t, 2.0, *alerts[0].Value)
+	require.Equal(t, entity.AlertInactive, alerts[1].State)
+
+	// the counter was reset
+	clock.Add(10 * time.Second)
+	e.Observe(counter("PollCount", 5))
+	require.Equal(t, 0.25, *e.Alerts()[0].Value)
+
+	// no updates longer than the rate window is the zero rate
+	clock.Add(2 * time.Minute)
+	evaluate(e)
+	alerts = e.Alerts()
+	require.Equal(t, entity.AlertResolved, alerts[0].State)
+	require.Equal(t, 0.0, *alerts[1].Value)
+	require.Equal(t, entity.AlertPending, alerts[1].State)
+
+	// the counter is reported without changes
+	e.Observe(counter("PollCount", 5))
+	alerts = e.Alerts()
+	require.Equal(t, 0.0, *alerts[1].Value)
+	require.Equal(t, entity.AlertPending, alerts[1].State)
+
+	clock.Add(5 * time.Minute)
+	e.Observe(counter("PollCount", 5))
+	require.Equal(t, entity.AlertFiring, e.Alerts()[1].State)
+}
+
+func TestEngine_RateWindow(t *testing.T) {
+	rule, err := NewRule("Slow", "rate(PollCount[5m]) < 1", 0)
+	require.NoError(t, err)
+
+	e, clock := testEngine(t, rule)
+
+	// a single sample is no data for the rate
+	e.Observe(counter("PollCount", 10))
+	a := e.Alerts()[0]
+	require.Equal(t, entity.AlertInactive, a.State)
+	require.Nil(t, a.Value)
+
+	// the samples are kept for the window of the rule
+	clock.Add(2 * time.Minute)
+	e.Observe(counter("PollCount", 70))
+	a = e.Alerts()[0]
+	require.Equal(t, 0.5, *a.Value)
+	require.Equal(t, entity.AlertFiring, a.State)
+
+	// no samples within the window is the zero rate
+	clock.Add(10 * time.Minute)
+	evaluate(e)
+	a = e.Alerts()[0]
+	require.Equal(t, entity.AlertFiring, a.State)
+	require.Equal(t, 0.0, *a.Value)
+}
+
+func TestEngine_StoppedCounter(t *testing.T) {
+	rule, err := NewRule("AgentStalled", "rate(PollCount) == 0", 5*time.Minute)
+	require.NoError(t, err)
+
+	e, clock := testEngine(t, rule)
+
+	// the counter never observed is no data
+	clock.Add(10 * time.Minute)
+	evaluate(e)
+	require.Equal(t, entity.AlertInactive, e.Alerts()[0].State)
+
+	for i := 0; i < 3; i++ {
+		e.Observe(counter("PollCount", int64(10*i)))
+		clock.Add(10 * time.Second)
+	}
+	require.Equal(t, entity.AlertInactive, e.Alerts()[0].State)
+
+	// the counter stops arriving
+	clock.Add(time.Minute)
+	evaluate(e)
+	require.Equal(t, entity.AlertPending, e.Alerts()[0].State)
+
+	clock.Add(5 * time.Minute)
+	evaluate(e)
+	require.Equal(t, entity.AlertFiring, e.Alerts()[0].State)
+}
+
+func TestEngine_StaleValue(t *testing.T) {
+	firing, err := NewRule("HighHeap", "HeapAlloc > 100", 0)
+	require.NoError(t, err)
+
+	pending, err := NewRule("LowMemory", "FreeMemory < 10", time.Hour)
+	require.NoError(t, err)
+
+	e, clock := testEngine(t, firing, pending)
+	e.Observe(gauge("HeapAlloc", 200))
+	e.Observe(gauge("FreeMemory", 1))
+
+	alerts := e.Alerts()
+	require.Equal(t, entity.AlertFiring, alerts[0].State)
+	require.Equal(t, entity.AlertPending, alerts[1].State)
+
+	// the alerts without data are resolved
+	clock.Add(defaultStaleAfter + time.Second)
+	evaluate(e)
+
+	alerts = e.Alerts()
+	require.Equal(t, entity.AlertResolved, alerts[0].State)
+	require.NotNil(t, alerts[0].ResolvedAt)
+	require.Nil(t, alerts[0].Value)
+	require.Equal(t, entity.AlertInactive, alerts[1].State)
+	require.Nil(t, alerts[1].Value)
+}
+
+func TestEngine_NotifyWithoutLock(t *testing.T) {
+	rule, err := NewRule("HighHeap", "HeapAlloc > 100", 0)
+	require.NoError(t, err)
+
+	e, _ := testEngine(t, rule)
+
+	var notified []entity.Alert
+	e.Notify(notifierFunc(func(a entity.Alert) {
+		// the notifier may call the engine
+		notified = append(notified, e.Alerts()...)
+	}))
+
+	e.Observe(gauge("HeapAlloc", 200))
+	require.Len(t, notified, 1)
+	require.Equal(t, entity.AlertFiring, notified[0].State)
+}
+
+func TestEngine_NoData(t *testing.T) {
+	rule, err := NewRule("Low", "FreeMemory < 10", 0)
+	require.NoError(t, err)
+
+	e, _ := testEngine(t, rule)
+	e.Observe(gauge("HeapAlloc", 1))
+
+	a := e.Alerts()[0]
+	require.Equal(t, entity.AlertInactive, a.State)
+	require.Nil(t, a.Value)
+}
@@ -0,0 +1,153 @@
+// Package alert provides the evaluation of alerting rules against the metrics accepted by the server.
+package alert
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Comparison operators of the rule expressions.
+const (
+	opGreater      = ">"
+	opGreaterEqual = ">="
+	opLess         = "<"
+	opLessEqual    = "<="
+	opEqual        = "=="
+	opNotEqual     = "!="
+)
+
+// fnRate is the function of the rule expressions computing the per-second rate of a counter.
+const fnRate = "rate"
+
+// defaultRateWindow is the window of the rate without the window in the expression.
+const defaultRateWindow = time.Minute
+
+var (
+	ErrInvalidRule = errors.New("invalid alerting rule")
+
+	exprRe = regexp.MustCompile(
+		`^\s*(?:(rate)\(\s*([^()\[\]\s]+)\s*(?:\[\s*([^\[\]\s]+)\s*\])?\s*\)|([^()\s<>=!]+))` +
+			`\s*(>=|<=|==|!=|>|<)\s*(\S+)\s*$`)
+)
+
+// Rule is an alerting rule, e.g. `HeapAlloc > 1e9` or `rate(PollCount[5m]) == 0`.
+// The rate is computed over the window in the brackets, one minute by default;
+// it is zero if the counter is not reported within the window.
+// The alert fires when the condition holds for the duration For.
+type Rule struct {
+	Name string        `yaml:"name"`
+	Expr string        `yaml:"expr"`
+	For  time.Duration `yaml:"for"`
+
+	fn        string
+	metrics   string
+	window    time.Duration
+	op        string
+	threshold float64
+}
+
+type rulesFile struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// LoadRules loads the alerting rules from the YAML file.
+//
+//	rules:
+//	  - name: HighHeap
+//	    expr: HeapAlloc > 1e9
+//	    for: 2m
+func LoadRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read alerting rules file: %w", err)
+	}
+
+	var f rulesFile
+	if err = yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("error unmarshalling alerting rules file: %w", err)
+	}
+
+	names := make(map[string]struct{}, len(f.Rules))
+	for i := range f.Rules {
+		if err = f.Rules[i].parse(); err != nil {
+			return nil, err
+		}
+		if _, ok := names[f.Rules[i].Name]; ok {
+			return nil, fmt.Errorf("%w: duplicate name %q", ErrInvalidRule, f.Rules[i].Name)
+		}
+		names[f.Rules[i].Name] = struct{}{}
+	}
+
+	return f.Rules, nil
+}
+
+// NewRule creates an alerting rule from the expression.
+func NewRule(name, expr string, forDuration time.Duration) (Rule, error) {
+	r := Rule{Name: name, Expr: expr, For: forDuration}
+	return r, r.parse()
+}
+
+func (r *Rule) parse() error {
+	if r.Name == "" {
+		return fmt.Errorf("%w: empty name", ErrInvalidRule)
+	}
+
+	if r.For < 0 {
+		return fmt.Errorf("%w %s: negative duration", ErrInvalidRule, r.Name)
+	}
+
+	m := exprRe.FindStringSubmatch(r.Expr)
+	if m == nil {
+		return fmt.Errorf("%w %s: cannot parse expression %q", ErrInvalidRule, r.Name, r.Expr)
+	}
+
+	threshold, err := strconv.ParseFloat(m[6], 64)
+	if err != nil {
+		return fmt.Errorf("%w %s: cannot parse threshold %q", ErrInvalidRule, r.Name, m[6])
+	}
+
+	r.fn, r.metrics = m[1], m[2]
+	if r.fn == "" {
+		r.metrics = m[4]
+	}
+
+	if r.fn == fnRate {
+		r.window = defaultRateWindow
+		if m[3] != "" {
+			r.window, err = time.ParseDuration(m[3])
+			if err != nil || r.window <= 0 {
+				return fmt.Errorf("%w %s: invalid rate window %q", ErrInvalidRule, r.Name, m[3])
+			}
+		}
+	}
+
+	r.op = m[5]
+	r.threshold = threshold
+
+	return nil
+}
+
+func (r *Rule) holds(value float64) bool {
+	switch r.op {
+	case opGreater:
+		return value > r.threshold
+	case opGreaterEqual:
+		return value >= r.threshold
+	case opLess:
+		return value < r.threshold
+	case opLessEqual:
+		return value <= r.threshold
+	case opEqual:
+		return value == r.threshold
+	case opNotEqual:
+		return value != r.threshold
+	default:
+		return false
+	}
+}
@@ -0,0 +1,68 @@
+package alert
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRule(t *testing.T) {
+	tests := []struct {
+		expr      string
+		fn        string
+		metrics   string
+		window    time.Duration
+		op        string
+		threshold float64
+		wantErr   bool
+	}{
+		{expr: "HeapAlloc > 1e9", metrics: "HeapAlloc", op: ">", threshold: 1e9},
+		{expr: "rate(PollCount) == 0", fn: "rate", metrics: "PollCount", window: time.Minute, op: "==", threshold: 0},
+		{expr: "rate(PollCount[5m]) == 0", fn: "rate", metrics: "PollCount", window: 5 * time.Minute, op: "==", threshold: 0},
+		{expr: "  CPUutilization1>=90.5 ", metrics: "CPUutilization1", op: ">=", threshold: 90.5},
+		{expr: "rate( PollCount )<1", fn: "rate", metrics: "PollCount", window: time.Minute, op: "<", threshold: 1},
+		{expr: "rate(PollCount[soon]) == 0", wantErr: true},
+		{expr: "rate(PollCount[0s]) == 0", wantErr: true},
+		{expr: "HeapAlloc => 1", wantErr: true},
+		{expr: "HeapAlloc > big", wantErr: true},
+		{expr: "avg(HeapAlloc) > 1", wantErr: true},
+		{expr: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		r, err := NewRule("rule", tt.expr, time.Minute)
+		if tt.wantErr {
+			require.ErrorIs(t, err, ErrInvalidRule, tt.expr)
+			continue
+		}
+		require.NoError(t, err, tt.expr)
+		require.Equal(t, tt.fn, r.fn)
+		require.Equal(t, tt.metrics, r.metrics)
+		require.Equal(t, tt.window, r.window)
+		require.Equal(t, tt.op, r.op)
+		require.Equal(t, tt.threshold, r.threshold)
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	rules, err := LoadRules("../../configs/alerts.yml")
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	require.Equal(t, "HighHeapAlloc", rules[0].Name)
+	require.Equal(t, 2*time.Minute, rules[0].For)
+	require.Equal(t, fnRate, rules[1].fn)
+
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "rules.yml")
+	err = os.WriteFile(path, []byte("rules:\n  - name: a\n    expr: x > 1\n  - name: a\n    expr: y > 1\n"), 0644)
+	require.NoError(t, err)
+
+	_, err = LoadRules(path)
+	require.ErrorIs(t, err, ErrInvalidRule)
+
+	_, err = LoadRules(filepath.Join(tempDir, "missing.yml"))
+	require.Error(t, err)
+}
@@ -0,0 +1,234 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/vladislaoramos/alemetric/internal/entity"
+	logger "github.com/vladislaoramos/alemetric/pkg/log"
+)
+
+const (
+	// HashHeader carries the HMAC-SHA256 sign of the webhook payload made with the server key.
+	HashHeader = "HashSHA256"
+	// IDHeader carries the identifier of the notification, the same for its retries.
+	IDHeader = "Idempotency-Key"
+
+	defaultWebhookAttempts = 3
+	defaultWebhookBackoff  = time.Second
+	defaultWebhookTimeout  = 5 * time.Second
+	webhookQueueSize       = 100
+	webhookDedupSize       = 1000
+	webhookDedupTTL        = time.Hour
+)
+
+// Notifier is notified about the changes of the alert states.
+// Notify must not block the engine.
+type Notifier interface {
+	Notify(entity.Alert)
+}
+
+// Notification is the payload of the webhook request.
+type Notification struct {
+	ID     string       `json:"id"`
+	Server string       `json:"server"`
+	Alert  entity.Alert `json:"alert"`
+}
+
+// WebhookNotifier posts the changes of the alert states to the webhook URLs.
+// Failed requests are retried with exponential backoff;
+// a change of the state is delivered at most once.
+type WebhookNotifier struct {
+	urls     []string
+	server   string
+	client   *http.Client
+	attempts int
+	backoff  time.Duration
+	l        logger.LogInterface
+
+	queue chan Notification
+
+	keyMu sync.RWMutex
+	key   string
+
+	mu   sync.Mutex
+	sent map[string]time.Time // the notification IDs with the times they are queued at
+	now  func() time.Time
+}
+
+// NewWebhookNotifier creates a notifier for the webhook URLs.
+// If the key is not empty, the requests are signed with it.
+func NewWebhookNotifier(urls []string, server, key string, l logger.LogInterface) *WebhookNotifier {
+	return &WebhookNotifier{
+		urls:     urls,
+		server:   server,
+		key:      key,
+		client:   &http.Client{Timeout: defaultWebhookTimeout},
+		attempts: defaultWebhookAttempts,
+		backoff:  defaultWebhookBackoff,
+		l:        l,
+		queue:    make(chan Notification, webhookQueueSize),
+		sent:     make(map[string]time.Time),
+		now:      time.Now,
+	}
+}
+
+// SetKey changes the key the requests are signed with; they are not signed if the key is empty.
+func (n *WebhookNotifier) SetKey(key string) {
+	n.keyMu.Lock()
+	defer n.keyMu.Unlock()
+
+	n.key = key
+}
+
+// Notify queues the notification about the alert state.
+// A repeated notification about the same change of the state is skipped.
+func (n *WebhookNotifier) Notify(a entity.Alert) {
+	id := notificationID(a)
+
+	n.mu.Lock()
+	if _, ok := n.sent[id]; ok {
+		n.mu.Unlock()
+		return
+	}
+	now := n.now()
+	n.evictSent(now)
+	n.sent[id] = now
+	n.mu.Unlock()
+
+	select {
+	case n.queue <- Notification{ID: id, Server: n.server, Alert: a}:
+	default:
+		n.l.Error(fmt.Sprintf("webhook queue is full, notification %s about alert %s is dropped", id, a.Name))
+	}
+}
+
+// evictSent removes the IDs of the notifications queued longer than the dedup TTL ago,
+// and the oldest ones if there are still too many of them.
+func (n *WebhookNotifier) evictSent(now time.Time) {
+	for id, at := range n.sent {
+		if now.Sub(at) > webhookDedupTTL {
+			delete(n.sent, id)
+		}
+	}
+
+	for len(n.sent) >= webhookDedupSize {
+		var oldest string
+		for id, at := range n.sent {
+			if oldest == "" || at.Before(n.sent[oldest]) {
+				oldest = id
+			}
+		}
+		delete(n.sent, oldest)
+	}
+}
+
+// Run sends the queued notifications until the context is done.
+func (n *WebhookNotifier) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case notification := <-n.queue:
+			body, err := json.Marshal(notification)
+			if err != nil {
+				n.l.Error(fmt.Sprintf("error marshalling webhook notification: %s", err))
+				continue
+			}
+
+			for _, url := range n.urls {
+				if err = n.send(ctx, url, notification.ID, body); err != nil {
+					n.l.Error(fmt.Sprintf("error sending webhook notification %s to %s: %s", notification.ID, url, err))
+				}
+			}
+		}
+	}
+}
+
+// send posts the notification retrying on network errors and server errors.
+func (n *WebhookNotifier) send(ctx context.Context, url, id string, body []byte) error {
+	backoff := n.backoff
+
+	var err error
+	for attempt := 1; attempt <= n.attempts; attempt++ {
+		var retry bool
+		retry, err = n.post(ctx, url, id, body)
+		if err == nil || !retry {
+			return err
+		}
+
+		if attempt == n.attempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+
+	return fmt.Errorf("%d attempts failed: %w", n.attempts, err)
+}
+
+func (n *WebhookNotifier) post(ctx context.Context, url, id string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("cannot create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(IDHeader, id)
+	n.keyMu.RLock()
+	key := n.key
+	n.keyMu.RUnlock()
+	if key != "" {
+		req.Header.Set(HashHeader, Sign(body, key))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("cannot send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests:
+		return true, fmt.Errorf("not successful status code: %d", resp.StatusCode)
+	case resp.StatusCode >= http.StatusBadRequest:
+		return false, fmt.Errorf("not successful status code: %d", resp.StatusCode)
+	}
+
+	return false, nil
+}
+
+// Sign makes the HMAC-SHA256 sign of the webhook payload.
+func Sign(body []byte, key string) string {
+	h := hmac.New(sha256.New, []byte(key))
+	h.Write(body)
+
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// notificationID identifies the change of the alert state by the time of the change.
+func notificationID(a entity.Alert) string {
+	at := timeOf(a.ChangedAt)
+
+	h := sha256.Sum256([]byte(fmt.Sprintf("%s:%s:%d", a.Name, a.State, at.UnixNano())))
+	return fmt.Sprintf("%x", h[:8])
+}
+
+func timeOf(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
@@ -0,0 +1,201 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vladislaoramos/alemetric/internal/entity"
+	logger "github.com/vladislaoramos/alemetric/pkg/log"
+)
+
+type recorder struct {
+	mu       sync.Mutex
+	failures int
+	calls    int
+	received []Notification
+}
+
+func (rec *recorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	rec.calls++
+	if rec.failures > 0 {
+		rec.failures--
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	if r.Header.Get(HashHeader) != Sign(body, "secret") {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var n Notification
+	if err := json.Unmarshal(body, &n); err != nil || n.ID != r.Header.Get(IDHeader) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	rec.received = append(rec.received, n)
+}
+
+func (rec *recorder) notifications() []Notification {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return append([]Notification(nil), rec.received...)
+}
+
+func testNotifier(t *testing.T, rec *recorder) *WebhookNotifier {
+	srv := httptest.NewServer(rec)
+	t.Cleanup(srv.Close)
+
+	n := NewWebhookNotifier([]string{srv.URL}, "test-server", "secret", logger.New("error", io.Discard))
+	n.backoff = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go n.Run(ctx)
+
+	return n
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	r, err := NewRule("HighHeap", "HeapAlloc > 100", 0)
+	require.NoError(t, err)
+
+	t.Run("notifies on state changes", func(t *testing.T) {
+		rec := &recorder{}
+		e, clock := testEngine(t, r)
+		e.Notify(testNotifier(t, rec))
+
+		e.Observe(gauge("HeapAlloc", 200))
+		e.Observe(gauge("HeapAlloc", 300))
+		clock.Add(time.Minute)
+		e.Observe(gauge("HeapAlloc", 50))
+
+		require.Eventually(t, func() bool {
+			return len(rec.notifications()) == 2
+		}, time.Second, 10*time.Millisecond)
+
+		got := rec.notifications()
+		require.Equal(t, "test-server", got[0].Server)
+		require.Equal(t, entity.AlertFiring, got[0].Alert.State)
+		require.Equal(t, entity.AlertResolved, got[1].Alert.State)
+		require.NotEqual(t, got[0].ID, got[1].ID)
+	})
+
+	t.Run("retries failed requests", func(t *testing.T) {
+		rec := &recorder{failures: 2}
+		e, _ := testEngine(t, r)
+		e.Notify(testNotifier(t, rec))
+
+		e.Observe(gauge("HeapAlloc", 200))
+
+		require.Eventually(t, func() bool {
+			return len(rec.notifications()) == 1
+		}, time.Second, 10*time.Millisecond)
+
+		rec.mu.Lock()
+		require.Equal(t, 3, rec.calls)
+		rec.mu.Unlock()
+	})
+
+	t.Run("skips duplicates", func(t *testing.T) {
+		rec := &recorder{}
+		n := testNotifier(t, rec)
+
+		at := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+		a := entity.Alert{Name: "HighHeap", State: entity.AlertFiring, ActiveAt: &at, FiredAt: &at, ChangedAt: &at}
+		n.Notify(a)
+		n.Notify(a)
+
+		later := at.Add(time.Minute)
+		a.FiredAt, a.ChangedAt = &later, &later
+		n.Notify(a)
+
+		require.Eventually(t, func() bool {
+			return len(rec.notifications()) == 2
+		}, time.Second, 10*time.Millisecond)
+		require.Never(t, func() bool {
+			return len(rec.notifications()) > 2
+		}, 100*time.Millisecond, 10*time.Millisecond)
+	})
+
+	t.Run("signs with the changed key", func(t *testing.T) {
+		rec := &recorder{}
+		n := testNotifier(t, rec)
+		n.SetKey("changed")
+
+		at := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+		n.Notify(entity.Alert{Name: "HighHeap", State: entity.AlertFiring, ChangedAt: &at})
+
+		// the recorder rejects the requests not signed with the initial key
+		require.Eventually(t, func() bool {
+			rec.mu.Lock()
+			defer rec.mu.Unlock()
+			return rec.calls == 1
+		}, time.Second, 10*time.Millisecond)
+		require.Empty(t, rec.notifications())
+
+		n.SetKey("secret")
+		later := at.Add(time.Minute)
+		n.Notify(entity.Alert{Name: "HighHeap", State: entity.AlertFiring, ChangedAt: &later})
+
+		require.Eventually(t, func() bool {
+			return len(rec.notifications()) == 1
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("notifies on every return to inactive", func(t *testing.T) {
+		pending, err := NewRule("HighHeap", "HeapAlloc > 100", time.Hour)
+		require.NoError(t, err)
+
+		rec := &recorder{}
+		e, clock := testEngine(t, pending)
+		e.Notify(testNotifier(t, rec))
+
+		for i := 0; i < 2; i++ {
+			e.Observe(gauge("HeapAlloc", 200))
+			clock.Add(time.Minute)
+			e.Observe(gauge("HeapAlloc", 50))
+			clock.Add(time.Minute)
+		}
+
+		require.Eventually(t, func() bool {
+			return len(rec.notifications()) == 4
+		}, time.Second, 10*time.Millisecond)
+
+		got := rec.notifications()
+		require.Equal(t, entity.AlertInactive, got[1].Alert.State)
+		require.Equal(t, entity.AlertInactive, got[3].Alert.State)
+		require.NotEqual(t, got[1].ID, got[3].ID)
+	})
+}
+
+func TestWebhookNotifier_EvictSent(t *testing.T) {
+	n := NewWebhookNotifier(nil, "test-server", "", logger.New("error", io.Discard))
+	now := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	n.sent["old"] = now.Add(-2 * webhookDedupTTL)
+	n.sent["recent"] = now.Add(-time.Minute)
+	n.evictSent(now)
+	require.Equal(t, map[string]time.Time{"recent": now.Add(-time.Minute)}, n.sent)
+
+	// the oldest IDs are evicted when there are too many of them
+	for i := 0; i < webhookDedupSize; i++ {
+		n.sent[fmt.Sprint(i)] = now
+	}
+	n.evictSent(now)
+	require.Len(t, n.sent, webhookDedupSize-1)
+	require.NotContains(t, n.sent, "recent")
+}
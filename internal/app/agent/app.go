@@ -2,6 +2,9 @@
 package agent
 
 import (
+	"context"
+	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -14,26 +17,127 @@ import (
 
 const urlProtocol = "http://"
 
+// BuildVersion is the version of the agent reported to the server.
+var BuildVersion = "N/A"
+
 // Run method launches the client application.
 func Run(cfg *configs.Config, lgr *logger.Logger) {
-	metrics := NewMetrics()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-	client := resty.New().SetBaseURL(urlProtocol + cfg.Agent.ServerURL)
+	logTail, err := NewLogTailCollector(cfg.Agent.Logs, cfg.Agent.LogOffsets)
+	if err != nil {
+		lgr.Fatal(err.Error())
+	}
+	defer logTail.Close()
 
-	webAPI := NewWebAPI(client, cfg.Agent.Key, cfg.Agent.CryptoKey)
+	aggregates, err := ParseAggregates(cfg.Agent.GaugeAggregates)
+	if err != nil {
+		lgr.Fatal(err.Error())
+	}
+
+	telemetry := NewTelemetry()
+
+	registry := NewRegistry(lgr, AggregateGauges(aggregates...), CollectTelemetry(telemetry))
+	registry.RegisterAll(cfg.Agent,
+		telemetry,
+		NewRuntimeCollector(),
+		NewGopsutilCollector(),
+		NewRandomCollector(),
+		NewLoadCollector(),
+		NewDiskCollector(),
+		NewNetCollector(),
+		NewSwapCollector(),
+		NewProcessesCollector(),
+		NewProcstatCollector(cfg.Agent.Processes),
+		NewPrometheusCollector(cfg.Agent.Scrape),
+		logTail,
+	)
+
+	for _, cmd := range cfg.Agent.Commands {
+		registry.Register(NewExecCollector(cmd), cmd.Interval)
+	}
+
+	if cfg.Agent.StatsDAddress != "" || cfg.Agent.StatsDSocket != "" {
+		statsd := NewStatsDCollector(lgr)
+		if err := statsd.Listen(ctx, cfg.Agent.StatsDAddress, cfg.Agent.StatsDSocket); err != nil {
+			lgr.Fatal(err.Error())
+		}
+		registry.SetReportInterval(cfg.Agent.ReportInterval)
+		registry.RegisterReported(statsd)
+	}
+
+	host, err := os.Hostname()
+	if err != nil {
+		lgr.Error(fmt.Sprintf("cannot get host name: %s", err))
+	}
+
+	client := resty.New().
+		SetBaseURL(urlProtocol+cfg.Agent.ServerURL).
+		SetHeader(AgentHeader, cfg.Agent.Name).
+		SetHeader(AgentVersionHeader, BuildVersion).
+		SetHeader(AgentHostHeader, host)
+	if cfg.Agent.Group != "" {
+		client.SetHeader(AgentGroupHeader, cfg.Agent.Group)
+	}
 
-	worker := NewWorker(lgr, metrics, cfg.Agent.MetricsNames, webAPI, cfg.RateLimit)
+	webAPI := NewWebAPI(client, cfg.Agent.Key, cfg.Agent.CryptoKey)
 
-	updateTicker := time.NewTicker(cfg.Agent.PollInterval)
-	go worker.UpdateMetrics(updateTicker)
-	go worker.UpdateAdditionalMetrics(updateTicker)
+	workerOpts := []WorkerOption{ReportTelemetry(telemetry)}
+	if cfg.Agent.ChangesOnly {
+		workerOpts = append(workerOpts, SendChangesOnly(cfg.Agent.ChangeEpsilon, cfg.Agent.HeartbeatReports))
+	}
+	worker := NewWorker(lgr, registry, cfg.Agent.MetricsNames, webAPI, cfg.RateLimit, workerOpts...)
 
 	sendTicker := time.NewTicker(cfg.Agent.ReportInterval)
+
+	remote := NewRemoteConfig(cfg.Agent, webAPI, registry, worker, sendTicker, lgr)
+	if cfg.Agent.RemoteConfigInterval > 0 {
+		if err := remote.Update(); err != nil {
+			lgr.Error(err.Error())
+		}
+		go remote.Run(ctx, cfg.Agent.RemoteConfigInterval)
+	}
+
+	// the changes of the intervals, the metrics names, the rate limit, the server address and the logger level
+	// are applied; the other changes require the restart
+	go cfg.ReloadOnChange(ctx, lgr, func(next *configs.Config) error {
+		lgr.SetLevel(next.Level)
+		remote.SetLocal(next.Agent)
+		webAPI.SetServerURL(urlProtocol + next.Agent.ServerURL)
+		return nil
+	})
+
+	registry.Run(ctx)
 	go worker.SendMetrics(sendTicker)
 
+	if cfg.Agent.PushAddress != "" {
+		if err := configs.CheckLoopbackAddress(cfg.Agent.PushAddress); err != nil {
+			lgr.Fatal(fmt.Sprintf("push endpoint: %s", err))
+		}
+		srv := serve(cfg.Agent.PushAddress, "push", NewPushHandler(registry, lgr), lgr)
+		defer srv.Shutdown(context.Background())
+	}
+
+	if cfg.Agent.StatusAddress != "" {
+		srv := serve(cfg.Agent.StatusAddress, "status", NewStatusHandler(telemetry), lgr)
+		defer srv.Shutdown(context.Background())
+	}
+
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
 	stop := <-sigs
 
 	lgr.Info("Agent got stop signal: " + stop.String())
 }
+
+// serve starts the local endpoint of the agent.
+func serve(addr, name string, handler http.Handler, lgr *logger.Logger) *http.Server {
+	srv := &http.Server{Addr: addr, Handler: handler}
+	go func() {
+		if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+			lgr.Fatal(fmt.Sprintf("%s endpoint error: %s", name, err))
+		}
+	}()
+	return srv
+}
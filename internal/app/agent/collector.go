@@ -0,0 +1,458 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vladislaoramos/alemetric/configs"
+	"github.com/vladislaoramos/alemetric/internal/entity"
+	"github.com/vladislaoramos/alemetric/internal/usecase"
+	logger "github.com/vladislaoramos/alemetric/pkg/log"
+)
+
+// Collector collects a set of metrics.
+// Gauges are the current values, counters are the increments since the previous collection.
+type Collector interface {
+	Name() string
+	Collect(context.Context) ([]entity.Metrics, error)
+}
+
+// UnfilteredCollector is a collector of the metrics defined by the configuration of the agent
+// rather than built into it. Its metrics are sent regardless of the metrics names of the worker.
+type UnfilteredCollector interface {
+	Collector
+	Unfiltered()
+}
+
+type registration struct {
+	collector Collector
+	interval  time.Duration
+	// polled and reported tell if the collector runs every poll interval or every report interval
+	polled   bool
+	reported bool
+	ticker   *time.Ticker
+}
+
+// Gauge aggregates sent by the registry with the last value of each gauge.
+const (
+	AggregateMin = "min"
+	AggregateMax = "max"
+	AggregateAvg = "avg"
+)
+
+// ErrUnknownAggregate is returned for an aggregate other than min, max and avg.
+var ErrUnknownAggregate = errors.New("unknown gauge aggregate")
+
+// gaugeWindow keeps the values of a gauge collected since the previous report.
+type gaugeWindow struct {
+	min, max, sum float64
+	count         int
+}
+
+func (w *gaugeWindow) add(v float64) {
+	if w.count == 0 || v < w.min {
+		w.min = v
+	}
+	if w.count == 0 || v > w.max {
+		w.max = v
+	}
+	w.sum += v
+	w.count++
+}
+
+// RegistryOption configures the registry.
+type RegistryOption func(r *Registry)
+
+// AggregateGauges sets the aggregation of the gauges between the reports.
+// Each gauge is sent with its aggregates over the values collected since the previous report
+// as the gauges with the suffixed names, e.g. HeapAlloc_max or DiskUsed_avg{mount="/"}.
+// A gauge not collected since the previous report has its last value as all aggregates.
+func AggregateGauges(aggregates ...string) RegistryOption {
+	return func(r *Registry) {
+		r.aggregates = aggregates
+	}
+}
+
+// CollectTelemetry sets the recording of the collections to the telemetry.
+func CollectTelemetry(t *Telemetry) RegistryOption {
+	return func(r *Registry) {
+		r.telemetry = t
+	}
+}
+
+// ParseAggregates checks the names of the gauge aggregates.
+func ParseAggregates(aggregates []string) ([]string, error) {
+	for _, a := range aggregates {
+		switch a {
+		case AggregateMin, AggregateMax, AggregateAvg:
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrUnknownAggregate, a)
+		}
+	}
+	return aggregates, nil
+}
+
+// Registry runs the collectors and stores the collected metrics until they are sent.
+// The last value of each gauge is kept and sent in every report;
+// the increments of each counter are summed up until the next report.
+// The metrics of the unfiltered collectors and the pushed ones are marked as unfiltered.
+type Registry struct {
+	l          logger.LogInterface
+	aggregates []string
+	telemetry  *Telemetry
+
+	regMu          sync.Mutex
+	registrations  []*registration
+	pollInterval   time.Duration
+	reportInterval time.Duration
+
+	mu         sync.Mutex
+	gauges     map[string]entity.Gauge
+	counters   map[string]entity.Counter
+	windows    map[string]*gaugeWindow
+	unfiltered map[string]struct{}
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry(l logger.LogInterface, opts ...RegistryOption) *Registry {
+	r := &Registry{
+		l:          l,
+		gauges:     make(map[string]entity.Gauge),
+		counters:   make(map[string]entity.Counter),
+		windows:    make(map[string]*gaugeWindow),
+		unfiltered: make(map[string]struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Register adds the collector running every interval.
+// The collector without interval runs every poll interval set by RegisterAll and SetPollInterval.
+func (r *Registry) Register(c Collector, interval time.Duration) {
+	r.regMu.Lock()
+	defer r.regMu.Unlock()
+
+	reg := &registration{collector: c, interval: interval}
+	if interval <= 0 {
+		reg.interval, reg.polled = r.pollInterval, true
+	}
+	r.registrations = append(r.registrations, reg)
+}
+
+// RegisterAll sets the poll interval and adds the enabled collectors according to their settings.
+// The collectors without interval run every poll interval.
+func (r *Registry) RegisterAll(cfg configs.Agent, collectors ...Collector) {
+	r.SetPollInterval(cfg.PollInterval)
+
+	for _, c := range collectors {
+		settings := cfg.Collectors[c.Name()]
+		if settings.Disabled {
+			r.l.Info(fmt.Sprintf("Collector %s is disabled", c.Name()))
+			continue
+		}
+
+		r.Register(c, settings.Interval)
+	}
+}
+
+// RegisterReported adds the collector running every report interval set by SetReportInterval,
+// e.g. the one collecting the metrics received between the reports.
+func (r *Registry) RegisterReported(c Collector) {
+	r.regMu.Lock()
+	defer r.regMu.Unlock()
+
+	r.registrations = append(r.registrations, &registration{collector: c, interval: r.reportInterval, reported: true})
+}
+
+// SetPollInterval changes the interval of the collectors running every poll interval,
+// including the running ones.
+func (r *Registry) SetPollInterval(interval time.Duration) {
+	r.regMu.Lock()
+	defer r.regMu.Unlock()
+
+	r.pollInterval = interval
+	r.resetIntervals(interval, func(reg *registration) bool {
+		return reg.polled
+	})
+}
+
+// SetReportInterval changes the interval of the collectors running every report interval,
+// including the running ones.
+func (r *Registry) SetReportInterval(interval time.Duration) {
+	r.regMu.Lock()
+	defer r.regMu.Unlock()
+
+	r.reportInterval = interval
+	r.resetIntervals(interval, func(reg *registration) bool {
+		return reg.reported
+	})
+}
+
+func (r *Registry) resetIntervals(interval time.Duration, match func(reg *registration) bool) {
+	for _, reg := range r.registrations {
+		if !match(reg) {
+			continue
+		}
+		reg.interval = interval
+		if reg.ticker != nil {
+			reg.ticker.Reset(interval)
+		}
+	}
+}
+
+// Run runs every collector at its interval until the context is done.
+func (r *Registry) Run(ctx context.Context) {
+	r.regMu.Lock()
+	defer r.regMu.Unlock()
+
+	for _, reg := range r.registrations {
+		reg.ticker = time.NewTicker(reg.interval)
+		go r.run(ctx, reg.collector, reg.ticker)
+	}
+}
+
+func (r *Registry) run(ctx context.Context, c Collector, ticker *time.Ticker) {
+	defer ticker.Stop()
+
+	for {
+		r.collect(ctx, c)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Registry) collect(ctx context.Context, c Collector) {
+	start := time.Now()
+	items, err := c.Collect(ctx)
+	if r.telemetry != nil {
+		r.telemetry.ObserveCollection(c.Name(), start, time.Since(start), err)
+	}
+	if err != nil {
+		r.l.Error(fmt.Sprintf("error collecting metrics by %s: %s", c.Name(), err))
+	}
+
+	if _, ok := c.(UnfilteredCollector); ok {
+		r.PushUnfiltered(items...)
+	} else {
+		r.Push(items...)
+	}
+	r.l.Info(fmt.Sprintf("Metrics collected by %s", c.Name()))
+}
+
+// Push merges the metrics into the registry:
+// a gauge replaces the stored value, a counter is added to it.
+// The merged metrics are filtered by the metrics names of the worker.
+func (r *Registry) Push(items ...entity.Metrics) {
+	r.push(false, items)
+}
+
+// PushUnfiltered merges the metrics into the registry like Push
+// and marks them as unfiltered, e.g. the metrics pushed by the applications.
+func (r *Registry) PushUnfiltered(items ...entity.Metrics) {
+	r.push(true, items)
+}
+
+// push merges the metrics into the registry. The mark of a stored metrics follows the last merged value,
+// so that a filtered metrics with the ID of an unfiltered one is filtered again.
+func (r *Registry) push(unfiltered bool, items []entity.Metrics) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, m := range items {
+		switch {
+		case m.MType == usecase.Gauge && m.Value != nil:
+			r.gauges[m.ID] = *m.Value
+			if len(r.aggregates) != 0 {
+				w, ok := r.windows[m.ID]
+				if !ok {
+					w = &gaugeWindow{}
+					r.windows[m.ID] = w
+				}
+				w.add(float64(*m.Value))
+			}
+		case m.MType == usecase.Counter && m.Delta != nil:
+			r.counters[m.ID] += *m.Delta
+		default:
+			continue
+		}
+
+		if unfiltered {
+			r.unfiltered[m.ID] = struct{}{}
+		} else {
+			delete(r.unfiltered, m.ID)
+		}
+	}
+}
+
+// Unfiltered tells if the metrics, or the gauge the aggregate is made of, is marked as unfiltered.
+func (r *Registry) Unfiltered(id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.unfiltered[id]; ok {
+		return true
+	}
+
+	base, ok := aggregateOf(id)
+	if !ok {
+		return false
+	}
+	_, ok = r.unfiltered[base]
+	return ok
+}
+
+// Flush returns the stored metrics sorted by name and resets the counters and the gauge aggregates.
+func (r *Registry) Flush() []entity.Metrics {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// the marks of the metrics not pushed since the previous flush are removed,
+	// the ones of the flushed counters are kept for the selection of the flushed metrics
+	for id := range r.unfiltered {
+		_, gauge := r.gauges[id]
+		_, counter := r.counters[id]
+		if !gauge && !counter {
+			delete(r.unfiltered, id)
+		}
+	}
+
+	res := make([]entity.Metrics, 0, len(r.gauges)*(len(r.aggregates)+1)+len(r.counters))
+	for name, value := range r.gauges {
+		v := value
+		res = append(res, entity.Metrics{ID: name, MType: usecase.Gauge, Value: &v})
+		res = append(res, r.aggregated(name, float64(value))...)
+	}
+	r.windows = make(map[string]*gaugeWindow)
+
+	for name, delta := range r.counters {
+		d := delta
+		res = append(res, entity.Metrics{ID: name, MType: usecase.Counter, Delta: &d})
+	}
+	r.counters = make(map[string]entity.Counter)
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ID < res[j].ID
+	})
+
+	return res
+}
+
+// collectErrors combines the errors of a partial collection,
+// so that a collector reports every failed source and still returns the collected metrics.
+type collectErrors []error
+
+func (e collectErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e collectErrors) errorOrNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// increments converts the cumulative counters of the system into the increments since the previous collection.
+// The first collection of a counter only remembers its value.
+type increments map[string]uint64
+
+func (inc increments) counter(id string, total uint64) (entity.Metrics, bool) {
+	prev, ok := inc[id]
+	inc[id] = total
+	if !ok {
+		return entity.Metrics{}, false
+	}
+
+	delta := total - prev
+	if total < prev {
+		// the counter was reset
+		delta = total
+	}
+	return counter(id, int64(delta)), true
+}
+
+func (inc increments) append(items []entity.Metrics, id string, total uint64) []entity.Metrics {
+	if m, ok := inc.counter(id, total); ok {
+		items = append(items, m)
+	}
+	return items
+}
+
+// aggregated returns the aggregates of the gauge.
+func (r *Registry) aggregated(id string, last float64) []entity.Metrics {
+	if len(r.aggregates) == 0 {
+		return nil
+	}
+
+	w, ok := r.windows[id]
+	if !ok {
+		w = &gaugeWindow{}
+		w.add(last)
+	}
+
+	name, labels, err := entity.ParseID(id)
+	if err != nil {
+		name, labels = id, nil
+	}
+
+	res := make([]entity.Metrics, 0, len(r.aggregates))
+	for _, a := range r.aggregates {
+		var v float64
+		switch a {
+		case AggregateMin:
+			v = w.min
+		case AggregateMax:
+			v = w.max
+		case AggregateAvg:
+			v = w.sum / float64(w.count)
+		default:
+			continue
+		}
+		res = append(res, gauge(entity.FormatID(name+"_"+a, labels), v))
+	}
+
+	return res
+}
+
+// aggregateOf returns the ID of the metrics the aggregate is made of,
+// or false if the ID is not of an aggregate.
+func aggregateOf(id string) (string, bool) {
+	name, labels, err := entity.ParseID(id)
+	if err != nil {
+		return "", false
+	}
+
+	for _, a := range []string{AggregateMin, AggregateMax, AggregateAvg} {
+		if base := strings.TrimSuffix(name, "_"+a); base != name {
+			return entity.FormatID(base, labels), true
+		}
+	}
+	return "", false
+}
+
+func gauge(name string, value float64) entity.Metrics {
+	v := entity.Gauge(value)
+	return entity.Metrics{ID: name, MType: usecase.Gauge, Value: &v}
+}
+
+func counter(name string, delta int64) entity.Metrics {
+	d := entity.Counter(delta)
+	return entity.Metrics{ID: name, MType: usecase.Counter, Delta: &d}
+}
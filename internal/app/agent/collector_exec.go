@@ -0,0 +1,199 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vladislaoramos/alemetric/configs"
+	"github.com/vladislaoramos/alemetric/internal/entity"
+	"github.com/vladislaoramos/alemetric/internal/usecase"
+)
+
+const (
+	defaultExecTimeout = 10 * time.Second
+
+	// ExecErrorsCounter counts the failed runs of a command, labeled by the command name.
+	ExecErrorsCounter = "ExecErrors"
+)
+
+// ErrInvalidOutput is returned when the output of a command cannot be parsed.
+var ErrInvalidOutput = errors.New("invalid command output")
+
+// ExecCollector runs a shell command and collects the metrics it prints to stdout:
+// either the lines `name type value` or the JSON of a metrics or of a list of metrics,
+// as accepted by the server at /update/ and /updates/. The counter values are increments.
+// The counter ExecErrors{command="NAME"} is incremented when the command fails, times out
+// or prints invalid output, and is sent with zero otherwise.
+type ExecCollector struct {
+	name    string
+	command string
+	timeout time.Duration
+}
+
+// NewExecCollector creates a collector of the command.
+func NewExecCollector(cmd configs.Command) *ExecCollector {
+	timeout := cmd.Timeout
+	if timeout <= 0 {
+		timeout = defaultExecTimeout
+	}
+
+	return &ExecCollector{
+		name:    cmd.Name,
+		command: cmd.Command,
+		timeout: timeout,
+	}
+}
+
+// Name returns the name of the collector, exec:NAME.
+func (c *ExecCollector) Name() string {
+	return ExecCollectorName + ":" + c.name
+}
+
+// Unfiltered marks the metrics of the command output and its errors
+// to be sent regardless of the metrics names of the worker.
+func (c *ExecCollector) Unfiltered() {}
+
+// Collect runs the command and parses its output.
+func (c *ExecCollector) Collect(ctx context.Context) ([]entity.Metrics, error) {
+	errorsID := entity.FormatID(ExecErrorsCounter, map[string]string{"command": c.name})
+
+	items, err := c.run(ctx)
+	if err != nil {
+		return append(items, counter(errorsID, 1)), fmt.Errorf("command %s: %w", c.name, err)
+	}
+
+	return append(items, counter(errorsID, 0)), nil
+}
+
+func (c *ExecCollector) run(ctx context.Context) ([]entity.Metrics, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", c.command)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	// the output is read until the command exits or times out: a killed shell
+	// may leave children holding the pipes, so Wait closes the pipes instead of waiting for them
+	var out, errOut []byte
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errOut, _ = io.ReadAll(stderr)
+		}()
+		out, _ = io.ReadAll(stdout)
+		wg.Wait()
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+	err = cmd.Wait()
+	<-done
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("timed out after %s", c.timeout)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, bytes.TrimSpace(errOut))
+	}
+
+	return parseCommandOutput(out)
+}
+
+// parseCommandOutput parses the metrics printed by a command.
+func parseCommandOutput(out []byte) ([]entity.Metrics, error) {
+	out = bytes.TrimSpace(out)
+
+	var items []entity.Metrics
+	switch {
+	case len(out) == 0:
+		return nil, nil
+	case out[0] == '{':
+		var m entity.Metrics
+		if err := json.Unmarshal(out, &m); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidOutput, err)
+		}
+		items = []entity.Metrics{m}
+	case out[0] == '[':
+		if err := json.Unmarshal(out, &items); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidOutput, err)
+		}
+	default:
+		scanner := bufio.NewScanner(bytes.NewReader(out))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+
+			m, err := parseCommandLine(line)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, m)
+		}
+	}
+
+	for _, m := range items {
+		if err := validateMetrics(m); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidOutput, err)
+		}
+	}
+
+	return items, nil
+}
+
+// parseCommandLine parses the line `name type value`. The name may have labels.
+func parseCommandLine(line string) (entity.Metrics, error) {
+	// the labels may contain spaces, so the type and the value are the last fields
+	fields := strings.Fields(line)
+	if len(fields) < 3 {
+		return entity.Metrics{}, fmt.Errorf("%w: %q", ErrInvalidOutput, line)
+	}
+
+	mtype, value := fields[len(fields)-2], fields[len(fields)-1]
+	name := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(strings.TrimSuffix(line, value)), mtype))
+
+	switch mtype {
+	case usecase.Gauge:
+		v, err := entity.ParseGaugeMetrics(value)
+		if err != nil {
+			return entity.Metrics{}, fmt.Errorf("%w: %q: %s", ErrInvalidOutput, line, err)
+		}
+		return gauge(name, float64(v)), nil
+	case usecase.Counter:
+		v, err := entity.ParseCounterMetrics(value)
+		if err != nil {
+			return entity.Metrics{}, fmt.Errorf("%w: %q: %s", ErrInvalidOutput, line, err)
+		}
+		return counter(name, int64(v)), nil
+	}
+
+	return entity.Metrics{}, fmt.Errorf("%w: %q: unknown type %q", ErrInvalidOutput, line, mtype)
+}
@@ -0,0 +1,115 @@
+package agent
+
+import (
+	"context"
+	"io"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vladislaoramos/alemetric/configs"
+	"github.com/vladislaoramos/alemetric/internal/entity"
+	logger "github.com/vladislaoramos/alemetric/pkg/log"
+)
+
+func TestParseCommandOutput(t *testing.T) {
+	tests := []struct {
+		name string
+		out  string
+		want []entity.Metrics
+		err  bool
+	}{
+		{
+			name: "lines",
+			out:  "QueueSize gauge 12.5\nJobs{queue=\"default mail\"} counter 3\n\n",
+			want: []entity.Metrics{gauge("QueueSize", 12.5), counter(`Jobs{queue="default mail"}`, 3)},
+		},
+		{
+			name: "json",
+			out:  `{"id":"QueueSize","type":"gauge","value":1}`,
+			want: []entity.Metrics{gauge("QueueSize", 1)},
+		},
+		{
+			name: "json list",
+			out:  `[{"id":"QueueSize","type":"gauge","value":1},{"id":"Jobs","type":"counter","delta":2}]`,
+			want: []entity.Metrics{gauge("QueueSize", 1), counter("Jobs", 2)},
+		},
+		{name: "empty", out: "  \n"},
+		{name: "unknown type", out: "QueueSize summary 1", err: true},
+		{name: "bad value", out: "Jobs counter 1.5", err: true},
+		{name: "no value", out: "QueueSize gauge", err: true},
+		{name: "invalid json", out: `{"id":"QueueSize","type":"gauge"}`, err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseCommandOutput([]byte(tt.out))
+			if tt.err {
+				require.ErrorIs(t, err, ErrInvalidOutput)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestExecCollector(t *testing.T) {
+	errorsID := `ExecErrors{command="check"}`
+
+	tests := []struct {
+		name    string
+		command string
+		want    []entity.Metrics
+		err     bool
+	}{
+		{
+			name:    "success",
+			command: "echo 'QueueSize gauge 3'",
+			want:    []entity.Metrics{gauge("QueueSize", 3), counter(errorsID, 0)},
+		},
+		{
+			name:    "non-zero exit",
+			command: "echo 'QueueSize gauge 3'; exit 2",
+			want:    []entity.Metrics{counter(errorsID, 1)},
+			err:     true,
+		},
+		{
+			name:    "timeout",
+			command: "sleep 5",
+			want:    []entity.Metrics{counter(errorsID, 1)},
+			err:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewExecCollector(configs.Command{Name: "check", Command: tt.command, Timeout: 200 * time.Millisecond})
+			require.Equal(t, "exec:check", c.Name())
+
+			got, err := c.Collect(context.Background())
+			if tt.err {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestExecCollector_SentByWorker(t *testing.T) {
+	c := NewExecCollector(configs.Command{Name: "queue", Command: "echo 'QueueSize gauge 0'"})
+
+	l := logger.New("error", io.Discard)
+	r := NewRegistry(l)
+	r.collect(context.Background(), c)
+
+	// the output and the errors of the command are sent regardless of the metrics names
+	api := &fakeWebAPI{}
+	NewWorker(l, r, []string{"Alloc"}, api, 1).sendAll()
+
+	sort.Strings(api.sent)
+	require.Equal(t, []string{`ExecErrors{command="queue"}`, "QueueSize"}, api.sent)
+}
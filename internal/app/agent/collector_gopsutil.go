@@ -0,0 +1,48 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/vladislaoramos/alemetric/internal/entity"
+)
+
+// GopsutilCollector collects the memory of the host and the utilization of each CPU core
+// as CPUutilization1, ..., CPUutilizationN by gopsutil.
+type GopsutilCollector struct{}
+
+// NewGopsutilCollector creates a collector of the host metrics.
+func NewGopsutilCollector() *GopsutilCollector {
+	return &GopsutilCollector{}
+}
+
+// Name returns the name of the collector.
+func (c *GopsutilCollector) Name() string {
+	return GopsutilCollectorName
+}
+
+// Collect reads the memory and the CPU utilization of the host.
+func (c *GopsutilCollector) Collect(ctx context.Context) ([]entity.Metrics, error) {
+	vm, err := mem.VirtualMemoryWithContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read virtual memory: %w", err)
+	}
+
+	res := []entity.Metrics{
+		gauge("TotalMemory", float64(vm.Total)),
+		gauge("FreeMemory", float64(vm.Free)),
+	}
+
+	cpuUtil, err := cpu.PercentWithContext(ctx, 0, true)
+	if err != nil {
+		return res, fmt.Errorf("cannot read cpu utilization: %w", err)
+	}
+
+	for i, percent := range cpuUtil {
+		res = append(res, gauge(fmt.Sprintf("CPUutilization%d", i+1), percent))
+	}
+
+	return res, nil
+}
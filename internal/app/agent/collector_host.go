@@ -0,0 +1,197 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/shirou/gopsutil/v3/load"
+	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/shirou/gopsutil/v3/net"
+	"github.com/vladislaoramos/alemetric/internal/entity"
+)
+
+// LoadCollector collects the load average of the host: LoadAverage1, LoadAverage5, LoadAverage15.
+type LoadCollector struct{}
+
+// NewLoadCollector creates a collector of the load average.
+func NewLoadCollector() *LoadCollector {
+	return &LoadCollector{}
+}
+
+// Name returns the name of the collector.
+func (c *LoadCollector) Name() string {
+	return LoadCollectorName
+}
+
+// Collect reads the load average.
+func (c *LoadCollector) Collect(ctx context.Context) ([]entity.Metrics, error) {
+	avg, err := load.AvgWithContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read load average: %w", err)
+	}
+
+	return []entity.Metrics{
+		gauge("LoadAverage1", avg.Load1),
+		gauge("LoadAverage5", avg.Load5),
+		gauge("LoadAverage15", avg.Load15),
+	}, nil
+}
+
+// DiskCollector collects the usage of each mounted partition labeled by mount point,
+// e.g. DiskUsed{mount="/"}, and the IO of each device labeled by device name as counters,
+// e.g. DiskReadBytes{device="sda"}.
+type DiskCollector struct {
+	increments increments
+}
+
+// NewDiskCollector creates a collector of the disk usage and IO.
+func NewDiskCollector() *DiskCollector {
+	return &DiskCollector{increments: make(increments)}
+}
+
+// Name returns the name of the collector.
+func (c *DiskCollector) Name() string {
+	return DiskCollectorName
+}
+
+// Collect reads the usage of the partitions and the IO counters of the devices.
+func (c *DiskCollector) Collect(ctx context.Context) ([]entity.Metrics, error) {
+	var (
+		res  []entity.Metrics
+		errs collectErrors
+	)
+
+	partitions, err := disk.PartitionsWithContext(ctx, false)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("cannot read partitions: %w", err))
+	}
+
+	for _, p := range partitions {
+		usage, err := disk.UsageWithContext(ctx, p.Mountpoint)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cannot read usage of %s: %w", p.Mountpoint, err))
+			continue
+		}
+
+		labels := map[string]string{"mount": p.Mountpoint}
+		res = append(res,
+			gauge(entity.FormatID("DiskTotal", labels), float64(usage.Total)),
+			gauge(entity.FormatID("DiskUsed", labels), float64(usage.Used)),
+			gauge(entity.FormatID("DiskFree", labels), float64(usage.Free)),
+			gauge(entity.FormatID("DiskUsedPercent", labels), usage.UsedPercent),
+		)
+	}
+
+	counters, err := disk.IOCountersWithContext(ctx)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("cannot read io counters: %w", err))
+	}
+
+	devices := make([]string, 0, len(counters))
+	for name := range counters {
+		devices = append(devices, name)
+	}
+	sort.Strings(devices)
+
+	for _, name := range devices {
+		io := counters[name]
+		labels := map[string]string{"device": name}
+		res = c.increments.append(res, entity.FormatID("DiskReadBytes", labels), io.ReadBytes)
+		res = c.increments.append(res, entity.FormatID("DiskWriteBytes", labels), io.WriteBytes)
+		res = c.increments.append(res, entity.FormatID("DiskReadCount", labels), io.ReadCount)
+		res = c.increments.append(res, entity.FormatID("DiskWriteCount", labels), io.WriteCount)
+	}
+
+	return res, errs.errorOrNil()
+}
+
+// NetCollector collects the bytes and packets of each network interface as counters
+// labeled by interface name, e.g. NetBytesSent{interface="eth0"}.
+type NetCollector struct {
+	increments increments
+}
+
+// NewNetCollector creates a collector of the network IO.
+func NewNetCollector() *NetCollector {
+	return &NetCollector{increments: make(increments)}
+}
+
+// Name returns the name of the collector.
+func (c *NetCollector) Name() string {
+	return NetCollectorName
+}
+
+// Collect reads the IO counters of the network interfaces.
+func (c *NetCollector) Collect(ctx context.Context) ([]entity.Metrics, error) {
+	counters, err := net.IOCountersWithContext(ctx, true)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read network io counters: %w", err)
+	}
+
+	var res []entity.Metrics
+	for _, io := range counters {
+		labels := map[string]string{"interface": io.Name}
+		res = c.increments.append(res, entity.FormatID("NetBytesSent", labels), io.BytesSent)
+		res = c.increments.append(res, entity.FormatID("NetBytesRecv", labels), io.BytesRecv)
+		res = c.increments.append(res, entity.FormatID("NetPacketsSent", labels), io.PacketsSent)
+		res = c.increments.append(res, entity.FormatID("NetPacketsRecv", labels), io.PacketsRecv)
+	}
+
+	return res, nil
+}
+
+// SwapCollector collects the swap memory: SwapTotal, SwapUsed, SwapFree.
+type SwapCollector struct{}
+
+// NewSwapCollector creates a collector of the swap memory.
+func NewSwapCollector() *SwapCollector {
+	return &SwapCollector{}
+}
+
+// Name returns the name of the collector.
+func (c *SwapCollector) Name() string {
+	return SwapCollectorName
+}
+
+// Collect reads the swap memory.
+func (c *SwapCollector) Collect(ctx context.Context) ([]entity.Metrics, error) {
+	swap, err := mem.SwapMemoryWithContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read swap memory: %w", err)
+	}
+
+	return []entity.Metrics{
+		gauge("SwapTotal", float64(swap.Total)),
+		gauge("SwapUsed", float64(swap.Used)),
+		gauge("SwapFree", float64(swap.Free)),
+	}, nil
+}
+
+// ProcessesCollector collects the numbers of processes: ProcessCount, ProcessRunning, ProcessBlocked.
+type ProcessesCollector struct{}
+
+// NewProcessesCollector creates a collector of the numbers of processes.
+func NewProcessesCollector() *ProcessesCollector {
+	return &ProcessesCollector{}
+}
+
+// Name returns the name of the collector.
+func (c *ProcessesCollector) Name() string {
+	return ProcessesCollectorName
+}
+
+// Collect reads the numbers of processes.
+func (c *ProcessesCollector) Collect(ctx context.Context) ([]entity.Metrics, error) {
+	misc, err := load.MiscWithContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read numbers of processes: %w", err)
+	}
+
+	return []entity.Metrics{
+		gauge("ProcessCount", float64(misc.ProcsTotal)),
+		gauge("ProcessRunning", float64(misc.ProcsRunning)),
+		gauge("ProcessBlocked", float64(misc.ProcsBlocked)),
+	}, nil
+}
@@ -0,0 +1,327 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"github.com/vladislaoramos/alemetric/configs"
+	"github.com/vladislaoramos/alemetric/internal/entity"
+)
+
+// logValueGroup is the name of the capture group the gauge value is extracted from.
+const logValueGroup = "value"
+
+type logRule struct {
+	re      *regexp.Regexp
+	counter string
+	gauge   string
+}
+
+// logFile is a followed file. The offset is the position after the last read line.
+type logFile struct {
+	path   string
+	rules  []logRule
+	file   *os.File
+	offset int64
+}
+
+// LogTailCollector follows the log files listed in the agent config and derives metrics from their lines.
+// A line matching the rule regexp increments the counter of the rule
+// and sets the gauge of the rule to the value of the capture group named value or of the first group.
+// The other named capture groups are the labels of the metrics, e.g. for
+// `" (?P<status>5\d\d) "` the counter is sent as Nginx5xx{status="502"}.
+//
+// The files are followed through rotation and truncation. If the offsets file is set,
+// the offsets are saved to it after every collection and the agent continues from them after restart;
+// otherwise a file is read from its end at the first collection.
+// After restart, a file rotated while the agent was stopped is detected only if it is shorter than its offset.
+type LogTailCollector struct {
+	files       []*logFile
+	offsetsPath string
+}
+
+// NewLogTailCollector creates a collector of the log files.
+func NewLogTailCollector(logs []configs.LogFile, offsetsPath string) (*LogTailCollector, error) {
+	c := &LogTailCollector{offsetsPath: offsetsPath}
+
+	for _, l := range logs {
+		f := &logFile{path: l.Path, offset: -1}
+		for _, r := range l.Rules {
+			re, err := regexp.Compile(r.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern of log %s: %w", l.Path, err)
+			}
+			if r.Counter == "" && r.Gauge == "" {
+				return nil, fmt.Errorf("rule %q of log %s has neither counter nor gauge", r.Pattern, l.Path)
+			}
+			if r.Gauge != "" && re.NumSubexp() == 0 {
+				return nil, fmt.Errorf("rule %q of log %s has gauge but no capture group", r.Pattern, l.Path)
+			}
+			f.rules = append(f.rules, logRule{re: re, counter: r.Counter, gauge: r.Gauge})
+		}
+		c.files = append(c.files, f)
+	}
+
+	offsets, err := c.loadOffsets()
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range c.files {
+		if offset, ok := offsets[f.path]; ok {
+			f.offset = offset
+		}
+	}
+
+	return c, nil
+}
+
+// Name returns the name of the collector.
+func (c *LogTailCollector) Name() string {
+	return LogTailCollectorName
+}
+
+// Unfiltered marks the derived metrics to be sent regardless of the metrics names of the worker.
+func (c *LogTailCollector) Unfiltered() {}
+
+// Collect reads the lines appended to the files since the previous collection.
+// The counters of the rules without labels are sent even if nothing matched.
+func (c *LogTailCollector) Collect(context.Context) ([]entity.Metrics, error) {
+	var errs collectErrors
+
+	counters := make(map[string]int64)
+	gauges := make(map[string]float64)
+
+	// order keeps the metrics in the order of their first appearance
+	var order []string
+	seen := func(id string) {
+		_, isCounter := counters[id]
+		_, isGauge := gauges[id]
+		if !isCounter && !isGauge {
+			order = append(order, id)
+		}
+	}
+
+	for _, f := range c.files {
+		for _, r := range f.rules {
+			if _, ok := counters[r.counter]; !ok && r.counter != "" && !hasLabels(r.re) {
+				seen(r.counter)
+				counters[r.counter] = 0
+			}
+		}
+
+		err := f.read(func(line []byte) {
+			for _, r := range f.rules {
+				match := r.re.FindSubmatch(line)
+				if match == nil {
+					continue
+				}
+
+				labels := make(map[string]string)
+				for i, name := range r.re.SubexpNames() {
+					if name != "" && name != logValueGroup {
+						labels[name] = string(match[i])
+					}
+				}
+
+				if r.counter != "" {
+					id := entity.FormatID(r.counter, labels)
+					seen(id)
+					counters[id]++
+				}
+
+				if r.gauge != "" {
+					value, err := strconv.ParseFloat(string(match[valueGroup(r.re)]), 64)
+					if err != nil {
+						errs = append(errs, fmt.Errorf("cannot parse value of %s from log %s: %w", r.gauge, f.path, err))
+						continue
+					}
+					id := entity.FormatID(r.gauge, labels)
+					seen(id)
+					gauges[id] = value
+				}
+			}
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cannot read log %s: %w", f.path, err))
+		}
+	}
+
+	if err := c.saveOffsets(); err != nil {
+		errs = append(errs, err)
+	}
+
+	res := make([]entity.Metrics, 0, len(order))
+	for _, id := range order {
+		if value, ok := gauges[id]; ok {
+			res = append(res, gauge(id, value))
+		} else {
+			res = append(res, counter(id, counters[id]))
+		}
+	}
+
+	return res, errs.errorOrNil()
+}
+
+// Close closes the followed files.
+func (c *LogTailCollector) Close() error {
+	for _, f := range c.files {
+		if f.file != nil {
+			f.file.Close()
+			f.file = nil
+		}
+	}
+	return nil
+}
+
+// read calls fn for the complete lines appended to the file since the previous read.
+// If the file was rotated, the rest of the old file is read before the new one.
+func (f *logFile) read(fn func(line []byte)) error {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		if f.file != nil && os.IsNotExist(err) {
+			// the file was rotated and the new one is not created yet
+			return f.readLines(fn)
+		}
+		return err
+	}
+
+	if f.file != nil {
+		current, err := f.file.Stat()
+		if err != nil {
+			return err
+		}
+		if !os.SameFile(current, info) {
+			if err = f.readLines(fn); err != nil {
+				return err
+			}
+			f.file.Close()
+			f.file = nil
+			f.offset = 0
+		}
+	}
+
+	if f.file == nil {
+		f.file, err = os.Open(f.path)
+		if err != nil {
+			return err
+		}
+		if f.offset < 0 {
+			f.offset = info.Size()
+		}
+	}
+
+	if info.Size() < f.offset {
+		// the file was truncated
+		f.offset = 0
+	}
+
+	return f.readLines(fn)
+}
+
+func (f *logFile) readLines(fn func(line []byte)) error {
+	if _, err := f.file.Seek(f.offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	data, err := io.ReadAll(f.file)
+	if err != nil {
+		return err
+	}
+
+	// an incomplete last line is read at the next collection
+	end := bytes.LastIndexByte(data, '\n')
+	if end < 0 {
+		return nil
+	}
+
+	for _, line := range bytes.Split(data[:end], []byte{'\n'}) {
+		fn(bytes.TrimSuffix(line, []byte{'\r'}))
+	}
+	f.offset += int64(end + 1)
+
+	return nil
+}
+
+func (c *LogTailCollector) loadOffsets() (map[string]int64, error) {
+	offsets := make(map[string]int64)
+	if c.offsetsPath == "" {
+		return offsets, nil
+	}
+
+	data, err := os.ReadFile(c.offsetsPath)
+	if os.IsNotExist(err) {
+		return offsets, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read log offsets: %w", err)
+	}
+
+	if err = json.Unmarshal(data, &offsets); err != nil {
+		return nil, fmt.Errorf("cannot parse log offsets: %w", err)
+	}
+
+	return offsets, nil
+}
+
+// saveOffsets writes the offsets into a temporary file and replaces the offsets file with it,
+// so the offsets file is never left partially written.
+func (c *LogTailCollector) saveOffsets() error {
+	if c.offsetsPath == "" {
+		return nil
+	}
+
+	offsets := make(map[string]int64, len(c.files))
+	for _, f := range c.files {
+		if f.offset >= 0 {
+			offsets[f.path] = f.offset
+		}
+	}
+
+	data, err := json.Marshal(offsets)
+	if err != nil {
+		return fmt.Errorf("cannot marshal log offsets: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.offsetsPath), filepath.Base(c.offsetsPath)+".*")
+	if err != nil {
+		return fmt.Errorf("cannot save log offsets: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("cannot save log offsets: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("cannot save log offsets: %w", err)
+	}
+
+	if err = os.Rename(tmp.Name(), c.offsetsPath); err != nil {
+		return fmt.Errorf("cannot save log offsets: %w", err)
+	}
+
+	return nil
+}
+
+func hasLabels(re *regexp.Regexp) bool {
+	for _, name := range re.SubexpNames() {
+		if name != "" && name != logValueGroup {
+			return true
+		}
+	}
+	return false
+}
+
+func valueGroup(re *regexp.Regexp) int {
+	if i := re.SubexpIndex(logValueGroup); i > 0 {
+		return i
+	}
+	return 1
+}
@@ -0,0 +1,124 @@
+package agent
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vladislaoramos/alemetric/configs"
+	"github.com/vladislaoramos/alemetric/internal/entity"
+	logger "github.com/vladislaoramos/alemetric/pkg/log"
+)
+
+func appendLog(t *testing.T, path, lines string) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	require.NoError(t, err)
+	_, err = f.WriteString(lines)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+}
+
+func TestLogTailCollector(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "access.log")
+	offsets := filepath.Join(dir, "offsets.json")
+
+	logs := []configs.LogFile{{
+		Path: path,
+		Rules: []configs.LogRule{
+			{Pattern: `" (?P<status>5\d\d) `, Counter: "Nginx5xx"},
+			{Pattern: `ERROR`, Counter: "Errors"},
+			{Pattern: `rt=(?P<value>[\d.]+)`, Gauge: "ResponseTime"},
+		},
+	}}
+
+	appendLog(t, path, "old ERROR line\n")
+
+	c, err := NewLogTailCollector(logs, offsets)
+	require.NoError(t, err)
+	defer c.Close()
+
+	collect := func() map[string]entity.Metrics {
+		items, err := c.Collect(context.Background())
+		require.NoError(t, err)
+		return byName(items)
+	}
+
+	// the existing lines are skipped at the first collection
+	require.Equal(t, map[string]entity.Metrics{"Errors": counter("Errors", 0)}, collect())
+
+	appendLog(t, path, "\"GET /\" 502 rt=0.5\nERROR one\n\"GET /\" 502 rt=1.5\n\"GET /\" 503 rt=2.5\nERROR incomplete")
+	metrics := collect()
+	require.Equal(t, entity.Counter(2), *metrics[`Nginx5xx{status="502"}`].Delta)
+	require.Equal(t, entity.Counter(1), *metrics[`Nginx5xx{status="503"}`].Delta)
+	require.Equal(t, entity.Counter(1), *metrics["Errors"].Delta)
+	require.Equal(t, entity.Gauge(2.5), *metrics["ResponseTime"].Value)
+
+	t.Run("restart", func(t *testing.T) {
+		appendLog(t, path, " line\n")
+
+		restarted, err := NewLogTailCollector(logs, offsets)
+		require.NoError(t, err)
+		defer restarted.Close()
+
+		items, err := restarted.Collect(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, entity.Counter(1), *byName(items)["Errors"].Delta)
+	})
+
+	t.Run("rotation", func(t *testing.T) {
+		appendLog(t, path, "ERROR before rotation\n")
+		require.NoError(t, os.Rename(path, path+".1"))
+		appendLog(t, path, "ERROR after rotation\n")
+
+		// the line completed in the restart test, the rest of the old file and the new file
+		require.Equal(t, entity.Counter(3), *collect()["Errors"].Delta)
+	})
+
+	t.Run("truncation", func(t *testing.T) {
+		require.NoError(t, os.Truncate(path, 0))
+		appendLog(t, path, "ERROR\n")
+
+		require.Equal(t, entity.Counter(1), *collect()["Errors"].Delta)
+	})
+}
+
+func TestNewLogTailCollector_Errors(t *testing.T) {
+	for _, rule := range []configs.LogRule{
+		{Pattern: `(`, Counter: "Errors"},
+		{Pattern: `ERROR`},
+		{Pattern: `rt=\d+`, Gauge: "ResponseTime"},
+	} {
+		_, err := NewLogTailCollector([]configs.LogFile{{Path: "app.log", Rules: []configs.LogRule{rule}}}, "")
+		require.Error(t, err, rule.Pattern)
+	}
+}
+
+func TestLogTailCollector_SentByWorker(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendLog(t, path, "")
+
+	logs := []configs.LogFile{{
+		Path:  path,
+		Rules: []configs.LogRule{{Pattern: `ERROR`, Counter: "LogErrors"}},
+	}}
+
+	c, err := NewLogTailCollector(logs, "")
+	require.NoError(t, err)
+	defer c.Close()
+
+	l := logger.New("error", io.Discard)
+	r := NewRegistry(l)
+	r.collect(context.Background(), c)
+
+	appendLog(t, path, "ERROR one\n")
+	r.collect(context.Background(), c)
+
+	// the derived metrics are sent regardless of the metrics names
+	api := &fakeWebAPI{}
+	NewWorker(l, r, []string{"Alloc"}, api, 1).sendAll()
+	require.Equal(t, []string{"LogErrors"}, api.sent)
+}
@@ -0,0 +1,190 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/shirou/gopsutil/v3/process"
+	"github.com/vladislaoramos/alemetric/configs"
+	"github.com/vladislaoramos/alemetric/internal/entity"
+)
+
+// ProcstatCollector collects the metrics of the processes selected in the agent config:
+// ProcessRSS, ProcessCPUPercent, ProcessFDs, ProcessThreads and the IO counters
+// ProcessReadBytes, ProcessWriteBytes, ProcessReadCount, ProcessWriteCount.
+// The metrics are gauges labeled by the process name and PID, e.g. ProcessRSS{pid="42",process="service"}.
+type ProcstatCollector struct {
+	targets []configs.Process
+
+	// procs keeps the processes between the collections to compute their CPU utilization.
+	procs map[int32]*process.Process
+}
+
+// NewProcstatCollector creates a collector of the selected processes.
+func NewProcstatCollector(targets []configs.Process) *ProcstatCollector {
+	return &ProcstatCollector{
+		targets: targets,
+		procs:   make(map[int32]*process.Process),
+	}
+}
+
+// Name returns the name of the collector.
+func (c *ProcstatCollector) Name() string {
+	return ProcstatCollectorName
+}
+
+// Collect reads the metrics of the selected processes.
+// The CPU utilization of a process is reported from its second collection.
+func (c *ProcstatCollector) Collect(ctx context.Context) ([]entity.Metrics, error) {
+	var (
+		res  []entity.Metrics
+		errs collectErrors
+	)
+
+	var all []*process.Process
+	seen := make(map[int32]struct{})
+	for _, target := range c.targets {
+		pids, err := c.pids(ctx, target, &all)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		for _, pid := range pids {
+			if _, ok := seen[pid]; ok {
+				continue
+			}
+			seen[pid] = struct{}{}
+
+			items, err := c.collectProcess(ctx, target.Name, pid)
+			res = append(res, items...)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("process %d: %w", pid, err))
+			}
+		}
+	}
+
+	for pid := range c.procs {
+		if _, ok := seen[pid]; !ok {
+			delete(c.procs, pid)
+		}
+	}
+
+	return res, errs.errorOrNil()
+}
+
+// pids finds the PIDs of the processes selected by the target.
+// The list of all processes is read once per collection and only if a target has a name pattern.
+func (c *ProcstatCollector) pids(ctx context.Context, target configs.Process, all *[]*process.Process) ([]int32, error) {
+	switch {
+	case target.PID != 0:
+		return []int32{target.PID}, nil
+	case target.PIDFile != "":
+		data, err := os.ReadFile(target.PIDFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read pid file: %w", err)
+		}
+		pid, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse pid file %s: %w", target.PIDFile, err)
+		}
+		return []int32{int32(pid)}, nil
+	case target.Pattern != "":
+		if *all == nil {
+			procs, err := process.ProcessesWithContext(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("cannot read processes: %w", err)
+			}
+			*all = procs
+		}
+
+		var pids []int32
+		for _, p := range *all {
+			name, err := p.NameWithContext(ctx)
+			if err != nil {
+				// the process has exited
+				continue
+			}
+			if ok, err := path.Match(target.Pattern, name); err != nil {
+				return nil, fmt.Errorf("invalid process pattern %s: %w", target.Pattern, err)
+			} else if ok {
+				pids = append(pids, p.Pid)
+			}
+		}
+		return pids, nil
+	}
+
+	return nil, fmt.Errorf("process %s has neither pid, nor pid file, nor pattern", target.Name)
+}
+
+func (c *ProcstatCollector) collectProcess(ctx context.Context, name string, pid int32) ([]entity.Metrics, error) {
+	p, known := c.procs[pid]
+	if !known {
+		var err error
+		p, err = process.NewProcessWithContext(ctx, pid)
+		if err != nil {
+			return nil, err
+		}
+		c.procs[pid] = p
+	}
+
+	if name == "" {
+		var err error
+		name, err = p.NameWithContext(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read name: %w", err)
+		}
+	}
+
+	labels := map[string]string{
+		"process": name,
+		"pid":     strconv.Itoa(int(pid)),
+	}
+
+	var (
+		res  []entity.Metrics
+		errs collectErrors
+	)
+
+	if mem, err := p.MemoryInfoWithContext(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("cannot read memory: %w", err))
+	} else {
+		res = append(res, gauge(entity.FormatID("ProcessRSS", labels), float64(mem.RSS)))
+	}
+
+	// the first call only remembers the CPU times of the process
+	if percent, err := p.PercentWithContext(ctx, 0); err != nil {
+		errs = append(errs, fmt.Errorf("cannot read cpu utilization: %w", err))
+	} else if known {
+		res = append(res, gauge(entity.FormatID("ProcessCPUPercent", labels), percent))
+	}
+
+	if fds, err := p.NumFDsWithContext(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("cannot read number of fds: %w", err))
+	} else {
+		res = append(res, gauge(entity.FormatID("ProcessFDs", labels), float64(fds)))
+	}
+
+	if threads, err := p.NumThreadsWithContext(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("cannot read number of threads: %w", err))
+	} else {
+		res = append(res, gauge(entity.FormatID("ProcessThreads", labels), float64(threads)))
+	}
+
+	if io, err := p.IOCountersWithContext(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("cannot read io counters: %w", err))
+	} else {
+		res = append(res,
+			gauge(entity.FormatID("ProcessReadBytes", labels), float64(io.ReadBytes)),
+			gauge(entity.FormatID("ProcessWriteBytes", labels), float64(io.WriteBytes)),
+			gauge(entity.FormatID("ProcessReadCount", labels), float64(io.ReadCount)),
+			gauge(entity.FormatID("ProcessWriteCount", labels), float64(io.WriteCount)),
+		)
+	}
+
+	return res, errs.errorOrNil()
+}
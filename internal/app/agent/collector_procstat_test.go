@@ -0,0 +1,68 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/shirou/gopsutil/v3/process"
+	"github.com/stretchr/testify/require"
+	"github.com/vladislaoramos/alemetric/configs"
+	"github.com/vladislaoramos/alemetric/internal/entity"
+)
+
+func TestProcstatCollector(t *testing.T) {
+	ctx := context.Background()
+	pid := int32(os.Getpid())
+
+	self, err := process.NewProcess(pid)
+	require.NoError(t, err)
+	name, err := self.Name()
+	require.NoError(t, err)
+
+	pidFile := filepath.Join(t.TempDir(), "agent.pid")
+	require.NoError(t, os.WriteFile(pidFile, []byte(strconv.Itoa(int(pid))+"\n"), 0o600))
+
+	tests := []struct {
+		name   string
+		target configs.Process
+		label  string
+	}{
+		{name: "pid", target: configs.Process{Name: "self", PID: pid}, label: "self"},
+		{name: "pid file", target: configs.Process{PIDFile: pidFile}, label: name},
+		{name: "pattern", target: configs.Process{Name: "self", Pattern: name[:len(name)-1] + "*"}, label: "self"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewProcstatCollector([]configs.Process{tt.target})
+			labels := map[string]string{"process": tt.label, "pid": strconv.Itoa(int(pid))}
+
+			items, err := c.Collect(ctx)
+			require.NoError(t, err)
+			metrics := byName(items)
+			require.Contains(t, metrics, entity.FormatID("ProcessRSS", labels))
+			require.Greater(t, float64(*metrics[entity.FormatID("ProcessRSS", labels)].Value), 0.0)
+			require.Contains(t, metrics, entity.FormatID("ProcessThreads", labels))
+			require.NotContains(t, metrics, entity.FormatID("ProcessCPUPercent", labels))
+
+			items, err = c.Collect(ctx)
+			require.NoError(t, err)
+			require.Contains(t, byName(items), entity.FormatID("ProcessCPUPercent", labels))
+		})
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		c := NewProcstatCollector([]configs.Process{
+			{PIDFile: filepath.Join(t.TempDir(), "missing.pid")},
+			{Name: "empty"},
+			{Name: "self", PID: pid},
+		})
+
+		items, err := c.Collect(ctx)
+		require.Error(t, err)
+		require.NotEmpty(t, items)
+	})
+}
@@ -0,0 +1,240 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vladislaoramos/alemetric/configs"
+	"github.com/vladislaoramos/alemetric/internal/entity"
+)
+
+const defaultScrapeTimeout = 5 * time.Second
+
+// ErrInvalidExposition is returned when a line of the Prometheus text format cannot be parsed.
+var ErrInvalidExposition = errors.New("invalid prometheus exposition")
+
+// promSample is a sample of the Prometheus text format.
+type promSample struct {
+	name   string
+	labels map[string]string
+	value  float64
+	mtype  string
+}
+
+// parsePrometheus parses the counters, gauges and untyped metrics of the Prometheus text format.
+// The samples of histograms and summaries are skipped.
+func parsePrometheus(r io.Reader) ([]promSample, error) {
+	types := make(map[string]string)
+
+	var res []promSample
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "#") {
+			fields := strings.Fields(line)
+			if len(fields) == 4 && fields[1] == "TYPE" {
+				types[fields[2]] = fields[3]
+			}
+			continue
+		}
+
+		sample, err := parsePromLine(line)
+		if err != nil {
+			return nil, err
+		}
+
+		mtype, ok := types[sample.name]
+		if !ok {
+			mtype = "untyped"
+			for _, suffix := range []string{"_bucket", "_sum", "_count"} {
+				if base := strings.TrimSuffix(sample.name, suffix); base != sample.name && types[base] != "" {
+					mtype = types[base]
+				}
+			}
+		}
+
+		switch mtype {
+		case "counter", "gauge", "untyped":
+		default:
+			continue
+		}
+
+		if math.IsNaN(sample.value) || math.IsInf(sample.value, 0) {
+			continue
+		}
+
+		sample.mtype = mtype
+		res = append(res, sample)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read exposition: %w", err)
+	}
+
+	return res, nil
+}
+
+// parsePromLine parses the sample line `name{labels} value [timestamp]`.
+func parsePromLine(line string) (promSample, error) {
+	end := strings.IndexAny(line, "{ \t")
+	if end <= 0 {
+		return promSample{}, fmt.Errorf("%w: %q", ErrInvalidExposition, line)
+	}
+
+	if line[end] == '{' {
+		// the labels end with the first brace outside of the quotes
+		closing := -1
+		quoted := false
+		for i := end + 1; i < len(line) && closing < 0; i++ {
+			switch {
+			case quoted && line[i] == '\\':
+				i++
+			case line[i] == '"':
+				quoted = !quoted
+			case !quoted && line[i] == '}':
+				closing = i
+			}
+		}
+		if closing < 0 {
+			return promSample{}, fmt.Errorf("%w: %q", ErrInvalidExposition, line)
+		}
+		end = closing + 1
+	}
+
+	name, labels, err := entity.ParseID(line[:end])
+	if err != nil {
+		return promSample{}, fmt.Errorf("%w: %q: %s", ErrInvalidExposition, line, err)
+	}
+
+	fields := strings.Fields(line[end:])
+	if len(fields) == 0 {
+		return promSample{}, fmt.Errorf("%w: %q: no value", ErrInvalidExposition, line)
+	}
+
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return promSample{}, fmt.Errorf("%w: %q: bad value", ErrInvalidExposition, line)
+	}
+
+	return promSample{name: name, labels: labels, value: value}, nil
+}
+
+// PrometheusCollector scrapes the Prometheus endpoints listed in the agent config.
+// Gauges and untyped metrics are sent as gauges, counters are sent as increments since the previous scrape.
+type PrometheusCollector struct {
+	targets    []configs.ScrapeTarget
+	client     *http.Client
+	increments increments
+}
+
+// NewPrometheusCollector creates a collector of the Prometheus endpoints.
+func NewPrometheusCollector(targets []configs.ScrapeTarget) *PrometheusCollector {
+	return &PrometheusCollector{
+		targets:    targets,
+		client:     &http.Client{Timeout: defaultScrapeTimeout},
+		increments: make(increments),
+	}
+}
+
+// Name returns the name of the collector.
+func (c *PrometheusCollector) Name() string {
+	return PrometheusCollectorName
+}
+
+// Unfiltered marks the scraped metrics to be sent regardless of the metrics names of the worker:
+// they are selected by the allowlists of the targets.
+func (c *PrometheusCollector) Unfiltered() {}
+
+// Collect scrapes the endpoints. An unavailable endpoint does not stop scraping the others.
+func (c *PrometheusCollector) Collect(ctx context.Context) ([]entity.Metrics, error) {
+	var (
+		res  []entity.Metrics
+		errs collectErrors
+	)
+
+	for _, target := range c.targets {
+		samples, err := c.scrape(ctx, target.URL)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cannot scrape %s: %w", target.URL, err))
+			continue
+		}
+
+		for _, s := range samples {
+			if !allowed(target.Allow, s.name) {
+				continue
+			}
+
+			name := s.name
+			if renamed, ok := target.Rename[name]; ok {
+				name = renamed
+			}
+
+			labels := s.labels
+			if len(target.Labels) != 0 {
+				labels = make(map[string]string, len(s.labels)+len(target.Labels))
+				for k, v := range s.labels {
+					labels[k] = v
+				}
+				for k, v := range target.Labels {
+					labels[k] = v
+				}
+			}
+			id := entity.FormatID(name, labels)
+
+			if s.mtype == "counter" {
+				if s.value >= 0 {
+					// the totals are truncated, so the fractional increments are sent once they add up to one
+					res = c.increments.append(res, id, uint64(s.value))
+				}
+				continue
+			}
+			res = append(res, gauge(id, s.value))
+		}
+	}
+
+	return res, errs.errorOrNil()
+}
+
+func (c *PrometheusCollector) scrape(ctx context.Context, url string) ([]promSample, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create request: %w", err)
+	}
+	req.Header.Set("Accept", "text/plain")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("not successful status code: %d", resp.StatusCode)
+	}
+
+	return parsePrometheus(resp.Body)
+}
+
+func allowed(patterns []string, name string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if matchName(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vladislaoramos/alemetric/configs"
+	"github.com/vladislaoramos/alemetric/internal/entity"
+	logger "github.com/vladislaoramos/alemetric/pkg/log"
+)
+
+const exposition = `# HELP http_requests_total The total number of requests.
+# TYPE http_requests_total counter
+http_requests_total{method="post",code="200"} %d
+http_requests_total{method="get",code="200",} 3 1395066363000
+# TYPE queue_length gauge
+queue_length 12.5
+# TYPE request_duration_seconds histogram
+request_duration_seconds_bucket{le="0.1"} 4
+request_duration_seconds_sum 0.3
+request_duration_seconds_count 4
+temperature{room="a \"big\" one"} -1
+go_goroutines NaN
+`
+
+func TestParsePrometheus(t *testing.T) {
+	samples, err := parsePrometheus(strings.NewReader(fmt.Sprintf(exposition, 1027)))
+	require.NoError(t, err)
+
+	require.Equal(t, []promSample{
+		{name: "http_requests_total", labels: map[string]string{"method": "post", "code": "200"}, value: 1027, mtype: "counter"},
+		{name: "http_requests_total", labels: map[string]string{"method": "get", "code": "200"}, value: 3, mtype: "counter"},
+		{name: "queue_length", value: 12.5, mtype: "gauge"},
+		{name: "temperature", labels: map[string]string{"room": `a "big" one`}, value: -1, mtype: "untyped"},
+	}, samples)
+
+	for _, line := range []string{"queue_length", "queue_length{a=\"b\" 1", "queue_length x"} {
+		_, err = parsePrometheus(strings.NewReader(line))
+		require.ErrorIs(t, err, ErrInvalidExposition, line)
+	}
+}
+
+func TestPrometheusCollector(t *testing.T) {
+	total := 1027
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/metrics" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, exposition, total)
+	}))
+	defer srv.Close()
+
+	c := NewPrometheusCollector([]configs.ScrapeTarget{
+		{
+			URL:    srv.URL + "/metrics",
+			Allow:  []string{"http_requests_*", "queue_length"},
+			Rename: map[string]string{"queue_length": "QueueLength"},
+			Labels: map[string]string{"job": "api"},
+		},
+		{URL: srv.URL + "/missing", Allow: []string{"none"}},
+	})
+
+	items, err := c.Collect(context.Background())
+	require.Error(t, err)
+	require.Equal(t, []entity.Metrics{gauge(`QueueLength{job="api"}`, 12.5)}, items)
+
+	total += 5
+	items, err = c.Collect(context.Background())
+	require.Error(t, err)
+
+	metrics := byName(items)
+	require.Len(t, metrics, 3)
+	require.Equal(t, entity.Counter(5), *metrics[`http_requests_total{code="200",job="api",method="post"}`].Delta)
+	require.Equal(t, entity.Counter(0), *metrics[`http_requests_total{code="200",job="api",method="get"}`].Delta)
+}
+
+func TestPrometheusCollector_SentByWorker(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, exposition, 1027)
+	}))
+	defer srv.Close()
+
+	l := logger.New("error", io.Discard)
+	c := NewPrometheusCollector([]configs.ScrapeTarget{{URL: srv.URL, Allow: []string{"queue_length"}}})
+
+	r := NewRegistry(l)
+	r.Push(gauge("Sys", 1))
+	r.collect(context.Background(), c)
+
+	// the scraped metrics are sent regardless of the metrics names
+	api := &fakeWebAPI{}
+	NewWorker(l, r, []string{"Alloc"}, api, 1).sendAll()
+	require.Equal(t, []string{"queue_length"}, api.sent)
+}
@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"context"
+	"math/rand"
+	"runtime"
+
+	"github.com/vladislaoramos/alemetric/internal/entity"
+)
+
+// Names of the built-in collectors.
+const (
+	RuntimeCollectorName    = "runtime"
+	GopsutilCollectorName   = "gopsutil"
+	RandomCollectorName     = "random"
+	LoadCollectorName       = "load"
+	DiskCollectorName       = "disk"
+	NetCollectorName        = "net"
+	SwapCollectorName       = "swap"
+	ProcessesCollectorName  = "processes"
+	ProcstatCollectorName   = "procstat"
+	StatsDCollectorName     = "statsd"
+	PrometheusCollectorName = "prometheus"
+	LogTailCollectorName    = "logtail"
+	ExecCollectorName       = "exec"
+	TelemetryCollectorName  = "telemetry"
+)
+
+var memStatsGauges = []struct {
+	name  string
+	value func(*runtime.MemStats) float64
+}{
+	{"Alloc", func(s *runtime.MemStats) float64 { return float64(s.Alloc) }},
+	{"BuckHashSys", func(s *runtime.MemStats) float64 { return float64(s.BuckHashSys) }},
+	{"Frees", func(s *runtime.MemStats) float64 { return float64(s.Frees) }},
+	{"GCCPUFraction", func(s *runtime.MemStats) float64 { return s.GCCPUFraction }},
+	{"GCSys", func(s *runtime.MemStats) float64 { return float64(s.GCSys) }},
+	{"HeapAlloc", func(s *runtime.MemStats) float64 { return float64(s.HeapAlloc) }},
+	{"HeapIdle", func(s *runtime.MemStats) float64 { return float64(s.HeapIdle) }},
+	{"HeapInuse", func(s *runtime.MemStats) float64 { return float64(s.HeapInuse) }},
+	{"HeapObjects", func(s *runtime.MemStats) float64 { return float64(s.HeapObjects) }},
+	{"HeapReleased", func(s *runtime.MemStats) float64 { return float64(s.HeapReleased) }},
+	{"HeapSys", func(s *runtime.MemStats) float64 { return float64(s.HeapSys) }},
+	{"LastGC", func(s *runtime.MemStats) float64 { return float64(s.LastGC) }},
+	{"Lookups", func(s *runtime.MemStats) float64 { return float64(s.Lookups) }},
+	{"MCacheInuse", func(s *runtime.MemStats) float64 { return float64(s.MCacheInuse) }},
+	{"MCacheSys", func(s *runtime.MemStats) float64 { return float64(s.MCacheSys) }},
+	{"MSpanInuse", func(s *runtime.MemStats) float64 { return float64(s.MSpanInuse) }},
+	{"MSpanSys", func(s *runtime.MemStats) float64 { return float64(s.MSpanSys) }},
+	{"Mallocs", func(s *runtime.MemStats) float64 { return float64(s.Mallocs) }},
+	{"NextGC", func(s *runtime.MemStats) float64 { return float64(s.NextGC) }},
+	{"NumForcedGC", func(s *runtime.MemStats) float64 { return float64(s.NumForcedGC) }},
+	{"NumGC", func(s *runtime.MemStats) float64 { return float64(s.NumGC) }},
+	{"OtherSys", func(s *runtime.MemStats) float64 { return float64(s.OtherSys) }},
+	{"PauseTotalNs", func(s *runtime.MemStats) float64 { return float64(s.PauseTotalNs) }},
+	{"StackInuse", func(s *runtime.MemStats) float64 { return float64(s.StackInuse) }},
+	{"StackSys", func(s *runtime.MemStats) float64 { return float64(s.StackSys) }},
+	{"Sys", func(s *runtime.MemStats) float64 { return float64(s.Sys) }},
+	{"TotalAlloc", func(s *runtime.MemStats) float64 { return float64(s.TotalAlloc) }},
+}
+
+// RuntimeCollector collects the memory statistics of the Go runtime
+// and counts the collections in PollCount.
+type RuntimeCollector struct {
+	readMemStats func(*runtime.MemStats)
+}
+
+// NewRuntimeCollector creates a collector of the Go runtime metrics.
+func NewRuntimeCollector() *RuntimeCollector {
+	return &RuntimeCollector{readMemStats: runtime.ReadMemStats}
+}
+
+// Name returns the name of the collector.
+func (c *RuntimeCollector) Name() string {
+	return RuntimeCollectorName
+}
+
+// Collect reads the memory statistics of the Go runtime.
+func (c *RuntimeCollector) Collect(context.Context) ([]entity.Metrics, error) {
+	memStats := &runtime.MemStats{}
+	c.readMemStats(memStats)
+
+	res := make([]entity.Metrics, 0, len(memStatsGauges)+1)
+	for _, g := range memStatsGauges {
+		res = append(res, gauge(g.name, g.value(memStats)))
+	}
+	res = append(res, counter("PollCount", 1))
+
+	return res, nil
+}
+
+// RandomCollector generates RandomValue.
+type RandomCollector struct{}
+
+// NewRandomCollector creates a collector of random values.
+func NewRandomCollector() *RandomCollector {
+	return &RandomCollector{}
+}
+
+// Name returns the name of the collector.
+func (c *RandomCollector) Name() string {
+	return RandomCollectorName
+}
+
+// Collect generates a random value.
+func (c *RandomCollector) Collect(context.Context) ([]entity.Metrics, error) {
+	return []entity.Metrics{gauge("RandomValue", rand.Float64())}, nil
+}
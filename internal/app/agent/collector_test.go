@@ -0,0 +1,271 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"io"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vladislaoramos/alemetric/configs"
+	"github.com/vladislaoramos/alemetric/internal/entity"
+	logger "github.com/vladislaoramos/alemetric/pkg/log"
+)
+
+type fakeCollector struct {
+	name  string
+	items []entity.Metrics
+	err   error
+}
+
+func (c *fakeCollector) Name() string {
+	return c.name
+}
+
+func (c *fakeCollector) Collect(context.Context) ([]entity.Metrics, error) {
+	return c.items, c.err
+}
+
+func testRegistry() *Registry {
+	return NewRegistry(logger.New("error", io.Discard))
+}
+
+func byName(items []entity.Metrics) map[string]entity.Metrics {
+	res := make(map[string]entity.Metrics, len(items))
+	for _, m := range items {
+		res[m.ID] = m
+	}
+	return res
+}
+
+func TestRegistry_PushFlush(t *testing.T) {
+	r := testRegistry()
+
+	r.Push(gauge("Alloc", 1), counter("PollCount", 1))
+	r.Push(gauge("Alloc", 2), counter("PollCount", 2), entity.Metrics{ID: "Invalid", MType: "histogram"})
+
+	items := r.Flush()
+	require.Len(t, items, 2)
+	require.Equal(t, "Alloc", items[0].ID)
+	require.Equal(t, entity.Gauge(2), *items[0].Value)
+	require.Equal(t, "PollCount", items[1].ID)
+	require.Equal(t, entity.Counter(3), *items[1].Delta)
+
+	// the gauges are kept, the counters are reset
+	items = r.Flush()
+	require.Len(t, items, 1)
+	require.Equal(t, "Alloc", items[0].ID)
+}
+
+func TestRegistry_Unfiltered(t *testing.T) {
+	r := testRegistry()
+
+	// the mark follows the last pushed value
+	r.PushUnfiltered(gauge("Alloc", 1), counter("requests", 1))
+	require.True(t, r.Unfiltered("Alloc"))
+	r.Push(gauge("Alloc", 2))
+	require.False(t, r.Unfiltered("Alloc"))
+
+	// the mark of a flushed counter is kept until the next flush without it
+	r.Flush()
+	require.True(t, r.Unfiltered("requests"))
+	r.Flush()
+	require.False(t, r.Unfiltered("requests"))
+}
+
+func TestRegistry_RegisterAll(t *testing.T) {
+	r := testRegistry()
+
+	cfg := configs.Agent{
+		PollInterval: time.Second,
+		Collectors: map[string]configs.Collector{
+			"slow":     {Interval: time.Minute},
+			"disabled": {Disabled: true},
+		},
+	}
+	r.RegisterAll(cfg,
+		&fakeCollector{name: "default"},
+		&fakeCollector{name: "slow"},
+		&fakeCollector{name: "disabled"},
+	)
+
+	require.Len(t, r.registrations, 2)
+	require.Equal(t, "default", r.registrations[0].collector.Name())
+	require.Equal(t, time.Second, r.registrations[0].interval)
+	require.Equal(t, "slow", r.registrations[1].collector.Name())
+	require.Equal(t, time.Minute, r.registrations[1].interval)
+}
+
+func TestRegistry_Run(t *testing.T) {
+	r := testRegistry()
+	r.Register(&fakeCollector{name: "ok", items: []entity.Metrics{gauge("A", 1)}}, time.Hour)
+	r.Register(&fakeCollector{
+		name:  "partial",
+		items: []entity.Metrics{gauge("B", 2)},
+		err:   errors.New("some error"),
+	}, time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r.Run(ctx)
+
+	// the collectors run immediately
+	require.Eventually(t, func() bool {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return len(r.gauges) == 2
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestRegistry_SetPollInterval(t *testing.T) {
+	r := testRegistry()
+	r.RegisterAll(configs.Agent{PollInterval: time.Hour},
+		&fakeCollector{name: "polled", items: []entity.Metrics{counter("Polled", 1)}})
+	r.Register(&fakeCollector{name: "slow", items: []entity.Metrics{counter("Slow", 1)}}, time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r.Run(ctx)
+
+	// the running collectors follow the new poll interval
+	r.SetPollInterval(10 * time.Millisecond)
+	require.Eventually(t, func() bool {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return r.counters["Polled"] >= 3
+	}, time.Second, 10*time.Millisecond)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	require.Equal(t, entity.Counter(1), r.counters["Slow"])
+}
+
+func TestRuntimeCollector(t *testing.T) {
+	c := NewRuntimeCollector()
+	c.readMemStats = func(s *runtime.MemStats) {
+		s.Alloc = 1000
+		s.GCCPUFraction = 0.5
+	}
+
+	items, err := c.Collect(context.Background())
+	require.NoError(t, err)
+	require.Len(t, items, len(memStatsGauges)+1)
+
+	metrics := byName(items)
+	require.Equal(t, entity.Gauge(1000), *metrics["Alloc"].Value)
+	require.Equal(t, entity.Gauge(0.5), *metrics["GCCPUFraction"].Value)
+	require.Equal(t, entity.Gauge(0), *metrics["Sys"].Value)
+	require.Equal(t, entity.Counter(1), *metrics["PollCount"].Delta)
+}
+
+func TestRandomCollector(t *testing.T) {
+	items, err := NewRandomCollector().Collect(context.Background())
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	require.Equal(t, "RandomValue", items[0].ID)
+	require.NotNil(t, items[0].Value)
+}
+
+func TestGopsutilCollector(t *testing.T) {
+	items, err := NewGopsutilCollector().Collect(context.Background())
+	require.NoError(t, err)
+
+	metrics := byName(items)
+	require.Contains(t, metrics, "TotalMemory")
+	require.Contains(t, metrics, "FreeMemory")
+	require.Greater(t, float64(*metrics["TotalMemory"].Value), 0.0)
+}
+
+func TestIncrements(t *testing.T) {
+	inc := make(increments)
+
+	_, ok := inc.counter("NetBytesSent", 100)
+	require.False(t, ok)
+
+	m, ok := inc.counter("NetBytesSent", 150)
+	require.True(t, ok)
+	require.Equal(t, entity.Counter(50), *m.Delta)
+
+	// the counter was reset
+	m, ok = inc.counter("NetBytesSent", 20)
+	require.True(t, ok)
+	require.Equal(t, entity.Counter(20), *m.Delta)
+}
+
+func TestCollectErrors(t *testing.T) {
+	var errs collectErrors
+	require.NoError(t, errs.errorOrNil())
+
+	errs = append(errs, errors.New("first"), errors.New("second"))
+	require.EqualError(t, errs.errorOrNil(), "first; second")
+}
+
+func TestHostCollectors(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("load", func(t *testing.T) {
+		items, err := NewLoadCollector().Collect(ctx)
+		require.NoError(t, err)
+		require.Contains(t, byName(items), "LoadAverage1")
+	})
+
+	t.Run("swap", func(t *testing.T) {
+		items, err := NewSwapCollector().Collect(ctx)
+		require.NoError(t, err)
+		require.Contains(t, byName(items), "SwapTotal")
+	})
+
+	t.Run("processes", func(t *testing.T) {
+		items, err := NewProcessesCollector().Collect(ctx)
+		require.NoError(t, err)
+		metrics := byName(items)
+		require.Contains(t, metrics, "ProcessCount")
+		require.Greater(t, float64(*metrics["ProcessCount"].Value), 0.0)
+	})
+
+	t.Run("net counters start from the second collection", func(t *testing.T) {
+		c := NewNetCollector()
+		items, err := c.Collect(ctx)
+		require.NoError(t, err)
+		require.Empty(t, items)
+
+		items, err = c.Collect(ctx)
+		require.NoError(t, err)
+		for _, m := range items {
+			require.Equal(t, "counter", m.MType)
+		}
+	})
+}
+
+func TestRegistry_AggregateGauges(t *testing.T) {
+	r := NewRegistry(logger.New("error", io.Discard), AggregateGauges(AggregateMin, AggregateMax, AggregateAvg))
+
+	r.Push(gauge("HeapAlloc", 10), gauge(`DiskUsed{mount="/"}`, 5))
+	r.Push(gauge("HeapAlloc", 40), counter("PollCount", 1))
+	r.Push(gauge("HeapAlloc", 20))
+
+	metrics := byName(r.Flush())
+	require.Len(t, metrics, 9)
+	require.Equal(t, entity.Gauge(20), *metrics["HeapAlloc"].Value)
+	require.Equal(t, entity.Gauge(10), *metrics["HeapAlloc_min"].Value)
+	require.Equal(t, entity.Gauge(40), *metrics["HeapAlloc_max"].Value)
+	require.Equal(t, entity.Gauge(70.0/3), *metrics["HeapAlloc_avg"].Value)
+	require.Equal(t, entity.Gauge(5), *metrics[`DiskUsed_max{mount="/"}`].Value)
+
+	// the gauges not collected since the previous report have their last values as aggregates
+	r.Push(gauge("HeapAlloc", 30))
+	metrics = byName(r.Flush())
+	require.Equal(t, entity.Gauge(30), *metrics["HeapAlloc_min"].Value)
+	require.Equal(t, entity.Gauge(5), *metrics[`DiskUsed_min{mount="/"}`].Value)
+}
+
+func TestParseAggregates(t *testing.T) {
+	aggregates, err := ParseAggregates([]string{"min", "avg"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"min", "avg"}, aggregates)
+
+	_, err = ParseAggregates([]string{"min", "p99"})
+	require.ErrorIs(t, err, ErrUnknownAggregate)
+}
@@ -7,3 +7,8 @@ type WebAPIAgent interface {
 	SendMetrics(string, string, *entity.Counter, *entity.Gauge) error
 	SendSeveralMetrics([]entity.Metrics) error
 }
+
+// ConfigSource defines the interface of requesting the configuration of the agent from the server.
+type ConfigSource interface {
+	GetConfig() (entity.AgentConfig, error)
+}
@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/vladislaoramos/alemetric/internal/entity"
+	"github.com/vladislaoramos/alemetric/internal/usecase"
+	logger "github.com/vladislaoramos/alemetric/pkg/log"
+)
+
+// ErrInvalidMetrics is returned when the pushed metrics has no ID or no value of its type.
+var ErrInvalidMetrics = errors.New("invalid metrics")
+
+// NewPushHandler creates the handler of the local push endpoint of the agent.
+// It accepts the same JSON as the server at /update/ and /updates/ and puts the metrics into the registry,
+// so they are sent to the server with the regular reports of the agent regardless of its metrics names.
+func NewPushHandler(registry *Registry, l logger.LogInterface) http.Handler {
+	handler := chi.NewRouter()
+
+	handler.Post("/update/", func(w http.ResponseWriter, r *http.Request) {
+		var m entity.Metrics
+		if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+			http.Error(w, "error decoding metrics", http.StatusBadRequest)
+			return
+		}
+		pushMetrics(w, registry, l, m)
+	})
+
+	handler.Post("/updates/", func(w http.ResponseWriter, r *http.Request) {
+		var items []entity.Metrics
+		if err := json.NewDecoder(r.Body).Decode(&items); err != nil {
+			http.Error(w, "error decoding several metrics: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		pushMetrics(w, registry, l, items...)
+	})
+
+	return handler
+}
+
+// pushMetrics puts the metrics into the registry if all of them are valid.
+func pushMetrics(w http.ResponseWriter, registry *Registry, l logger.LogInterface, items ...entity.Metrics) {
+	for _, m := range items {
+		if err := validateMetrics(m); err != nil {
+			l.Error(fmt.Sprintf("Push - Error: %s", err.Error()))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	registry.PushUnfiltered(items...)
+	w.WriteHeader(http.StatusOK)
+}
+
+func validateMetrics(m entity.Metrics) error {
+	if m.ID == "" {
+		return fmt.Errorf("%w: empty id", ErrInvalidMetrics)
+	}
+	if _, _, err := entity.ParseID(m.ID); err != nil {
+		return fmt.Errorf("%w: %s: %s", ErrInvalidMetrics, m.ID, err)
+	}
+
+	switch m.MType {
+	case usecase.Gauge:
+		if m.Value == nil {
+			return fmt.Errorf("%w: %s: gauge without value", ErrInvalidMetrics, m.ID)
+		}
+	case usecase.Counter:
+		if m.Delta == nil {
+			return fmt.Errorf("%w: %s: counter without delta", ErrInvalidMetrics, m.ID)
+		}
+	default:
+		return fmt.Errorf("%w: %s: unknown type %q", ErrInvalidMetrics, m.ID, m.MType)
+	}
+
+	return nil
+}
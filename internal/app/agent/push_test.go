@@ -0,0 +1,91 @@
+package agent
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vladislaoramos/alemetric/internal/entity"
+	logger "github.com/vladislaoramos/alemetric/pkg/log"
+)
+
+func TestPushHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+	}{
+		{
+			name:   "one metrics",
+			path:   "/update/",
+			body:   `{"id":"Requests","type":"counter","delta":2}`,
+			status: http.StatusOK,
+		},
+		{
+			name:   "several metrics",
+			path:   "/updates/",
+			body:   `[{"id":"Requests","type":"counter","delta":3},{"id":"Queue{app=\"api\"}","type":"gauge","value":1.5}]`,
+			status: http.StatusOK,
+		},
+		{
+			name:   "bad json",
+			path:   "/update/",
+			body:   `{"id":`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "gauge without value",
+			path:   "/updates/",
+			body:   `[{"id":"Requests","type":"counter","delta":3},{"id":"Queue","type":"gauge"}]`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "unknown type",
+			path:   "/update/",
+			body:   `{"id":"Requests","type":"histogram","delta":2}`,
+			status: http.StatusBadRequest,
+		},
+	}
+
+	r := NewRegistry(logger.New("error", io.Discard))
+	handler := NewPushHandler(r, logger.New("error", io.Discard))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			require.Equal(t, tt.status, w.Code)
+		})
+	}
+
+	// only the valid requests are pushed
+	metrics := byName(r.Flush())
+	require.Len(t, metrics, 2)
+	require.Equal(t, entity.Counter(5), *metrics["Requests"].Delta)
+	require.Equal(t, entity.Gauge(1.5), *metrics[`Queue{app="api"}`].Value)
+}
+
+func TestPushHandler_SentByWorker(t *testing.T) {
+	l := logger.New("error", io.Discard)
+	r := NewRegistry(l, AggregateGauges(AggregateMax))
+	r.Push(gauge("Alloc", 1), gauge("Sys", 2))
+
+	body := `[{"id":"Requests","type":"counter","delta":3},{"id":"Queue{app=\"api\"}","type":"gauge","value":1.5}]`
+	req := httptest.NewRequest(http.MethodPost, "/updates/", strings.NewReader(body))
+	resp := httptest.NewRecorder()
+	NewPushHandler(r, l).ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	// the pushed metrics are sent regardless of the metrics names
+	api := &fakeWebAPI{}
+	NewWorker(l, r, []string{"Alloc"}, api, 1).sendAll()
+
+	sort.Strings(api.sent)
+	require.Equal(t, []string{"Alloc", "Alloc_max", `Queue_max{app="api"}`, `Queue{app="api"}`, "Requests"}, api.sent)
+}
@@ -0,0 +1,146 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/vladislaoramos/alemetric/configs"
+	"github.com/vladislaoramos/alemetric/internal/agentconfig"
+	"github.com/vladislaoramos/alemetric/internal/entity"
+	logger "github.com/vladislaoramos/alemetric/pkg/log"
+)
+
+// RemoteConfig applies the configuration served by the server to the running agent:
+// the poll interval to the registry, the report interval to the report ticker and the registry,
+// the metrics names and the rate limit to the worker.
+// A field not served by the server gets the local value of the agent.
+// Without the server configuration the local configuration is applied, e.g. on its reload.
+type RemoteConfig struct {
+	source     ConfigSource
+	registry   *Registry
+	worker     *Worker
+	sendTicker *time.Ticker
+	l          logger.LogInterface
+
+	mu      sync.Mutex
+	local   entity.AgentConfig
+	served  entity.AgentConfig
+	applied entity.AgentConfig
+}
+
+// NewRemoteConfig creates the remote configuration of the agent with the local configuration.
+func NewRemoteConfig(
+	cfg configs.Agent,
+	source ConfigSource,
+	registry *Registry,
+	worker *Worker,
+	sendTicker *time.Ticker,
+	l logger.LogInterface,
+) *RemoteConfig {
+	local := localConfig(cfg)
+
+	return &RemoteConfig{
+		source:     source,
+		registry:   registry,
+		worker:     worker,
+		sendTicker: sendTicker,
+		l:          l,
+		local:      local,
+		applied:    local,
+	}
+}
+
+// Run updates the configuration every interval until the context is done.
+func (rc *RemoteConfig) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := rc.Update(); err != nil {
+			rc.l.Error(err.Error())
+		}
+	}
+}
+
+// Update requests the configuration from the server and applies its changes.
+// An invalid configuration is not applied.
+func (rc *RemoteConfig) Update() error {
+	served, err := rc.source.GetConfig()
+	if err != nil {
+		return fmt.Errorf("error updating agent config: %w", err)
+	}
+
+	return rc.Apply(served)
+}
+
+// Apply applies the changes of the served configuration.
+func (rc *RemoteConfig) Apply(served entity.AgentConfig) error {
+	if err := agentconfig.Validate(served); err != nil {
+		return fmt.Errorf("error applying agent config: %w", err)
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.served = served
+	rc.apply()
+
+	return nil
+}
+
+// SetLocal replaces the local configuration and applies its changes
+// to the fields not served by the server.
+func (rc *RemoteConfig) SetLocal(cfg configs.Agent) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.local = localConfig(cfg)
+	rc.apply()
+}
+
+func (rc *RemoteConfig) apply() {
+	cfg := agentconfig.Merge(rc.local, rc.served)
+
+	if cfg.PollInterval != rc.applied.PollInterval {
+		interval, _ := time.ParseDuration(cfg.PollInterval)
+		rc.registry.SetPollInterval(interval)
+		rc.l.Info(fmt.Sprintf("Poll interval is set to %s", interval))
+	}
+
+	if cfg.ReportInterval != rc.applied.ReportInterval {
+		interval, _ := time.ParseDuration(cfg.ReportInterval)
+		rc.sendTicker.Reset(interval)
+		rc.registry.SetReportInterval(interval)
+		rc.l.Info(fmt.Sprintf("Report interval is set to %s", interval))
+	}
+
+	if !reflect.DeepEqual(cfg.MetricsNames, rc.applied.MetricsNames) {
+		rc.worker.SetMetricsNames(cfg.MetricsNames)
+		rc.l.Info(fmt.Sprintf("Metrics names are set to %v", cfg.MetricsNames))
+	}
+
+	if cfg.RateLimit != rc.applied.RateLimit {
+		rc.worker.SetRateLimit(cfg.RateLimit)
+		rc.l.Info(fmt.Sprintf("Rate limit is set to %d", cfg.RateLimit))
+	}
+
+	rc.applied = cfg
+}
+
+func localConfig(cfg configs.Agent) entity.AgentConfig {
+	return entity.AgentConfig{
+		PollInterval:   cfg.PollInterval.String(),
+		ReportInterval: cfg.ReportInterval.String(),
+		MetricsNames:   cfg.MetricsNames,
+		RateLimit:      cfg.RateLimit,
+	}
+}
@@ -0,0 +1,118 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vladislaoramos/alemetric/configs"
+	"github.com/vladislaoramos/alemetric/internal/agentconfig"
+	"github.com/vladislaoramos/alemetric/internal/entity"
+	logger "github.com/vladislaoramos/alemetric/pkg/log"
+)
+
+type fakeConfigSource struct {
+	cfg entity.AgentConfig
+	err error
+}
+
+func (f *fakeConfigSource) GetConfig() (entity.AgentConfig, error) {
+	return f.cfg, f.err
+}
+
+func TestRemoteConfig_Update(t *testing.T) {
+	l := logger.New("error", io.Discard)
+	cfg := configs.Agent{
+		PollInterval:   time.Second,
+		ReportInterval: 10 * time.Second,
+		MetricsNames:   []string{"Alloc"},
+		RateLimit:      1,
+	}
+
+	r := NewRegistry(l)
+	r.RegisterAll(cfg, &fakeCollector{name: "polled"})
+	r.Register(&fakeCollector{name: "slow"}, time.Minute)
+	r.SetReportInterval(cfg.ReportInterval)
+	r.RegisterReported(&fakeCollector{name: "reported"})
+
+	w := NewWorker(l, r, cfg.MetricsNames, &fakeWebAPI{}, cfg.RateLimit)
+	sendTicker := time.NewTicker(cfg.ReportInterval)
+	defer sendTicker.Stop()
+
+	source := &fakeConfigSource{cfg: entity.AgentConfig{
+		PollInterval: "5s",
+		MetricsNames: []string{"Alloc", "Sys"},
+		RateLimit:    4,
+	}}
+	rc := NewRemoteConfig(cfg, source, r, w, sendTicker, l)
+
+	require.NoError(t, rc.Update())
+	require.Equal(t, 5*time.Second, r.registrations[0].interval)
+	require.Equal(t, time.Minute, r.registrations[1].interval)
+	require.Equal(t, []string{"Alloc", "Sys"}, w.metricsNames)
+	require.Equal(t, uint(4), w.rateLimitCounter)
+
+	// the invalid configuration is not applied
+	source.cfg = entity.AgentConfig{PollInterval: "2s", ReportInterval: "soon"}
+	require.ErrorIs(t, rc.Update(), agentconfig.ErrInvalidConfig)
+	require.Equal(t, 5*time.Second, r.registrations[0].interval)
+
+	source.err = errors.New("some error")
+	require.Error(t, rc.Update())
+	require.Equal(t, uint(4), w.rateLimitCounter)
+
+	// the fields not served get the local values
+	source.cfg, source.err = entity.AgentConfig{RateLimit: 2}, nil
+	require.NoError(t, rc.Update())
+	require.Equal(t, time.Second, r.registrations[0].interval)
+	require.Equal(t, []string{"Alloc"}, w.metricsNames)
+	require.Equal(t, uint(2), w.rateLimitCounter)
+
+	// the reloaded local config does not override the served fields
+	cfg.PollInterval, cfg.MetricsNames, cfg.RateLimit = 3*time.Second, []string{"Sys"}, 8
+	rc.SetLocal(cfg)
+	require.Equal(t, 3*time.Second, r.registrations[0].interval)
+	require.Equal(t, []string{"Sys"}, w.metricsNames)
+	require.Equal(t, uint(2), w.rateLimitCounter)
+
+	// the collectors running every report interval follow it
+	require.Equal(t, 10*time.Second, r.registrations[2].interval)
+	cfg.ReportInterval = 20 * time.Second
+	rc.SetLocal(cfg)
+	require.Equal(t, 20*time.Second, r.registrations[2].interval)
+	require.Equal(t, time.Minute, r.registrations[1].interval)
+}
+
+func TestWebAPI_GetConfig(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/agents/config" || r.Header.Get(AgentGroupHeader) != "db" {
+			w.WriteHeader(http.StatusNotImplemented)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(entity.AgentConfig{PollInterval: "5s", RateLimit: 2})
+	}))
+	defer ts.Close()
+
+	api := NewWebAPI(resty.New().SetBaseURL(ts.URL).SetHeader(AgentGroupHeader, "db"), noEncryptionKey, "")
+	cfg, err := api.GetConfig()
+	require.NoError(t, err)
+	require.Equal(t, entity.AgentConfig{PollInterval: "5s", RateLimit: 2}, cfg)
+
+	api = NewWebAPI(resty.New().SetBaseURL(ts.URL), noEncryptionKey, "")
+	_, err = api.GetConfig()
+	require.Error(t, err)
+
+	// the changed server URL replaces the base URL of the client
+	api = NewWebAPI(resty.New().SetBaseURL("http://localhost:1").SetHeader(AgentGroupHeader, "db"), noEncryptionKey, "")
+	api.SetServerURL(ts.URL)
+	_, err = api.GetConfig()
+	require.NoError(t, err)
+}
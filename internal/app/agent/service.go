@@ -7,20 +7,34 @@ import (
 	"crypto/x509"
 	"encoding/json"
 	"encoding/pem"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"os"
+	"sync"
 
 	"github.com/go-resty/resty/v2"
 	"github.com/vladislaoramos/alemetric/internal/entity"
 )
 
+// AgentHeader, AgentVersionHeader and AgentHostHeader carry the identity of the agent sending the metrics.
+// AgentGroupHeader carries the group of the agent requesting its configuration.
+const (
+	AgentHeader        = "X-Agent-Name"
+	AgentVersionHeader = "X-Agent-Version"
+	AgentHostHeader    = "X-Agent-Host"
+	AgentGroupHeader   = "X-Agent-Group"
+)
+
 // WebAPIClient implements the client web-application for Agent.
 type WebAPIClient struct {
 	client    *resty.Client
 	Key       string
 	publicKey string
+
+	// serverURL replaces the base URL of the client if it is set.
+	urlMu     sync.RWMutex
+	serverURL string
 }
 
 func NewWebAPI(client *resty.Client, key string, cryptoKey string) *WebAPIClient {
@@ -31,6 +45,21 @@ func NewWebAPI(client *resty.Client, key string, cryptoKey string) *WebAPIClient
 	}
 }
 
+// SetServerURL changes the URL of the server the requests are sent to, e.g. http://localhost:8080.
+func (wc *WebAPIClient) SetServerURL(serverURL string) {
+	wc.urlMu.Lock()
+	defer wc.urlMu.Unlock()
+
+	wc.serverURL = serverURL
+}
+
+func (wc *WebAPIClient) url(path string) string {
+	wc.urlMu.RLock()
+	defer wc.urlMu.RUnlock()
+
+	return wc.serverURL + path
+}
+
 // SendMetrics sends a client request for a metrics update to the server.
 func (wc *WebAPIClient) SendMetrics(
 	metricsName,
@@ -50,23 +79,16 @@ func (wc *WebAPIClient) SendMetrics(
 	// respBody, _ := json.Marshal(body)
 	// log.Printf("req body: %s", string(respBody))
 
-	b, err := json.Marshal(body)
-	if err != nil {
-		return err
-	}
-
-	publicKey, err := loadPublicKeyFromFile(wc.publicKey)
+	encryptedBody, err := wc.encryptedJSON(body)
 	if err != nil {
 		return err
 	}
 
-	encryptedBody := tryEncrypt(b, publicKey)
-
 	resp, err := wc.client.
 		R().
 		SetHeader("Content-Type", "application/json").
 		SetBody(encryptedBody).
-		Post("/update/")
+		Post(wc.url("/update/"))
 	if err != nil {
 		return fmt.Errorf("cannot send metrics from agent: %w", err)
 	}
@@ -80,12 +102,33 @@ func (wc *WebAPIClient) SendMetrics(
 }
 
 // SendSeveralMetrics sends a client request for several metrics update to the server.
+// As by SendMetrics, each metrics is signed and the request is encrypted.
+// The metrics too long to be encrypted with the public key together are sent one by one.
 func (wc *WebAPIClient) SendSeveralMetrics(items []entity.Metrics) error {
+	body := make([]entity.Metrics, 0, len(items))
+	for _, m := range items {
+		m.SignData("agent", wc.Key)
+		body = append(body, m)
+	}
+
+	encryptedBody, err := wc.encryptedJSON(body)
+	if errors.Is(err, rsa.ErrMessageTooLong) && len(items) > 1 {
+		for _, m := range items {
+			if err = wc.SendMetrics(m.ID, m.MType, m.Delta, m.Value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
 	resp, err := wc.client.
 		R().
 		SetHeader("Content-Type", "application/json").
-		SetBody(items).
-		Post("/updates/")
+		SetBody(encryptedBody).
+		Post(wc.url("/updates/"))
 	if err != nil {
 		return fmt.Errorf("cannot send several metrics from agent: %w", err)
 	}
@@ -97,19 +140,54 @@ func (wc *WebAPIClient) SendSeveralMetrics(items []entity.Metrics) error {
 	return nil
 }
 
-func tryEncrypt(msg []byte, key *rsa.PublicKey) []byte {
+// GetConfig requests the configuration of the agent from the server.
+func (wc *WebAPIClient) GetConfig() (entity.AgentConfig, error) {
+	var cfg entity.AgentConfig
+
+	resp, err := wc.client.
+		R().
+		SetResult(&cfg).
+		Get(wc.url("/api/v1/agents/config"))
+	if err != nil {
+		return entity.AgentConfig{}, fmt.Errorf("cannot get agent config: %w", err)
+	}
+
+	status := resp.StatusCode()
+	if status != http.StatusOK {
+		return entity.AgentConfig{}, fmt.Errorf("getting agent config with not successful status code: %d", status)
+	}
+
+	return cfg, nil
+}
+
+// encryptedJSON marshals the body and encrypts it with the public key if it is set.
+func (wc *WebAPIClient) encryptedJSON(body interface{}) ([]byte, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, err := loadPublicKeyFromFile(wc.publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return tryEncrypt(b, publicKey)
+}
+
+func tryEncrypt(msg []byte, key *rsa.PublicKey) ([]byte, error) {
 	if key == nil {
-		return msg
+		return msg, nil
 	}
 
 	hash := sha512.New()
 
 	result, err := rsa.EncryptOAEP(hash, rand.Reader, key, msg, nil)
 	if err != nil {
-		log.Fatal(err)
+		return nil, fmt.Errorf("cannot encrypt metrics: %w", err)
 	}
 
-	return result
+	return result, nil
 }
 
 func loadPublicKeyFromFile(filePath string) (*rsa.PublicKey, error) {
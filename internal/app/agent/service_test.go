@@ -1,8 +1,18 @@
 package agent
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 
 	"github.com/vladislaoramos/alemetric/internal/entity"
@@ -80,7 +90,7 @@ func TestWebAPI_SendMetric(t *testing.T) {
 				serverURL = testServer.URL
 			}
 
-			webAPI := &WebAPIClient{resty.New().SetBaseURL(serverURL), noEncryptionKey, ""}
+			webAPI := NewWebAPI(resty.New().SetBaseURL(serverURL), noEncryptionKey, "")
 
 			val := entity.Gauge(tt.args.metricsValue)
 
@@ -141,7 +151,7 @@ func TestWebAPI_SendSeveralMetric(t *testing.T) {
 				serverURL = testServer.URL
 			}
 
-			webAPI := &WebAPIClient{resty.New().SetBaseURL(serverURL), noEncryptionKey, ""}
+			webAPI := NewWebAPI(resty.New().SetBaseURL(serverURL), noEncryptionKey, "")
 
 			err := webAPI.SendSeveralMetrics(tt.args)
 			if !tt.wantErr {
@@ -151,3 +161,54 @@ func TestWebAPI_SendSeveralMetric(t *testing.T) {
 		})
 	}
 }
+
+func TestWebAPI_SendSeveralMetrics_SignedAndEncrypted(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	require.NoError(t, err)
+	keyPath := filepath.Join(t.TempDir(), "public.pem")
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600))
+
+	var mu sync.Mutex
+	received := make(map[string][]entity.Metrics)
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		plain, err := rsa.DecryptOAEP(sha512.New(), rand.Reader, privateKey, body, nil)
+		require.NoError(t, err)
+
+		var items []entity.Metrics
+		if r.URL.Path == "/update/" {
+			var m entity.Metrics
+			require.NoError(t, json.Unmarshal(plain, &m))
+			items = append(items, m)
+		} else {
+			require.NoError(t, json.Unmarshal(plain, &items))
+		}
+
+		mu.Lock()
+		received[r.URL.Path] = append(received[r.URL.Path], items...)
+		mu.Unlock()
+	}))
+	defer testServer.Close()
+
+	webAPI := NewWebAPI(resty.New().SetBaseURL(testServer.URL), "key", keyPath)
+
+	require.NoError(t, webAPI.SendSeveralMetrics([]entity.Metrics{counter("A", 1)}))
+	require.Len(t, received["/updates/"], 1)
+	require.True(t, received["/updates/"][0].CheckDataSign("key"))
+
+	// the metrics too long to be encrypted together are sent one by one
+	items := make([]entity.Metrics, 0, 5)
+	for _, id := range []string{"PollCount", "Requests", "Errors", "Retries", "Timeouts"} {
+		items = append(items, counter(id, 1))
+	}
+	require.NoError(t, webAPI.SendSeveralMetrics(items))
+	require.Len(t, received["/updates/"], 1)
+	require.Len(t, received["/update/"], 5)
+	for _, m := range received["/update/"] {
+		require.True(t, m.CheckDataSign("key"), m.ID)
+	}
+}
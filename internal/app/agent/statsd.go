@@ -0,0 +1,283 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/vladislaoramos/alemetric/internal/entity"
+	logger "github.com/vladislaoramos/alemetric/pkg/log"
+)
+
+const (
+	statsdCounter = "c"
+	statsdGauge   = "g"
+	statsdTimer   = "ms"
+
+	statsdPacketSize = 65535
+)
+
+// ErrInvalidStatsD is returned when a StatsD line cannot be parsed.
+var ErrInvalidStatsD = errors.New("invalid statsd line")
+
+// statsdSample is a parsed StatsD line `name:value|type[|@rate][|#tag:value,...]`.
+type statsdSample struct {
+	id    string
+	value float64
+	mtype string
+	rate  float64
+	// relative is set for the gauges with the sign, which change the gauge instead of setting it.
+	relative bool
+}
+
+// parseStatsD parses a StatsD line. The tags are converted into the labels of the metrics.
+func parseStatsD(line string) (statsdSample, error) {
+	sep := strings.LastIndexByte(strings.SplitN(line, "|", 2)[0], ':')
+	if sep <= 0 {
+		return statsdSample{}, fmt.Errorf("%w: %q", ErrInvalidStatsD, line)
+	}
+
+	name := line[:sep]
+	parts := strings.Split(line[sep+1:], "|")
+	if len(parts) < 2 {
+		return statsdSample{}, fmt.Errorf("%w: %q", ErrInvalidStatsD, line)
+	}
+
+	value, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return statsdSample{}, fmt.Errorf("%w: %q: bad value", ErrInvalidStatsD, line)
+	}
+
+	sample := statsdSample{
+		value:    value,
+		mtype:    parts[1],
+		rate:     1,
+		relative: strings.HasPrefix(parts[0], "+") || strings.HasPrefix(parts[0], "-"),
+	}
+
+	switch sample.mtype {
+	case statsdCounter, statsdGauge, statsdTimer:
+	default:
+		return statsdSample{}, fmt.Errorf("%w: %q: unsupported type", ErrInvalidStatsD, line)
+	}
+
+	var labels map[string]string
+	for _, part := range parts[2:] {
+		switch {
+		case strings.HasPrefix(part, "@"):
+			sample.rate, err = strconv.ParseFloat(part[1:], 64)
+			if err != nil || sample.rate <= 0 || sample.rate > 1 {
+				return statsdSample{}, fmt.Errorf("%w: %q: bad sample rate", ErrInvalidStatsD, line)
+			}
+		case strings.HasPrefix(part, "#"):
+			labels = make(map[string]string)
+			for _, tag := range strings.Split(part[1:], ",") {
+				kv := strings.SplitN(tag, ":", 2)
+				if len(kv) == 2 {
+					labels[kv[0]] = kv[1]
+				} else {
+					labels[kv[0]] = ""
+				}
+			}
+		}
+	}
+
+	sample.id = entity.FormatID(name, labels)
+
+	return sample, nil
+}
+
+type statsdTimerStats struct {
+	// count is scaled by the sample rates, received is not
+	count    float64
+	received int
+	sum      float64
+	min      float64
+	max      float64
+}
+
+// StatsDCollector receives the StatsD metrics over UDP or a unix socket and aggregates them
+// between the collections: counters are summed, the last value of a gauge is kept,
+// timers are reported as NAME_count counter and NAME_min, NAME_max, NAME_avg gauges.
+// The sample rates scale the counters and the counts of the timers.
+type StatsDCollector struct {
+	l logger.LogInterface
+
+	mu       sync.Mutex
+	counters map[string]float64
+	gauges   map[string]float64
+	updated  map[string]struct{}
+	timers   map[string]*statsdTimerStats
+	// remainders are the fractions of the sampled counts carried over to the next collection
+	remainders map[string]float64
+}
+
+// NewStatsDCollector creates a StatsD collector. It receives nothing until Listen is called.
+func NewStatsDCollector(l logger.LogInterface) *StatsDCollector {
+	return &StatsDCollector{
+		l:        l,
+		counters: make(map[string]float64),
+		gauges:   make(map[string]float64),
+		updated:  make(map[string]struct{}),
+		timers:   make(map[string]*statsdTimerStats),
+
+		remainders: make(map[string]float64),
+	}
+}
+
+// Name returns the name of the collector.
+func (c *StatsDCollector) Name() string {
+	return StatsDCollectorName
+}
+
+// Unfiltered marks the received metrics to be sent regardless of the metrics names of the worker.
+func (c *StatsDCollector) Unfiltered() {}
+
+// Listen starts receiving the StatsD packets on the UDP address and the unix socket path,
+// if they are not empty, until the context is done.
+func (c *StatsDCollector) Listen(ctx context.Context, address, socket string) error {
+	if address != "" {
+		conn, err := net.ListenPacket("udp", address)
+		if err != nil {
+			return fmt.Errorf("cannot listen statsd on %s: %w", address, err)
+		}
+		go c.serve(ctx, conn)
+	}
+
+	if socket != "" {
+		// remove the socket left by the previous run
+		if err := os.Remove(socket); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("cannot remove statsd socket %s: %w", socket, err)
+		}
+		conn, err := net.ListenPacket("unixgram", socket)
+		if err != nil {
+			return fmt.Errorf("cannot listen statsd on %s: %w", socket, err)
+		}
+		go c.serve(ctx, conn)
+	}
+
+	return nil
+}
+
+func (c *StatsDCollector) serve(ctx context.Context, conn net.PacketConn) {
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	buf := make([]byte, statsdPacketSize)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() == nil {
+				c.l.Error(fmt.Sprintf("error reading statsd packet: %s", err))
+				continue
+			}
+			return
+		}
+		c.Handle(string(buf[:n]))
+	}
+}
+
+// Handle aggregates the metrics of the StatsD packet, one metrics per line.
+// Invalid lines are logged and skipped.
+func (c *StatsDCollector) Handle(packet string) {
+	for _, line := range strings.Split(packet, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		sample, err := parseStatsD(line)
+		if err != nil {
+			c.l.Error(err.Error())
+			continue
+		}
+		c.add(sample)
+	}
+}
+
+func (c *StatsDCollector) add(s statsdSample) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch s.mtype {
+	case statsdCounter:
+		c.counters[s.id] += s.value / s.rate
+	case statsdGauge:
+		if s.relative {
+			c.gauges[s.id] += s.value
+		} else {
+			c.gauges[s.id] = s.value
+		}
+		c.updated[s.id] = struct{}{}
+	case statsdTimer:
+		t, ok := c.timers[s.id]
+		if !ok {
+			t = &statsdTimerStats{min: s.value, max: s.value}
+			c.timers[s.id] = t
+		}
+		t.count += 1 / s.rate
+		t.received++
+		t.sum += s.value
+		t.min = math.Min(t.min, s.value)
+		t.max = math.Max(t.max, s.value)
+	}
+}
+
+// Collect returns the metrics aggregated since the previous collection.
+// The gauges keep their values for the relative changes, but only the updated gauges are returned.
+func (c *StatsDCollector) Collect(context.Context) ([]entity.Metrics, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var res []entity.Metrics
+	for id, value := range c.counters {
+		res = append(res, counter(id, c.whole(id, value)))
+	}
+	for id := range c.updated {
+		res = append(res, gauge(id, c.gauges[id]))
+	}
+	for id, t := range c.timers {
+		name, labels, err := entity.ParseID(id)
+		if err != nil {
+			name = id
+		}
+		res = append(res,
+			counter(entity.FormatID(name+"_count", labels), c.whole(entity.FormatID(name+"_count", labels), t.count)),
+			gauge(entity.FormatID(name+"_min", labels), t.min),
+			gauge(entity.FormatID(name+"_max", labels), t.max),
+			gauge(entity.FormatID(name+"_avg", labels), t.sum/float64(t.received)),
+		)
+	}
+
+	c.counters = make(map[string]float64)
+	c.updated = make(map[string]struct{})
+	c.timers = make(map[string]*statsdTimerStats)
+
+	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+	return res, nil
+}
+
+// whole returns the whole part of the sampled count with the remainder of the previous collection
+// and keeps the fraction as the new remainder, so that the sampled counters are not biased by rounding.
+func (c *StatsDCollector) whole(id string, count float64) int64 {
+	count += c.remainders[id]
+
+	// the epsilon absorbs the errors of the division by the sample rate
+	n := math.Floor(count + 1e-9)
+	if rest := count - n; rest > 1e-9 {
+		c.remainders[id] = rest
+	} else {
+		delete(c.remainders, id)
+	}
+
+	return int64(n)
+}
@@ -0,0 +1,147 @@
+package agent
+
+import (
+	"context"
+	"io"
+	"net"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vladislaoramos/alemetric/internal/entity"
+	logger "github.com/vladislaoramos/alemetric/pkg/log"
+)
+
+func TestParseStatsD(t *testing.T) {
+	tests := []struct {
+		line string
+		want statsdSample
+		err  bool
+	}{
+		{line: "requests:1|c", want: statsdSample{id: "requests", value: 1, mtype: "c", rate: 1}},
+		{line: "requests:2|c|@0.5", want: statsdSample{id: "requests", value: 2, mtype: "c", rate: 0.5}},
+		{line: "queue:-3|g", want: statsdSample{id: "queue", value: -3, mtype: "g", rate: 1, relative: true}},
+		{line: "latency:12.5|ms|#route:/api,method:get", want: statsdSample{
+			id: `latency{method="get",route="/api"}`, value: 12.5, mtype: "ms", rate: 1,
+		}},
+		{line: "requests", err: true},
+		{line: "requests:1", err: true},
+		{line: "requests:x|c", err: true},
+		{line: "requests:1|s", err: true},
+		{line: "requests:1|c|@2", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, err := parseStatsD(tt.line)
+			if tt.err {
+				require.ErrorIs(t, err, ErrInvalidStatsD)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestStatsDCollector_Collect(t *testing.T) {
+	c := NewStatsDCollector(logger.New("error", io.Discard))
+
+	c.Handle("requests:1|c\nrequests:1|c|@0.5\nbad line\n")
+	c.Handle("queue:10|g\nqueue:+5|g")
+	c.Handle("latency:10|ms\nlatency:30|ms|@0.5\nlatency:20|ms")
+
+	items, err := c.Collect(context.Background())
+	require.NoError(t, err)
+
+	metrics := byName(items)
+	require.Len(t, metrics, 6)
+	require.Equal(t, entity.Counter(3), *metrics["requests"].Delta)
+	require.Equal(t, entity.Gauge(15), *metrics["queue"].Value)
+	require.Equal(t, entity.Counter(4), *metrics["latency_count"].Delta)
+	require.Equal(t, entity.Gauge(10), *metrics["latency_min"].Value)
+	require.Equal(t, entity.Gauge(30), *metrics["latency_max"].Value)
+	require.Equal(t, entity.Gauge(20), *metrics["latency_avg"].Value)
+
+	// only the updated gauges are reported, relative to the kept value
+	c.Handle("queue:-1|g")
+	items, err = c.Collect(context.Background())
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	require.Equal(t, entity.Gauge(14), *items[0].Value)
+}
+
+func TestStatsDCollector_SampledRemainder(t *testing.T) {
+	c := NewStatsDCollector(logger.New("error", io.Discard))
+
+	// every sample counts 1/0.3 = 3.33 events, the fractions are carried over
+	var total entity.Counter
+	for i := 0; i < 3; i++ {
+		c.Handle("requests:1|c|@0.3\nlatency:10|ms|@0.3")
+		items, err := c.Collect(context.Background())
+		require.NoError(t, err)
+
+		metrics := byName(items)
+		require.Equal(t, *metrics["requests"].Delta, *metrics["latency_count"].Delta)
+		total += *metrics["requests"].Delta
+	}
+	require.Equal(t, entity.Counter(10), total)
+
+	// the low-rate counter is not reported as zero forever
+	c.Handle("rare:1|c|@0.4")
+	items, err := c.Collect(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, entity.Counter(2), *items[0].Delta)
+	c.Handle("rare:1|c|@0.4")
+	items, err = c.Collect(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, entity.Counter(3), *items[0].Delta)
+}
+
+func TestStatsDCollector_SentByWorker(t *testing.T) {
+	l := logger.New("error", io.Discard)
+	c := NewStatsDCollector(l)
+	c.Handle("requests:1|c\nlatency:10|ms")
+
+	r := NewRegistry(l)
+	r.Push(gauge("Sys", 1))
+	r.collect(context.Background(), c)
+
+	// the received metrics are sent regardless of the metrics names
+	api := &fakeWebAPI{}
+	NewWorker(l, r, []string{"Alloc"}, api, 1).sendAll()
+
+	sort.Strings(api.sent)
+	require.Equal(t, []string{"latency_avg", "latency_count", "latency_max", "latency_min", "requests"}, api.sent)
+}
+
+func TestStatsDCollector_Listen(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := udp.LocalAddr().String()
+	require.NoError(t, udp.Close())
+
+	socket := filepath.Join(t.TempDir(), "statsd.sock")
+
+	c := NewStatsDCollector(logger.New("error", io.Discard))
+	require.NoError(t, c.Listen(ctx, address, socket))
+
+	for network, addr := range map[string]string{"udp": address, "unixgram": socket} {
+		conn, err := net.Dial(network, addr)
+		require.NoError(t, err)
+		_, err = conn.Write([]byte("requests:1|c"))
+		require.NoError(t, err)
+		require.NoError(t, conn.Close())
+	}
+
+	require.Eventually(t, func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return c.counters["requests"] == 2
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,178 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/vladislaoramos/alemetric/internal/entity"
+)
+
+// Telemetry collects the metrics of the agent itself:
+// AgentSendSuccess and AgentSendFailure count the sent and failed metrics,
+// AgentQueueDepth is the number of metrics in the last report,
+// AgentLastReport is the Unix time of the last report delivered without failures,
+// AgentCollectDuration{collector="NAME"} is the duration of the last collection in seconds
+// and AgentCollectErrors{collector="NAME"} counts the failed collections.
+type Telemetry struct {
+	mu sync.Mutex
+
+	// the counters since the previous collection of the telemetry
+	sendSuccess   int64
+	sendFailure   int64
+	collectErrors map[string]int64
+
+	status Status
+}
+
+// Status is the state of the agent reported by the status endpoint.
+type Status struct {
+	Ready       bool                        `json:"ready"`
+	LastReport  *time.Time                  `json:"last_report,omitempty"`
+	Sent        int64                       `json:"sent"`
+	Failed      int64                       `json:"failed"`
+	QueueDepth  int                         `json:"queue_depth"`
+	Collections map[string]CollectionStatus `json:"collections"`
+}
+
+// CollectionStatus is the state of the last collection of a collector.
+type CollectionStatus struct {
+	At        time.Time `json:"at"`
+	Duration  float64   `json:"duration"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// NewTelemetry creates the telemetry of the agent.
+func NewTelemetry() *Telemetry {
+	return &Telemetry{
+		collectErrors: make(map[string]int64),
+		status:        Status{Collections: make(map[string]CollectionStatus)},
+	}
+}
+
+// Name returns the name of the collector.
+func (t *Telemetry) Name() string {
+	return TelemetryCollectorName
+}
+
+// ObserveCollection records the collection by the collector.
+func (t *Telemetry) ObserveCollection(name string, at time.Time, d time.Duration, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	status := CollectionStatus{At: at, Duration: d.Seconds()}
+	if err != nil {
+		status.LastError = err.Error()
+		t.collectErrors[name]++
+	} else if _, ok := t.collectErrors[name]; !ok {
+		t.collectErrors[name] = 0
+	}
+	t.status.Collections[name] = status
+}
+
+// ObserveSend records the sending of a metrics.
+func (t *Telemetry) ObserveSend(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err != nil {
+		t.sendFailure++
+		t.status.Failed++
+		return
+	}
+
+	t.sendSuccess++
+	t.status.Sent++
+	t.status.Ready = true
+}
+
+// ObserveReport records the report of the queued metrics, of which the failed ones were not delivered.
+func (t *Telemetry) ObserveReport(at time.Time, queued, failed int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.status.QueueDepth = queued
+	if failed == 0 && queued > 0 {
+		t.status.LastReport = &at
+	}
+}
+
+// Status returns the current state of the agent.
+func (t *Telemetry) Status() Status {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	status := t.status
+	status.Collections = make(map[string]CollectionStatus, len(t.status.Collections))
+	for name, c := range t.status.Collections {
+		status.Collections[name] = c
+	}
+
+	return status
+}
+
+// Collect returns the telemetry of the agent.
+func (t *Telemetry) Collect(context.Context) ([]entity.Metrics, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	res := []entity.Metrics{
+		counter("AgentSendSuccess", t.sendSuccess),
+		counter("AgentSendFailure", t.sendFailure),
+		gauge("AgentQueueDepth", float64(t.status.QueueDepth)),
+	}
+	if t.status.LastReport != nil {
+		res = append(res, gauge("AgentLastReport", float64(t.status.LastReport.Unix())))
+	}
+
+	names := make([]string, 0, len(t.status.Collections))
+	for name := range t.status.Collections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		labels := map[string]string{"collector": name}
+		res = append(res,
+			gauge(entity.FormatID("AgentCollectDuration", labels), t.status.Collections[name].Duration),
+			counter(entity.FormatID("AgentCollectErrors", labels), t.collectErrors[name]),
+		)
+		t.collectErrors[name] = 0
+	}
+
+	t.sendSuccess, t.sendFailure = 0, 0
+
+	return res, nil
+}
+
+// NewStatusHandler creates the handler of the local status endpoint of the agent:
+// /healthz responds with 200 after the first metrics is delivered to the server and with 503 before,
+// /status responds with the state of the agent.
+func NewStatusHandler(t *Telemetry) http.Handler {
+	handler := chi.NewRouter()
+
+	handler.Get("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		if !t.Status().Ready {
+			http.Error(w, "not ready", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+
+	handler.Get("/status", func(w http.ResponseWriter, r *http.Request) {
+		resp, err := json.Marshal(t.Status())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(resp)
+	})
+
+	return handler
+}
@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vladislaoramos/alemetric/internal/entity"
+	logger "github.com/vladislaoramos/alemetric/pkg/log"
+)
+
+func TestTelemetry(t *testing.T) {
+	tm := NewTelemetry()
+
+	r := NewRegistry(logger.New("error", io.Discard), CollectTelemetry(tm))
+	r.collect(context.Background(), &fakeCollector{name: "fake", items: []entity.Metrics{gauge("Alloc", 1)}})
+	r.collect(context.Background(), &fakeCollector{name: "broken", err: errors.New("some error")})
+
+	api := &fakeWebAPI{}
+	w := NewWorker(logger.New("error", io.Discard), r, nil, api, 1, ReportTelemetry(tm))
+	w.sendAll()
+
+	status := tm.Status()
+	require.True(t, status.Ready)
+	require.NotNil(t, status.LastReport)
+	require.Equal(t, int64(1), status.Sent)
+	require.Equal(t, 1, status.QueueDepth)
+	require.Equal(t, "some error", status.Collections["broken"].LastError)
+
+	api.err = errors.New("unavailable")
+	r.Push(counter("PollCount", 1))
+	w.sendAll()
+
+	items, err := tm.Collect(context.Background())
+	require.NoError(t, err)
+
+	metrics := byName(items)
+	require.Equal(t, entity.Counter(1), *metrics["AgentSendSuccess"].Delta)
+	require.Equal(t, entity.Counter(2), *metrics["AgentSendFailure"].Delta)
+	require.Equal(t, entity.Gauge(2), *metrics["AgentQueueDepth"].Value)
+	require.Equal(t, entity.Gauge(status.LastReport.Unix()), *metrics["AgentLastReport"].Value)
+	require.Equal(t, entity.Counter(1), *metrics[`AgentCollectErrors{collector="broken"}`].Delta)
+	require.Equal(t, entity.Counter(0), *metrics[`AgentCollectErrors{collector="fake"}`].Delta)
+	require.Contains(t, metrics, `AgentCollectDuration{collector="fake"}`)
+
+	// the counters are increments
+	items, err = tm.Collect(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, entity.Counter(0), *byName(items)["AgentSendFailure"].Delta)
+}
+
+func TestStatusHandler(t *testing.T) {
+	tm := NewTelemetry()
+	handler := NewStatusHandler(tm)
+
+	request := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	tm.ObserveSend(errors.New("unavailable"))
+	require.Equal(t, http.StatusServiceUnavailable, request("/healthz").Code)
+
+	tm.ObserveSend(nil)
+	require.Equal(t, http.StatusOK, request("/healthz").Code)
+
+	resp := request("/status")
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	var status Status
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &status))
+	require.True(t, status.Ready)
+	require.Equal(t, int64(1), status.Sent)
+	require.Equal(t, int64(1), status.Failed)
+}
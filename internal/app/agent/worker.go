@@ -2,154 +2,287 @@ package agent
 
 import (
 	"fmt"
-	"reflect"
-	"strings"
+	"math"
+	"path"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/vladislaoramos/alemetric/internal/usecase"
-
 	logger "github.com/vladislaoramos/alemetric/pkg/log"
 
 	"github.com/vladislaoramos/alemetric/internal/entity"
+	"github.com/vladislaoramos/alemetric/internal/usecase"
 )
 
+// reportBatchSize is the maximum number of the metrics sent by one request.
+const reportBatchSize = 100
+
 // Worker implements a mechanism of asynchronous metrics sending from agent to server.
+// The metrics of a report are sent in batches.
 type Worker struct {
-	webAPI           WebAPIAgent
-	metrics          *Metrics
+	webAPI   WebAPIAgent
+	registry *Registry
+	l        logger.LogInterface
+
+	cfgMu            sync.RWMutex
 	metricsNames     []string
-	l                logger.LogInterface
 	rateLimitCounter uint
+
+	changesOnly bool
+	epsilon     float64
+	heartbeat   uint
+	reports     uint
+
+	sentMu sync.Mutex
+	sent   map[string]entity.Gauge
+
+	telemetry *Telemetry
+}
+
+// WorkerOption configures the worker.
+type WorkerOption func(w *Worker)
+
+// SendChangesOnly sets the sending of the changed metrics only: a gauge is skipped
+// if it differs from the last sent value by no more than epsilon, a counter is skipped if it has not increased.
+// Every heartbeat reports all metrics are sent, so the server can tell unchanged metrics from a dead agent.
+func SendChangesOnly(epsilon float64, heartbeat uint) WorkerOption {
+	return func(w *Worker) {
+		w.changesOnly = true
+		w.epsilon = epsilon
+		w.heartbeat = heartbeat
+	}
 }
 
 // NewWorker creates a worker object.
-// Worker can work asynchronously if the transmitted rate limit is greater than 1.
+// Worker sends only the metrics matching the names from the list; with the empty list it sends all metrics.
+// The list doesn't apply to the metrics marked as unfiltered by the registry, e.g. the pushed ones.
+// A name may be a glob pattern, e.g. CPUutilization*, and matches either the metrics ID
+// or its name without labels, e.g. DiskUsed matches DiskUsed{mount="/"}.
+// The aggregates of a gauge, e.g. HeapAlloc_max, are sent if the gauge is.
+// Worker sends several batches concurrently if the transmitted rate limit is greater than 1.
 func NewWorker(
 	l logger.LogInterface,
-	metrics *Metrics,
+	registry *Registry,
 	metricsNames []string,
 	webAPI WebAPIAgent,
-	limit uint) *Worker {
-	return &Worker{
+	limit uint,
+	opts ...WorkerOption) *Worker {
+	w := &Worker{
 		l:                l,
-		metrics:          metrics,
+		registry:         registry,
 		metricsNames:     metricsNames,
 		webAPI:           webAPI,
 		rateLimitCounter: limit,
+		sent:             make(map[string]entity.Gauge),
 	}
-}
 
-// UpdateMetrics stores the general metrics according to the poll interval of Agent.
-func (w *Worker) UpdateMetrics(ticker *time.Ticker) {
-	for {
-		<-ticker.C
-		w.metrics.CollectMetrics()
-		w.l.Info("Metrics updated")
+	for _, opt := range opts {
+		opt(w)
 	}
-}
 
-// UpdateAdditionalMetrics stores the additional metrics according to the poll interval of Agent.
-func (w *Worker) UpdateAdditionalMetrics(ticker *time.Ticker) {
-	for {
-		<-ticker.C
-		w.metrics.CollectAdditionalMetrics()
-		w.l.Info("Additional metrics updated")
-	}
+	return w
 }
 
 // SendMetrics sends metrics according to the report interval of Agent.
 func (w *Worker) SendMetrics(ticker *time.Ticker) {
 	for {
 		<-ticker.C
+		w.sendAll()
+	}
+}
+
+// SetMetricsNames changes the list of the sent metrics names starting from the next report.
+func (w *Worker) SetMetricsNames(names []string) {
+	w.cfgMu.Lock()
+	defer w.cfgMu.Unlock()
+
+	w.metricsNames = names
+}
+
+// SetRateLimit changes the number of the batches sent concurrently starting from the next report.
+func (w *Worker) SetRateLimit(limit uint) {
+	w.cfgMu.Lock()
+	defer w.cfgMu.Unlock()
 
-		var wg sync.WaitGroup
-		tasks := make(chan entity.Metrics)
-
-		var workersNum int
-		if w.rateLimitCounter > 0 {
-			workersNum = int(w.rateLimitCounter)
-		} else {
-			w.l.Fatal(
-				fmt.Sprintf(
-					"The current number of workers is %d. It must be positive and greater than 0",
-					w.rateLimitCounter))
+	w.rateLimitCounter = limit
+}
+
+func (w *Worker) sendAll() {
+	var wg sync.WaitGroup
+	batches := make(chan []entity.Metrics)
+
+	w.cfgMu.RLock()
+	limit := w.rateLimitCounter
+	w.cfgMu.RUnlock()
+
+	var workersNum int
+	if limit > 0 {
+		workersNum = int(limit)
+	} else {
+		w.l.Fatal(
+			fmt.Sprintf(
+				"The current number of workers is %d. It must be positive and greater than 0",
+				limit))
+	}
+
+	var failed int32
+	for i := 0; i < workersNum; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.worker(batches, &failed)
+		}()
+	}
+
+	var queued int
+	batch := make([]entity.Metrics, 0, reportBatchSize)
+
+	full := w.fullReport()
+	for _, task := range w.registry.Flush() {
+		if !w.selected(task.ID) {
+			continue
 		}
 
-		for i := 0; i < workersNum; i++ {
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
-				w.worker(tasks)
-			}()
+		if !full && !w.changed(task) {
+			continue
 		}
 
-		for _, name := range w.metricsNames {
-			field := reflect.Indirect(reflect.ValueOf(w.metrics)).FieldByName(name)
-			if !field.IsValid() {
-				w.l.Error(fmt.Sprintf("Field `%s` is not valid", name))
-				continue
-			}
+		batch = append(batch, task)
+		queued++
+		w.l.Info(fmt.Sprintf("Metrics %s added to jobs list", task.ID))
 
-			fieldType := strings.ToLower(field.Type().Name())
-
-			var (
-				valCounter *entity.Counter
-				valGauge   *entity.Gauge
-			)
-
-			switch fieldType {
-			case usecase.Counter:
-				val := entity.Counter(field.Int())
-				valCounter = &val
-			case usecase.Gauge:
-				val := entity.Gauge(field.Float())
-				valGauge = &val
-			default:
-				w.l.Error(fmt.Sprintf("Type of the metrics field `%s` is invalid", fieldType))
-				continue
-			}
+		if len(batch) == reportBatchSize {
+			batches <- batch
+			batch = make([]entity.Metrics, 0, reportBatchSize)
+		}
+	}
 
-			task := entity.Metrics{
-				ID:    name,
-				MType: fieldType,
-				Delta: valCounter,
-				Value: valGauge,
-			}
+	if len(batch) != 0 {
+		batches <- batch
+	}
+
+	close(batches)
+	wg.Wait()
+
+	if w.telemetry != nil {
+		w.telemetry.ObserveReport(time.Now(), queued, int(atomic.LoadInt32(&failed)))
+	}
+}
+
+func (w *Worker) selected(id string) bool {
+	if w.registry != nil && w.registry.Unfiltered(id) {
+		return true
+	}
+
+	w.cfgMu.RLock()
+	names := w.metricsNames
+	w.cfgMu.RUnlock()
+
+	return selectedBy(names, id)
+}
+
+func selectedBy(names []string, id string) bool {
+	if len(names) == 0 {
+		return true
+	}
+
+	if base, ok := aggregateOf(id); ok && selectedBy(names, base) {
+		return true
+	}
 
-			tasks <- task
-			w.l.Info(fmt.Sprintf("Metrics %s added to jobs list", name))
+	name, _, err := entity.ParseID(id)
+	if err != nil {
+		name = id
+	}
+
+	for _, pattern := range names {
+		if matchName(pattern, id) || matchName(pattern, name) {
+			return true
 		}
+	}
+	return false
+}
 
-		close(tasks)
-		wg.Wait()
+// fullReport counts the reports and tells if all metrics are sent in the current one.
+func (w *Worker) fullReport() bool {
+	if !w.changesOnly {
+		return true
 	}
+
+	full := w.heartbeat == 0 || w.reports%w.heartbeat == 0
+	w.reports++
+	return full
 }
 
-func (w *Worker) sendMetrics(name, mType string, counter *entity.Counter, gauge *entity.Gauge) {
-	w.l.Info(fmt.Sprintf("Metrics %s is sending", name))
+// changed tells if the metrics has changed since it was sent.
+func (w *Worker) changed(task entity.Metrics) bool {
+	switch {
+	case task.MType == usecase.Counter && task.Delta != nil:
+		return *task.Delta != 0
+	case task.MType == usecase.Gauge && task.Value != nil:
+		w.sentMu.Lock()
+		defer w.sentMu.Unlock()
 
-	var c entity.Counter
-	if counter != nil {
-		c = *counter
+		sent, ok := w.sent[task.ID]
+		return !ok || math.Abs(float64(*task.Value-sent)) > w.epsilon
 	}
+	return true
+}
+
+// ReportTelemetry sets the recording of the sent and failed metrics and of the reports to the telemetry.
+func ReportTelemetry(t *Telemetry) WorkerOption {
+	return func(w *Worker) {
+		w.telemetry = t
+	}
+}
 
-	var g entity.Gauge
-	if gauge != nil {
-		g = *gauge
+func matchName(pattern, name string) bool {
+	if pattern == name {
+		return true
 	}
+	ok, err := path.Match(pattern, name)
+	return err == nil && ok
+}
+
+func (w *Worker) sendMetrics(batch []entity.Metrics) error {
+	w.l.Info(fmt.Sprintf("%d metrics are sending", len(batch)))
 
-	err := w.webAPI.SendMetrics(name, mType, counter, gauge)
+	err := w.webAPI.SendSeveralMetrics(batch)
+	if w.telemetry != nil {
+		for range batch {
+			w.telemetry.ObserveSend(err)
+		}
+	}
 	if err != nil {
-		w.l.Error(
-			fmt.Sprintf(
-				"error sending metrics conflict: %v; metricName: %s metricType: %s delta: %d value: %v",
-				err, name, mType, c, g))
+		w.l.Error(fmt.Sprintf("error sending %d metrics: %v", len(batch), err))
+
+		// the increments of the counters are sent with the next report,
+		// they are selected for it already
+		for _, task := range batch {
+			if task.MType == usecase.Counter {
+				w.registry.PushUnfiltered(task)
+			}
+		}
+		return err
 	}
+
+	if w.changesOnly {
+		w.sentMu.Lock()
+		for _, task := range batch {
+			if task.MType == usecase.Gauge && task.Value != nil {
+				w.sent[task.ID] = *task.Value
+			}
+		}
+		w.sentMu.Unlock()
+	}
+
+	return nil
 }
 
-func (w *Worker) worker(tasks chan entity.Metrics) {
-	for task := range tasks {
-		w.sendMetrics(task.ID, task.MType, task.Delta, task.Value)
+func (w *Worker) worker(batches chan []entity.Metrics, failed *int32) {
+	for batch := range batches {
+		if err := w.sendMetrics(batch); err != nil {
+			atomic.AddInt32(failed, int32(len(batch)))
+		}
 	}
 }
@@ -0,0 +1,135 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vladislaoramos/alemetric/internal/entity"
+	logger "github.com/vladislaoramos/alemetric/pkg/log"
+)
+
+type fakeWebAPI struct {
+	mu      sync.Mutex
+	sent    []string
+	batches int
+	err     error
+}
+
+func (f *fakeWebAPI) SendMetrics(name, _ string, _ *entity.Counter, _ *entity.Gauge) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+	f.sent = append(f.sent, name)
+	return nil
+}
+
+func (f *fakeWebAPI) SendSeveralMetrics(items []entity.Metrics) error {
+	f.mu.Lock()
+	f.batches++
+	f.mu.Unlock()
+
+	for _, m := range items {
+		if err := f.SendMetrics(m.ID, m.MType, m.Delta, m.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestWorker_SendAll(t *testing.T) {
+	t.Run("selected metrics", func(t *testing.T) {
+		r := NewRegistry(logger.New("error", io.Discard))
+		r.Push(gauge("Alloc", 1), gauge("Sys", 2), counter("PollCount", 1))
+
+		api := &fakeWebAPI{}
+		w := NewWorker(logger.New("error", io.Discard), r, []string{"Alloc", "PollCount"}, api, 2)
+		w.sendAll()
+
+		sort.Strings(api.sent)
+		require.Equal(t, []string{"Alloc", "PollCount"}, api.sent)
+	})
+
+	t.Run("metrics are sent in batches", func(t *testing.T) {
+		r := NewRegistry(logger.New("error", io.Discard))
+		for i := 0; i < reportBatchSize+1; i++ {
+			r.Push(gauge(fmt.Sprintf("Gauge%d", i), 1))
+		}
+
+		api := &fakeWebAPI{}
+		w := NewWorker(logger.New("error", io.Discard), r, nil, api, 2)
+		w.sendAll()
+
+		require.Len(t, api.sent, reportBatchSize+1)
+		require.Equal(t, 2, api.batches)
+	})
+
+	t.Run("counters are kept on error", func(t *testing.T) {
+		r := NewRegistry(logger.New("error", io.Discard))
+		r.Push(counter("PollCount", 3))
+
+		api := &fakeWebAPI{err: errors.New("some error")}
+		w := NewWorker(logger.New("error", io.Discard), r, nil, api, 1)
+		w.sendAll()
+
+		r.Push(counter("PollCount", 1))
+		items := r.Flush()
+		require.Len(t, items, 1)
+		require.Equal(t, entity.Counter(4), *items[0].Delta)
+	})
+}
+
+func TestWorker_Selected(t *testing.T) {
+	w := NewWorker(logger.New("error", io.Discard), nil,
+		[]string{"CPUutilization*", "DiskUsed", `NetBytesSent{interface="eth0"}`}, nil, 1)
+
+	for _, id := range []string{
+		"CPUutilization1",
+		"CPUutilization12",
+		"CPUutilization1_max",
+		`DiskUsed{mount="/"}`,
+		`DiskUsed_avg{mount="/"}`,
+		`NetBytesSent{interface="eth0"}`,
+	} {
+		require.True(t, w.selected(id), id)
+	}
+
+	for _, id := range []string{
+		"Alloc",
+		"Alloc_max",
+		`DiskFree{mount="/"}`,
+		`NetBytesSent{interface="lo"}`,
+	} {
+		require.False(t, w.selected(id), id)
+	}
+}
+
+func TestWorker_SendChangesOnly(t *testing.T) {
+	r := NewRegistry(logger.New("error", io.Discard))
+	api := &fakeWebAPI{}
+	w := NewWorker(logger.New("error", io.Discard), r, nil, api, 1, SendChangesOnly(0.5, 3))
+
+	report := func(items ...entity.Metrics) []string {
+		api.sent = nil
+		r.Push(items...)
+		w.sendAll()
+		sort.Strings(api.sent)
+		return api.sent
+	}
+
+	require.Equal(t, []string{"Alloc", "PollCount", "Sys"},
+		report(gauge("Alloc", 1), gauge("Sys", 2), counter("PollCount", 1)))
+
+	// unchanged gauges and counters without increments are skipped
+	require.Equal(t, []string{"Alloc"}, report(gauge("Alloc", 2), gauge("Sys", 2.4), counter("PollCount", 0)))
+	require.Equal(t, []string{"PollCount", "Sys"}, report(gauge("Sys", 2.6), counter("PollCount", 2)))
+
+	// heartbeat
+	require.Equal(t, []string{"Alloc", "PollCount", "Sys"}, report(counter("PollCount", 0)))
+}
@@ -6,6 +6,9 @@ import (
 	"fmt"
 	"github.com/go-chi/chi/v5"
 	"github.com/vladislaoramos/alemetric/configs"
+	"github.com/vladislaoramos/alemetric/internal/agentconfig"
+	"github.com/vladislaoramos/alemetric/internal/alert"
+	"github.com/vladislaoramos/alemetric/internal/entity"
 	"github.com/vladislaoramos/alemetric/internal/repo"
 	"github.com/vladislaoramos/alemetric/internal/usecase"
 	logger "github.com/vladislaoramos/alemetric/pkg/log"
@@ -14,8 +17,11 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 )
 
+const alertEvalInterval = 10 * time.Second
+
 // Run method launches the server application.
 func Run(cfg *configs.Config, lgr *logger.Logger) {
 	repoOpts := make([]repo.OptionFunc, 0)
@@ -36,8 +42,49 @@ func Run(cfg *configs.Config, lgr *logger.Logger) {
 		mtOptions = append(mtOptions, usecase.CheckDataSign(cfg.Server.Key))
 	}
 
+	ttls := make(map[string]time.Duration)
+	if cfg.Server.GaugeTTL != 0 {
+		ttls[usecase.Gauge] = cfg.Server.GaugeTTL
+	}
+	if cfg.Server.CounterTTL != 0 {
+		ttls[usecase.Counter] = cfg.Server.CounterTTL
+	}
+	if len(ttls) != 0 {
+		mtOptions = append(mtOptions, usecase.EvictStaleMetrics(cfg.Server.SweepInterval, ttls))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var notifier *alert.WebhookNotifier
+	if cfg.Server.AlertRules != "" {
+		rules, err := alert.LoadRules(cfg.Server.AlertRules)
+		if err != nil {
+			lgr.Fatal(fmt.Sprintf("Server - Alerting Rules - Error: %s", err.Error()))
+		}
+
+		engine := alert.NewEngine(rules)
+		if len(cfg.Server.AlertWebhooks) != 0 {
+			notifier = alert.NewWebhookNotifier(cfg.Server.AlertWebhooks, cfg.Server.Name, cfg.Server.Key, lgr)
+			go notifier.Run(ctx)
+			engine.Notify(notifier)
+		}
+		go engine.Run(ctx, alertEvalInterval)
+		mtOptions = append(mtOptions, usecase.Alerting(engine))
+	}
+
+	if cfg.Server.AgentConfigs != "" {
+		src, err := agentconfig.Load(cfg.Server.AgentConfigs, lgr)
+		if err != nil {
+			lgr.Fatal(fmt.Sprintf("Server - Agent Configs - Error: %s", err.Error()))
+		}
+		mtOptions = append(mtOptions, usecase.ServeAgentConfigs(src))
+	}
+
 	var (
 		curRepo usecase.MetricsRepo
+		history usecase.HistoryRepo
+		agents  usecase.AgentRepo
 		db      *postgres.DB
 		err     error
 	)
@@ -53,22 +100,62 @@ func Run(cfg *configs.Config, lgr *logger.Logger) {
 		}
 		defer db.Close()
 
-		curRepo, err = repo.NewPostgresRepo(db)
+		pgRepo, err := repo.NewPostgresRepo(db)
 		if err != nil {
 			lgr.Fatal(err.Error())
 		}
+		curRepo, history, agents = pgRepo, pgRepo, pgRepo
 	} else {
-		curRepo, err = repo.NewMetricsRepo(repoOpts...)
+		memRepo, err := repo.NewMetricsRepo(repoOpts...)
 		if err != nil {
 			lgr.Fatal(err.Error())
 		}
+		curRepo, history, agents = memRepo, memRepo, memRepo
 	}
 
+	if cfg.Server.RollupInterval > 0 {
+		mtOptions = append(mtOptions, usecase.History(history, cfg.Server.RollupInterval, map[string]time.Duration{
+			entity.ResolutionRaw:    cfg.Server.RawRetention,
+			entity.ResolutionMinute: cfg.Server.MinuteRetention,
+			entity.ResolutionHour:   cfg.Server.HourRetention,
+			entity.ResolutionDay:    cfg.Server.DayRetention,
+		}))
+	}
+
+	mtOptions = append(mtOptions, usecase.AgentInventory(agents, cfg.Server.AgentAbsentAfter))
+
 	handler := chi.NewRouter()
 
 	mt := usecase.NewMetricsTool(curRepo, lgr, mtOptions...)
 	NewRouter(handler, mt, lgr, cfg.Server.CryptoKey)
 
+	// setKey changes the key in every component using it: the data signs and the webhook signs
+	setKey := func(key string) {
+		mt.SetDataSignKey(key)
+		if notifier != nil {
+			notifier.SetKey(key)
+		}
+	}
+
+	// the changes of the logger level, the key and the store interval are applied;
+	// the other changes require the restart. The server has no trusted subnet to reload.
+	storeInterval := cfg.Server.StoreInterval
+	go cfg.ReloadOnChange(ctx, lgr, func(next *configs.Config) error {
+		if next.Server.StoreInterval != storeInterval {
+			if (next.Server.StoreInterval == 0) != (storeInterval == 0) {
+				return fmt.Errorf("store interval %s: %w", next.Server.StoreInterval, usecase.ErrStoreModeChanged)
+			}
+			if err := mt.SetStoreInterval(next.Server.StoreInterval); err != nil {
+				return fmt.Errorf("store interval %s: %w", next.Server.StoreInterval, err)
+			}
+			storeInterval = next.Server.StoreInterval
+		}
+
+		setKey(next.Server.Key)
+		lgr.SetLevel(next.Level)
+		return nil
+	})
+
 	var (
 		srv             = http.Server{Addr: cfg.Address, Handler: handler}
 		idleConnsClosed = make(chan struct{})
@@ -0,0 +1,84 @@
+package server
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"time"
+
+	"github.com/vladislaoramos/alemetric/internal/entity"
+)
+
+const (
+	dashboardTitle   = "Alemetric"
+	dashboardRefresh = 10 // seconds
+	dashboardTime    = "2006-01-02 15:04:05 MST"
+)
+
+//go:embed templates/dashboard.html
+var dashboardHTML string
+
+var dashboardTemplate = template.Must(template.New("dashboard").Parse(dashboardHTML))
+
+// dashboard is the data of the HTML page with all metrics.
+type dashboard struct {
+	Title     string
+	Refresh   int
+	Generated string
+	Groups    []dashboardGroup
+}
+
+// dashboardGroup contains the metrics of the same type.
+type dashboardGroup struct {
+	Type string
+	Rows []dashboardRow
+}
+
+type dashboardRow struct {
+	Name    string
+	Value   string
+	Updated string
+}
+
+// newDashboard groups the metrics by type; the metrics must be sorted by type.
+func newDashboard(items []entity.Metrics, refresh int) dashboard {
+	d := dashboard{
+		Title:     dashboardTitle,
+		Refresh:   refresh,
+		Generated: time.Now().Format(dashboardTime),
+		Groups:    make([]dashboardGroup, 0),
+	}
+
+	for _, m := range items {
+		if len(d.Groups) == 0 || d.Groups[len(d.Groups)-1].Type != m.MType {
+			d.Groups = append(d.Groups, dashboardGroup{Type: m.MType})
+		}
+
+		group := &d.Groups[len(d.Groups)-1]
+		group.Rows = append(group.Rows, dashboardRow{
+			Name:    m.ID,
+			Value:   formatValue(m),
+			Updated: formatUpdated(m),
+		})
+	}
+
+	return d
+}
+
+func formatValue(m entity.Metrics) string {
+	switch {
+	case m.Value != nil:
+		return fmt.Sprintf("%g", *m.Value)
+	case m.Delta != nil:
+		return fmt.Sprintf("%d", *m.Delta)
+	default:
+		return "-"
+	}
+}
+
+func formatUpdated(m entity.Metrics) string {
+	if m.Updated == nil {
+		return "-"
+	}
+	return m.Updated.Format(dashboardTime)
+}
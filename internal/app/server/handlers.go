@@ -1,10 +1,16 @@
 package server
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"path"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/vladislaoramos/alemetric/internal/entity"
@@ -12,18 +18,36 @@ import (
 	logger "github.com/vladislaoramos/alemetric/pkg/log"
 )
 
-// getMetricsHandler handles a request to get all metrics names.
+// getMetricsHandler handles a request to get the HTML page with all metrics grouped by type.
+// The page refreshes itself every "refresh" seconds from the query parameters.
 func getMetricsHandler(tool *usecase.ToolUseCase, l logger.LogInterface) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		names, err := tool.GetMetricsNames(r.Context())
+		refresh := dashboardRefresh
+		if value := r.URL.Query().Get("refresh"); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				http.Error(w, "refresh must be a positive number of seconds", http.StatusBadRequest)
+				return
+			}
+			refresh = n
+		}
+
+		items, err := tool.ListMetrics(r.Context(), entity.Filter{SortBy: entity.SortByType})
 		if err != nil {
 			l.Error(fmt.Sprintf("Handlers - GetMetrics - Error: %s", err.Error()))
 			errorHandler(w, err)
 			return
 		}
 
-		w.Header().Set("Content-Type", "text/html")
-		w.Write([]byte(strings.Join(names, "\n")))
+		var buf bytes.Buffer
+		if err = dashboardTemplate.Execute(&buf, newDashboard(items, refresh)); err != nil {
+			l.Error(fmt.Sprintf("Handlers - GetMetrics - Template Error: %s", err.Error()))
+			errorHandler(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(buf.Bytes())
 	}
 }
 
@@ -36,12 +60,10 @@ func updateSeveralMetricsHandler(tool *usecase.ToolUseCase, l logger.LogInterfac
 			return
 		}
 
-		for _, item := range items {
-			if err := tool.StoreMetrics(r.Context(), item); err != nil {
-				l.Error(fmt.Errorf("error with updating metrics: %w", err).Error())
-				errorHandler(w, err)
-				return
-			}
+		if err := tool.StoreSeveralMetrics(r.Context(), items); err != nil {
+			l.Error(fmt.Errorf("error with updating metrics: %w", err).Error())
+			errorHandler(w, err)
+			return
 		}
 
 		w.WriteHeader(http.StatusOK)
@@ -182,12 +204,89 @@ func getSomeMetricsHandler(tool *usecase.ToolUseCase, l logger.LogInterface) htt
 	}
 }
 
+// valuesItem is an item of the response to a request for several metrics.
+type valuesItem struct {
+	entity.Metrics
+	NotFound bool `json:"not_found,omitempty"`
+}
+
+// getSeveralMetricsHandler handles a request to get several metrics at once.
+// The response keeps the order of the request; missing metrics are marked as not found.
+func getSeveralMetricsHandler(tool *usecase.ToolUseCase, l logger.LogInterface) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var items []entity.Metrics
+		if err := json.NewDecoder(r.Body).Decode(&items); err != nil {
+			http.Error(w, "error decoding several metrics during get: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		found, err := tool.GetSeveralMetrics(r.Context(), items)
+		if err != nil {
+			l.Error(fmt.Sprintf("Handlers - GetSeveralMetrics - Error: %s", err.Error()))
+			errorHandler(w, err)
+			return
+		}
+
+		values := make(map[string]entity.Metrics, len(found))
+		for _, m := range found {
+			values[m.ID] = m
+		}
+
+		res := make([]valuesItem, 0, len(items))
+		for _, item := range items {
+			value, ok := values[entity.NormalizeID(item.ID)]
+			if !ok || item.MType != "" && item.MType != value.MType {
+				res = append(res, valuesItem{
+					Metrics:  entity.Metrics{ID: item.ID, MType: item.MType},
+					NotFound: true,
+				})
+				continue
+			}
+			res = append(res, valuesItem{Metrics: value})
+		}
+
+		resp, err := json.Marshal(res)
+		if err != nil {
+			l.Error(err.Error())
+			errorHandler(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(resp)
+	}
+}
+
 // getSpecificMetricsHandler handles a request to get one specific metrics.
 func getSpecificMetricsHandler(tool *usecase.ToolUseCase, l logger.LogInterface) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		metricsType := chi.URLParam(r, "metricsType")
 		metricsName := chi.URLParam(r, "metricsName")
 
+		fn, window, err := parseCounterFunc(r.URL.Query())
+		if err != nil {
+			http.Error(w, "error parsing function: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if fn != "" {
+			if metricsType != Counter {
+				http.Error(w, "functions are computed only for counters", http.StatusBadRequest)
+				return
+			}
+
+			value, err := tool.CounterFunc(r.Context(), metricsName, fn, window)
+			if err != nil {
+				l.Error(err.Error())
+				errorHandler(w, err)
+				return
+			}
+
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte(fmt.Sprintf("%g", value)))
+			return
+		}
+
 		metrics := entity.Metrics{
 			ID:    metricsName,
 			MType: metricsType,
@@ -217,6 +316,429 @@ func getSpecificMetricsHandler(tool *usecase.ToolUseCase, l logger.LogInterface)
 	}
 }
 
+// listItem is a metrics in the list with the function of a counter if it is requested.
+type listItem struct {
+	entity.Metrics
+	Rate     *float64 `json:"rate,omitempty"`
+	Increase *float64 `json:"increase,omitempty"`
+}
+
+// listMetricsHandler handles a request to get the metrics satisfying the filter from the query parameters.
+// Parameters: type, prefix, glob, selector (name{label="value",...}),
+// sort (name, type or updated, "-" prefix for descending order), limit, offset.
+// With fn (rate or increase) and window the function is computed for every counter.
+func listMetricsHandler(tool *usecase.ToolUseCase, l logger.LogInterface) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, err := parseFilter(r.URL.Query())
+		if err != nil {
+			http.Error(w, "error parsing filter: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		fn, window, err := parseCounterFunc(r.URL.Query())
+		if err != nil {
+			http.Error(w, "error parsing function: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		items, err := tool.ListMetrics(r.Context(), filter)
+		if err != nil {
+			l.Error(fmt.Sprintf("Handlers - ListMetrics - Error: %s", err.Error()))
+			errorHandler(w, err)
+			return
+		}
+
+		var values map[string]float64
+		if fn != "" {
+			counters := make([]string, 0, len(items))
+			for _, m := range items {
+				if m.MType == Counter {
+					counters = append(counters, m.ID)
+				}
+			}
+
+			values, err = tool.CounterFuncs(r.Context(), counters, fn, window)
+			if err != nil {
+				l.Error(fmt.Sprintf("Handlers - ListMetrics - CounterFuncs - Error: %s", err.Error()))
+				errorHandler(w, err)
+				return
+			}
+		}
+
+		res := make([]listItem, 0, len(items))
+		for _, m := range items {
+			item := listItem{Metrics: m}
+			if value, ok := values[m.ID]; ok {
+				if fn == usecase.FnRate {
+					item.Rate = &value
+				} else {
+					item.Increase = &value
+				}
+			}
+			res = append(res, item)
+		}
+
+		resp, err := json.Marshal(res)
+		if err != nil {
+			l.Error(err.Error())
+			errorHandler(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(resp)
+	}
+}
+
+// queryMetricsHandler computes the aggregates of the values of the metrics of one type satisfying the filter,
+// e.g. /api/v1/query?type=gauge&selector=HeapAlloc computes them across HeapAlloc with any labels.
+// The type is required, so that the values of gauges and counters are not aggregated together.
+func queryMetricsHandler(tool *usecase.ToolUseCase, l logger.LogInterface) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, err := parseFilter(r.URL.Query())
+		if err != nil {
+			http.Error(w, "error parsing filter: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if filter.Type == "" {
+			http.Error(w, "empty metrics type", http.StatusBadRequest)
+			return
+		}
+
+		res, err := tool.AggregateMetrics(r.Context(), filter)
+		if err != nil {
+			l.Error(fmt.Sprintf("Handlers - AggregateMetrics - Error: %s", err.Error()))
+			errorHandler(w, err)
+			return
+		}
+
+		resp, err := json.Marshal(res)
+		if err != nil {
+			l.Error(err.Error())
+			errorHandler(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(resp)
+	}
+}
+
+// getHistoryHandler gets the history of the metrics,
+// e.g. /api/v1/history?name=HeapAlloc&from=2023-04-01T00:00:00Z&to=2023-04-02T00:00:00Z&points=100.
+// The range is the last hour by default.
+func getHistoryHandler(tool *usecase.ToolUseCase, l logger.LogInterface) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		name := query.Get("name")
+		if name == "" {
+			http.Error(w, "empty metrics name", http.StatusBadRequest)
+			return
+		}
+
+		from, to, err := parseRange(query)
+		if err != nil {
+			http.Error(w, "error parsing range: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var step time.Duration
+		if value := query.Get("step"); value != "" {
+			if step, err = time.ParseDuration(value); err != nil || step < 0 {
+				http.Error(w, fmt.Sprintf("invalid step %q", value), http.StatusBadRequest)
+				return
+			}
+		}
+
+		points, err := parseNonNegative(query.Get("points"))
+		if err != nil {
+			http.Error(w, "invalid points: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		res, err := tool.GetHistory(r.Context(), name, from, to, step, points)
+		if err != nil {
+			l.Error(fmt.Sprintf("Handlers - GetHistory - Error: %s", err.Error()))
+			errorHandler(w, err)
+			return
+		}
+
+		resp, err := json.Marshal(res)
+		if err != nil {
+			l.Error(err.Error())
+			errorHandler(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(resp)
+	}
+}
+
+// parseCounterFunc parses the function of counters and its window, one minute by default.
+// Without the function it returns an empty one.
+func parseCounterFunc(query url.Values) (string, time.Duration, error) {
+	fn := query.Get("fn")
+	switch fn {
+	case "":
+		return "", 0, nil
+	case usecase.FnRate, usecase.FnIncrease:
+	default:
+		return "", 0, fmt.Errorf("unknown function %q", fn)
+	}
+
+	window := defaultCounterWindow
+	if value := query.Get("window"); value != "" {
+		var err error
+		if window, err = time.ParseDuration(value); err != nil || window <= 0 {
+			return "", 0, fmt.Errorf("invalid window %q", value)
+		}
+	}
+
+	return fn, window, nil
+}
+
+func parseRange(query url.Values) (time.Time, time.Time, error) {
+	to := time.Now()
+	if value := query.Get("to"); value != "" {
+		var err error
+		if to, err = time.Parse(time.RFC3339, value); err != nil {
+			return to, to, fmt.Errorf("invalid end %q: %w", value, err)
+		}
+	}
+
+	from := to.Add(-defaultHistoryRange)
+	if value := query.Get("from"); value != "" {
+		var err error
+		if from, err = time.Parse(time.RFC3339, value); err != nil {
+			return from, to, fmt.Errorf("invalid start %q: %w", value, err)
+		}
+	}
+
+	if !from.Before(to) {
+		return from, to, fmt.Errorf("start %s is not before end %s", from.Format(time.RFC3339), to.Format(time.RFC3339))
+	}
+
+	return from, to, nil
+}
+
+func parseFilter(query url.Values) (entity.Filter, error) {
+	filter := entity.Filter{
+		Type:   query.Get("type"),
+		Prefix: query.Get("prefix"),
+		Glob:   query.Get("glob"),
+	}
+
+	if filter.Type != "" && filter.Type != Gauge && filter.Type != Counter {
+		return filter, fmt.Errorf("unknown metrics type %q", filter.Type)
+	}
+
+	if _, err := path.Match(filter.Glob, ""); err != nil {
+		return filter, fmt.Errorf("invalid glob %q: %w", filter.Glob, err)
+	}
+
+	if selector := query.Get("selector"); selector != "" {
+		var err error
+		filter.Name, filter.Labels, err = entity.ParseID(selector)
+		if err != nil {
+			return filter, fmt.Errorf("invalid selector %q: %w", selector, err)
+		}
+	}
+
+	if sortBy := query.Get("sort"); sortBy != "" {
+		filter.Desc = strings.HasPrefix(sortBy, "-")
+		filter.SortBy = strings.TrimPrefix(sortBy, "-")
+		switch filter.SortBy {
+		case entity.SortByName, entity.SortByType, entity.SortByUpdated:
+		default:
+			return filter, fmt.Errorf("unknown sort key %q", filter.SortBy)
+		}
+	}
+
+	var err error
+	if filter.Limit, err = parseNonNegative(query.Get("limit")); err != nil {
+		return filter, fmt.Errorf("invalid limit: %w", err)
+	}
+
+	if filter.Offset, err = parseNonNegative(query.Get("offset")); err != nil {
+		return filter, fmt.Errorf("invalid offset: %w", err)
+	}
+
+	return filter, nil
+}
+
+func parseNonNegative(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+
+	if n < 0 {
+		return 0, errors.New("must not be negative")
+	}
+
+	return n, nil
+}
+
+// getAlertsHandler handles a request to get the states of the alerting rules.
+func getAlertsHandler(tool *usecase.ToolUseCase, l logger.LogInterface) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp, err := json.Marshal(tool.GetAlerts())
+		if err != nil {
+			l.Error(err.Error())
+			errorHandler(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(resp)
+	}
+}
+
+// getAgentsHandler handles a request to get the heartbeats of the agents with their statuses.
+func getAgentsHandler(tool *usecase.ToolUseCase, l logger.LogInterface) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		agents, err := tool.GetAgents(r.Context())
+		if err != nil {
+			l.Error(fmt.Sprintf("Handlers - GetAgents - Error: %s", err.Error()))
+			errorHandler(w, err)
+			return
+		}
+
+		resp, err := json.Marshal(agents)
+		if err != nil {
+			l.Error(err.Error())
+			errorHandler(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(resp)
+	}
+}
+
+// getAgentConfigHandler handles a request of an agent to get its configuration.
+// The agent is identified by the name and group query parameters, by default by the agent headers,
+// e.g. /api/v1/agents/config?name=web-1&group=web.
+func getAgentConfigHandler(tool *usecase.ToolUseCase, l logger.LogInterface) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("name")
+		if name == "" {
+			name = r.Header.Get(agentHeader)
+		}
+
+		group := r.URL.Query().Get("group")
+		if group == "" {
+			group = r.Header.Get(agentGroupHeader)
+		}
+
+		cfg, err := tool.GetAgentConfig(name, group)
+		if err != nil {
+			l.Error(fmt.Sprintf("Handlers - GetAgentConfig - Error: %s", err.Error()))
+			errorHandler(w, err)
+			return
+		}
+
+		resp, err := json.Marshal(cfg)
+		if err != nil {
+			l.Error(err.Error())
+			errorHandler(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(resp)
+	}
+}
+
+// deleteMetricsHandler handles a request to delete one specific metrics.
+// If the server has a signing key, the request must be signed via the Hash header
+// at the time from the X-Signed-At header.
+func deleteMetricsHandler(tool *usecase.ToolUseCase, l logger.LogInterface) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		metrics := entity.Metrics{
+			ID:    chi.URLParam(r, "metricsName"),
+			MType: chi.URLParam(r, "metricsType"),
+			Hash:  r.Header.Get(hashHeader),
+		}
+
+		if err := tool.DeleteMetrics(r.Context(), metrics, signedAt(r)); err != nil {
+			l.Error(fmt.Sprintf("Handlers - DeleteMetrics - Error: %s", err.Error()))
+			errorHandler(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// resetMetricsHandler handles a request to reset the value of one specific metrics to zero.
+// If the server has a signing key, the request must be signed via the Hash header
+// at the time from the X-Signed-At header.
+func resetMetricsHandler(tool *usecase.ToolUseCase, l logger.LogInterface) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		metrics := entity.Metrics{
+			ID:    chi.URLParam(r, "metricsName"),
+			MType: chi.URLParam(r, "metricsType"),
+			Hash:  r.Header.Get(hashHeader),
+		}
+
+		res, err := tool.ResetMetrics(r.Context(), metrics, signedAt(r))
+		if err != nil {
+			l.Error(fmt.Sprintf("Handlers - ResetMetrics - Error: %s", err.Error()))
+			errorHandler(w, err)
+			return
+		}
+
+		resp, err := json.Marshal(res)
+		if err != nil {
+			l.Error(err.Error())
+			errorHandler(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(resp)
+	}
+}
+
+// signedAt returns the time the request is signed at, or the zero time if it is not set.
+func signedAt(r *http.Request) time.Time {
+	sec, err := strconv.ParseInt(r.Header.Get(signedAtHeader), 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0)
+}
+
+// getStaleMetricsHandler handles a request to get the metrics that will be evicted by the next sweep.
+func getStaleMetricsHandler(tool *usecase.ToolUseCase, l logger.LogInterface) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		items, err := tool.GetStaleMetrics(r.Context())
+		if err != nil {
+			l.Error(fmt.Sprintf("Handlers - GetStaleMetrics - Error: %s", err.Error()))
+			errorHandler(w, err)
+			return
+		}
+
+		resp, err := json.Marshal(items)
+		if err != nil {
+			l.Error(err.Error())
+			errorHandler(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(resp)
+	}
+}
+
 // pingHandler handles a request to ping the server.
 func pingHandler(tool *usecase.ToolUseCase, l logger.LogInterface) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -1,19 +1,25 @@
 package server
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/vladislaoramos/alemetric/internal/agentconfig"
+	"github.com/vladislaoramos/alemetric/internal/alert"
 	"github.com/vladislaoramos/alemetric/internal/entity"
 	"github.com/vladislaoramos/alemetric/internal/repo"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/vladislaoramos/alemetric/internal/usecase"
@@ -25,9 +31,8 @@ type TestServer struct {
 	Server *httptest.Server
 }
 
-func NewTestServer(metricsStorage *repo.MetricsRepo, lgr *logger.Logger) TestServer {
+func NewTestServer(metricsStorage *repo.MetricsRepo, lgr *logger.Logger, mtOptions ...usecase.OptionFunc) TestServer {
 	handler := chi.NewRouter()
-	mtOptions := make([]usecase.OptionFunc, 0)
 	mt := usecase.NewMetricsTool(metricsStorage, lgr, mtOptions...)
 	NewRouter(handler, mt, lgr, "")
 	ts := httptest.NewServer(handler)
@@ -101,8 +106,9 @@ func TestGetMetricsHandler(t *testing.T) {
 		assert.Equal(t, http.StatusOK, statusCode)
 	}
 
-	statusCode, _ := ts.testRequest(t, "GET", "/", nil)
+	statusCode, body := ts.testRequest(t, "GET", "/", nil)
 	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Contains(t, string(body), "<td>HeapInuse</td><td class=\"value\">786432.01</td>")
 
 	for _, item := range []string{
 		"HeapInuse",
@@ -285,3 +291,541 @@ func TestPingHandler(t *testing.T) {
 	statusCode, _ := ts.testRequest(t, "GET", "/ping", nil)
 	assert.Equal(t, http.StatusOK, statusCode)
 }
+
+func TestGetStaleMetricsHandler(t *testing.T) {
+	memStorage, err := repo.NewMetricsRepo()
+	assert.NoError(t, err)
+
+	tl := testLogger()
+	ttls := map[string]time.Duration{Gauge: time.Millisecond}
+	ts := NewTestServer(memStorage, tl, usecase.EvictStaleMetrics(0, ttls))
+
+	statusCode, _ := ts.testRequest(t, "POST", "/update/gauge/HeapInuse/786432.01", nil)
+	assert.Equal(t, http.StatusOK, statusCode)
+
+	statusCode, _ = ts.testRequest(t, "POST", "/update/counter/PollCount/1", nil)
+	assert.Equal(t, http.StatusOK, statusCode)
+
+	time.Sleep(5 * time.Millisecond)
+
+	statusCode, body := ts.testRequest(t, "GET", "/admin/stale", nil)
+	assert.Equal(t, http.StatusOK, statusCode)
+
+	var stale []entity.Metrics
+	err = json.Unmarshal(body, &stale)
+	require.NoError(t, err)
+	require.Len(t, stale, 1)
+	assert.Equal(t, "HeapInuse", stale[0].ID)
+	assert.NotNil(t, stale[0].Updated)
+}
+
+func TestDeleteMetricsHandler(t *testing.T) {
+	memStorage, err := repo.NewMetricsRepo()
+	assert.NoError(t, err)
+
+	tl := testLogger()
+	ts := NewTestServer(memStorage, tl)
+
+	statusCode, _ := ts.testRequest(t, "POST", "/update/gauge/HeapInuse/786432.01", nil)
+	assert.Equal(t, http.StatusOK, statusCode)
+
+	statusCode, _ = ts.testRequest(t, "DELETE", "/value/counter/HeapInuse", nil)
+	assert.Equal(t, http.StatusNotFound, statusCode)
+
+	statusCode, _ = ts.testRequest(t, "DELETE", "/value/gauge/HeapInuse", nil)
+	assert.Equal(t, http.StatusOK, statusCode)
+
+	statusCode, _ = ts.testRequest(t, "GET", "/value/gauge/HeapInuse", nil)
+	assert.Equal(t, http.StatusNotFound, statusCode)
+}
+
+func TestResetMetricsHandler(t *testing.T) {
+	memStorage, err := repo.NewMetricsRepo()
+	assert.NoError(t, err)
+
+	tl := testLogger()
+	key := "key"
+	ts := NewTestServer(memStorage, tl, usecase.CheckDataSign(key))
+
+	var delta entity.Counter = 5
+	metrics := entity.Metrics{ID: "PollCount", MType: Counter, Delta: &delta}
+	metrics.SignData("test", key)
+	b, err := json.Marshal(metrics)
+	require.NoError(t, err)
+
+	statusCode, _ := ts.testRequest(t, "POST", "/update/", strings.NewReader(string(b)))
+	assert.Equal(t, http.StatusOK, statusCode)
+
+	statusCode, _ = ts.testRequest(t, "POST", "/reset/counter/PollCount", nil)
+	assert.Equal(t, http.StatusBadRequest, statusCode)
+
+	reset := func(signedAt time.Time) int {
+		action := entity.Metrics{ID: "PollCount", MType: Counter}
+		action.SignAction(usecase.ActionReset, key, signedAt)
+
+		req, err := http.NewRequest("POST", ts.Server.URL+"/reset/counter/PollCount", nil)
+		require.NoError(t, err)
+		req.Header.Set(hashHeader, action.Hash)
+		req.Header.Set(signedAtHeader, strconv.FormatInt(signedAt.Unix(), 10))
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	now := time.Now()
+	assert.Equal(t, http.StatusOK, reset(now))
+
+	// the signed request cannot be replayed
+	assert.Equal(t, http.StatusBadRequest, reset(now))
+	assert.Equal(t, http.StatusBadRequest, reset(now.Add(-2*usecase.ActionSignWindow)))
+
+	statusCode, body := ts.testRequest(t, "GET", "/value/counter/PollCount", nil)
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Equal(t, "0", string(body))
+}
+
+func TestListMetricsHandler(t *testing.T) {
+	memStorage, err := repo.NewMetricsRepo()
+	assert.NoError(t, err)
+
+	tl := testLogger()
+	ts := NewTestServer(memStorage, tl)
+	for _, request := range []string{
+		"/update/gauge/HeapInuse/786432.01",
+		"/update/gauge/HeapObjects/613",
+		"/update/gauge/NextGC/4194304",
+		"/update/counter/PollCount/5",
+	} {
+		statusCode, _ := ts.testRequest(t, "POST", request, nil)
+		assert.Equal(t, http.StatusOK, statusCode)
+	}
+
+	statusCode, body := ts.testRequest(t, "GET", "/api/v1/metrics?type=gauge&prefix=Heap&sort=-name&limit=1", nil)
+	assert.Equal(t, http.StatusOK, statusCode)
+
+	var items []entity.Metrics
+	err = json.Unmarshal(body, &items)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "HeapObjects", items[0].ID)
+	assert.Equal(t, entity.Gauge(613), *items[0].Value)
+
+	statusCode, body = ts.testRequest(t, "GET", "/api/v1/metrics?glob=Poll*", nil)
+	assert.Equal(t, http.StatusOK, statusCode)
+
+	err = json.Unmarshal(body, &items)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, entity.Counter(5), *items[0].Delta)
+
+	for _, query := range []string{"type=range", "glob=[", "sort=value", "limit=-1", "offset=x"} {
+		statusCode, _ = ts.testRequest(t, "GET", "/api/v1/metrics?"+query, nil)
+		assert.Equal(t, http.StatusBadRequest, statusCode, query)
+	}
+}
+
+func TestQueryMetricsHandler(t *testing.T) {
+	memStorage, err := repo.NewMetricsRepo()
+	assert.NoError(t, err)
+
+	tl := testLogger()
+	ts := NewTestServer(memStorage, tl)
+	for _, body := range []string{
+		`{"id":"HeapAlloc{agent=\"a1\"}","type":"gauge","value":100}`,
+		`{"id":"HeapAlloc{agent=\"a2\"}","type":"gauge","value":300}`,
+		`{"id":"HeapAlloc{agent=\"a3\",host=\"h1\"}","type":"gauge","value":200}`,
+		`{"id":"HeapInuse{agent=a1}","type":"gauge","value":50}`,
+		`{"id":"PollCount{agent=\"a1\"}","type":"counter","delta":5}`,
+	} {
+		statusCode, _ := ts.testRequest(t, "POST", "/update/", strings.NewReader(body))
+		assert.Equal(t, http.StatusOK, statusCode, body)
+	}
+
+	query := func(q string) entity.Aggregate {
+		statusCode, body := ts.testRequest(t, "GET", "/api/v1/query?"+q, nil)
+		require.Equal(t, http.StatusOK, statusCode, q)
+
+		var res entity.Aggregate
+		require.NoError(t, json.Unmarshal(body, &res))
+		return res
+	}
+
+	res := query("selector=HeapAlloc&type=gauge")
+	assert.Equal(t, int64(3), res.Count)
+	assert.Equal(t, 600.0, *res.Sum)
+	assert.Equal(t, 200.0, *res.Avg)
+	assert.Equal(t, 100.0, *res.Min)
+	assert.Equal(t, 300.0, *res.Max)
+
+	res = query(`selector={agent="a1"}&type=gauge`)
+	assert.Equal(t, int64(2), res.Count)
+	assert.Equal(t, 150.0, *res.Sum)
+
+	res = query("glob=Poll*&type=counter&limit=0")
+	assert.Equal(t, int64(1), res.Count)
+	assert.Equal(t, 5.0, *res.Sum)
+
+	res = query("selector=Unknown&type=gauge")
+	assert.Equal(t, entity.Aggregate{}, res)
+
+	// the labels without quotes match the same metrics
+	res = query(`selector={agent=a1}&type=gauge`)
+	assert.Equal(t, int64(2), res.Count)
+
+	for _, q := range []string{`selector=HeapAlloc{agent&type=gauge`, "selector=HeapAlloc"} {
+		statusCode, _ := ts.testRequest(t, "GET", "/api/v1/query?"+q, nil)
+		assert.Equal(t, http.StatusBadRequest, statusCode, q)
+	}
+}
+
+func TestGetHistoryHandler(t *testing.T) {
+	memStorage, err := repo.NewMetricsRepo()
+	assert.NoError(t, err)
+
+	tl := testLogger()
+	ts := NewTestServer(memStorage, tl, usecase.History(memStorage, 0, nil))
+	for _, request := range []string{
+		"/update/gauge/Alloc/1",
+		"/update/gauge/Alloc/3",
+	} {
+		statusCode, _ := ts.testRequest(t, "POST", request, nil)
+		assert.Equal(t, http.StatusOK, statusCode)
+	}
+
+	statusCode, body := ts.testRequest(t, "GET", "/api/v1/history?name=Alloc&step=1s", nil)
+	require.Equal(t, http.StatusOK, statusCode)
+
+	var history entity.History
+	require.NoError(t, json.Unmarshal(body, &history))
+	assert.Equal(t, "Alloc", history.Name)
+	assert.Equal(t, entity.ResolutionRaw, history.Resolution)
+	require.Len(t, history.Points, 2)
+	assert.Equal(t, 3.0, history.Points[1].Last)
+
+	statusCode, body = ts.testRequest(t, "GET", "/api/v1/history?name=Alloc&points=10", nil)
+	require.Equal(t, http.StatusOK, statusCode)
+	require.NoError(t, json.Unmarshal(body, &history))
+	assert.Equal(t, entity.ResolutionMinute, history.Resolution)
+	assert.Empty(t, history.Points)
+
+	for _, query := range []string{
+		"", "name=Alloc&from=yesterday", "name=Alloc&from=2023-04-02T00:00:00Z&to=2023-04-01T00:00:00Z",
+		"name=Alloc&step=-1s", "name=Alloc&points=x",
+	} {
+		statusCode, _ = ts.testRequest(t, "GET", "/api/v1/history?"+query, nil)
+		assert.Equal(t, http.StatusBadRequest, statusCode, query)
+	}
+
+	withoutHistory := NewTestServer(memStorage, tl)
+	statusCode, _ = withoutHistory.testRequest(t, "GET", "/api/v1/history?name=Alloc", nil)
+	assert.Equal(t, http.StatusNotImplemented, statusCode)
+}
+
+func TestCounterFuncHandlers(t *testing.T) {
+	memStorage, err := repo.NewMetricsRepo()
+	assert.NoError(t, err)
+
+	tl := testLogger()
+	ts := NewTestServer(memStorage, tl, usecase.History(memStorage, 0, nil))
+	for _, request := range []string{
+		"/update/counter/PollCount/5",
+		"/update/counter/PollCount/10",
+		"/update/gauge/Alloc/1",
+	} {
+		statusCode, _ := ts.testRequest(t, "POST", request, nil)
+		assert.Equal(t, http.StatusOK, statusCode)
+	}
+
+	// the first value in the window is the base of the increase
+	statusCode, body := ts.testRequest(t, "GET", "/value/counter/PollCount?fn=increase", nil)
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Equal(t, "10", string(body))
+
+	statusCode, body = ts.testRequest(t, "GET", "/value/counter/PollCount?fn=rate&window=10s", nil)
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Equal(t, "1", string(body))
+
+	resp, err := http.Get(ts.Server.URL + "/value/counter/PollCount?fn=rate&window=10s")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "text/plain", resp.Header.Get("Content-Type"))
+
+	statusCode, body = ts.testRequest(t, "GET", "/api/v1/metrics?fn=rate&window=10s", nil)
+	assert.Equal(t, http.StatusOK, statusCode)
+
+	var items []listItem
+	require.NoError(t, json.Unmarshal(body, &items))
+	require.Len(t, items, 2)
+	assert.Equal(t, "Alloc", items[0].ID)
+	assert.Nil(t, items[0].Rate)
+	assert.Equal(t, "PollCount", items[1].ID)
+	assert.Equal(t, 1.0, *items[1].Rate)
+	assert.Nil(t, items[1].Increase)
+
+	for _, request := range []string{
+		"/value/counter/PollCount?fn=avg",
+		"/value/counter/PollCount?fn=rate&window=0s",
+		"/value/gauge/Alloc?fn=rate",
+		"/api/v1/metrics?fn=rate&window=x",
+	} {
+		statusCode, _ = ts.testRequest(t, "GET", request, nil)
+		assert.Equal(t, http.StatusBadRequest, statusCode, request)
+	}
+
+	statusCode, _ = ts.testRequest(t, "GET", "/value/counter/Unknown?fn=rate", nil)
+	assert.Equal(t, http.StatusNotFound, statusCode)
+}
+
+func TestGetSeveralMetricsHandler(t *testing.T) {
+	memStorage, err := repo.NewMetricsRepo()
+	assert.NoError(t, err)
+
+	tl := testLogger()
+	ts := NewTestServer(memStorage, tl)
+	for _, request := range []string{
+		"/update/gauge/HeapInuse/786432.01",
+		"/update/counter/PollCount/5",
+	} {
+		statusCode, _ := ts.testRequest(t, "POST", request, nil)
+		assert.Equal(t, http.StatusOK, statusCode)
+	}
+
+	reqBody := `[{"id":"PollCount","type":"counter"},{"id":"Alloc","type":"gauge"},{"id":"HeapInuse","type":"gauge"}]`
+	statusCode, body := ts.testRequest(t, "POST", "/values/", strings.NewReader(reqBody))
+	assert.Equal(t, http.StatusOK, statusCode)
+
+	var items []valuesItem
+	err = json.Unmarshal(body, &items)
+	require.NoError(t, err)
+	require.Len(t, items, 3)
+
+	assert.Equal(t, "PollCount", items[0].ID)
+	assert.Equal(t, entity.Counter(5), *items[0].Delta)
+	assert.False(t, items[0].NotFound)
+
+	assert.Equal(t, "Alloc", items[1].ID)
+	assert.True(t, items[1].NotFound)
+
+	assert.Equal(t, entity.Gauge(786432.01), *items[2].Value)
+
+	// the labels are looked up in any order, the same ID of another type is not found
+	statusCode, _ = ts.testRequest(t, "POST", `/update/gauge/Alloc{a="1",b="2"}/3`, nil)
+	assert.Equal(t, http.StatusOK, statusCode)
+
+	reqBody = `[{"id":"Alloc{b=\"2\",a=\"1\"}","type":"gauge"},{"id":"Alloc{a=\"1\",b=\"2\"}","type":"counter"}]`
+	statusCode, body = ts.testRequest(t, "POST", "/values/", strings.NewReader(reqBody))
+	assert.Equal(t, http.StatusOK, statusCode)
+
+	items = nil
+	require.NoError(t, json.Unmarshal(body, &items))
+	require.Len(t, items, 2)
+	assert.False(t, items[0].NotFound)
+	assert.Equal(t, `Alloc{a="1",b="2"}`, items[0].ID)
+	assert.Equal(t, entity.Gauge(3), *items[0].Value)
+	assert.True(t, items[1].NotFound)
+	assert.Equal(t, Counter, items[1].MType)
+
+	statusCode, _ = ts.testRequest(t, "POST", "/values/", strings.NewReader("{"))
+	assert.Equal(t, http.StatusBadRequest, statusCode)
+}
+
+func TestGetMetricsHandlerEscaping(t *testing.T) {
+	memStorage, err := repo.NewMetricsRepo()
+	assert.NoError(t, err)
+
+	var value entity.Gauge = 1
+	err = memStorage.StoreMetrics(context.Background(), entity.Metrics{
+		ID:    "<script>alert(1)</script>",
+		MType: Gauge,
+		Value: &value,
+	})
+	require.NoError(t, err)
+
+	tl := testLogger()
+	ts := NewTestServer(memStorage, tl)
+
+	statusCode, body := ts.testRequest(t, "GET", "/?refresh=30", nil)
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.NotContains(t, string(body), "<script>")
+	assert.Contains(t, string(body), "&lt;script&gt;alert(1)&lt;/script&gt;")
+	assert.Contains(t, string(body), `content="30"`)
+
+	statusCode, _ = ts.testRequest(t, "GET", "/?refresh=0", nil)
+	assert.Equal(t, http.StatusBadRequest, statusCode)
+}
+
+func TestStreamHandler(t *testing.T) {
+	memStorage, err := repo.NewMetricsRepo()
+	assert.NoError(t, err)
+
+	tl := testLogger()
+	ts := NewTestServer(memStorage, tl)
+	defer ts.Server.Close()
+
+	resp, err := http.Get(ts.Server.URL + "/stream?type=counter")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	for _, request := range []string{
+		"/update/gauge/HeapInuse/786432.01",
+		"/update/counter/PollCount/5",
+	} {
+		statusCode, _ := ts.testRequest(t, "POST", request, nil)
+		assert.Equal(t, http.StatusOK, statusCode)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "event: metrics\n", line)
+
+	line, err = reader.ReadString('\n')
+	require.NoError(t, err)
+
+	var metrics entity.Metrics
+	err = json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &metrics)
+	require.NoError(t, err)
+	assert.Equal(t, "PollCount", metrics.ID)
+	assert.Equal(t, entity.Counter(5), *metrics.Delta)
+}
+
+func TestGetAlertsHandler(t *testing.T) {
+	memStorage, err := repo.NewMetricsRepo()
+	assert.NoError(t, err)
+
+	rules, err := alert.LoadRules("../../../configs/alerts.yml")
+	require.NoError(t, err)
+
+	tl := testLogger()
+	ts := NewTestServer(memStorage, tl, usecase.Alerting(alert.NewEngine(rules)))
+
+	statusCode, _ := ts.testRequest(t, "POST", "/update/gauge/HeapAlloc/2e9", nil)
+	assert.Equal(t, http.StatusOK, statusCode)
+
+	statusCode, body := ts.testRequest(t, "GET", "/api/v1/alerts", nil)
+	assert.Equal(t, http.StatusOK, statusCode)
+
+	var alerts []entity.Alert
+	err = json.Unmarshal(body, &alerts)
+	require.NoError(t, err)
+	require.Len(t, alerts, 2)
+	assert.Equal(t, "AgentStalled", alerts[0].Name)
+	assert.Equal(t, entity.AlertInactive, alerts[0].State)
+	assert.Equal(t, "HighHeapAlloc", alerts[1].Name)
+	assert.Equal(t, entity.AlertPending, alerts[1].State)
+	assert.Equal(t, "2m0s", alerts[1].For)
+}
+
+func TestGetAgentsHandler(t *testing.T) {
+	memStorage, err := repo.NewMetricsRepo()
+	assert.NoError(t, err)
+
+	tl := testLogger()
+
+	noInventory := NewTestServer(memStorage, tl)
+	statusCode, _ := noInventory.testRequest(t, "GET", "/api/v1/agents", nil)
+	assert.Equal(t, http.StatusNotImplemented, statusCode)
+
+	ts := NewTestServer(memStorage, tl, usecase.AgentInventory(memStorage, time.Minute))
+
+	before := time.Now()
+	for _, name := range []string{"web-2", "web-1", "web-1", ""} {
+		req, err := http.NewRequest(http.MethodPost, ts.Server.URL+"/updates/",
+			strings.NewReader(`[{"id":"Alloc","type":"gauge","value":1},{"id":"PollCount","type":"counter","delta":1}]`))
+		require.NoError(t, err)
+		if name != "" {
+			req.Header.Set(agentHeader, name)
+			req.Header.Set(agentVersionHeader, "v1.2.0")
+			req.Header.Set(agentHostHeader, name+".local")
+		}
+
+		resp, err := ts.Server.Client().Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	// the rejected updates and the other requests of an agent are not heartbeats
+	for _, r := range []struct {
+		method, path, body string
+	}{
+		{method: http.MethodPost, path: "/updates/", body: `[{"id":"Alloc","type":"summary","value":1}]`},
+		{method: http.MethodGet, path: "/api/v1/agents/config"},
+	} {
+		req, err := http.NewRequest(r.method, ts.Server.URL+r.path, strings.NewReader(r.body))
+		require.NoError(t, err)
+		req.Header.Set(agentHeader, "web-3")
+
+		resp, err := ts.Server.Client().Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.NotEqual(t, http.StatusOK, resp.StatusCode)
+	}
+
+	statusCode, body := ts.testRequest(t, "GET", "/api/v1/agents", nil)
+	assert.Equal(t, http.StatusOK, statusCode)
+
+	var agents []entity.Agent
+	err = json.Unmarshal(body, &agents)
+	require.NoError(t, err)
+	require.Len(t, agents, 2)
+	assert.Equal(t, "web-1", agents[0].Name)
+	assert.Equal(t, "v1.2.0", agents[0].Version)
+	assert.Equal(t, "web-1.local", agents[0].Host)
+	assert.Equal(t, int64(4), agents[0].Metrics)
+	assert.Equal(t, entity.AgentActive, agents[0].Status)
+	assert.False(t, agents[0].LastSeen.Before(before.Truncate(time.Second)))
+	assert.NotEmpty(t, agents[0].Address)
+	assert.Equal(t, "web-2", agents[1].Name)
+	assert.Equal(t, int64(2), agents[1].Metrics)
+}
+
+func TestGetAgentConfigHandler(t *testing.T) {
+	memStorage, err := repo.NewMetricsRepo()
+	assert.NoError(t, err)
+
+	tl := testLogger()
+
+	noConfigs := NewTestServer(memStorage, tl)
+	statusCode, _ := noConfigs.testRequest(t, "GET", "/api/v1/agents/config", nil)
+	assert.Equal(t, http.StatusNotImplemented, statusCode)
+
+	path := t.TempDir() + "/agents.yml"
+	err = os.WriteFile(path, []byte(`
+configs:
+  - pollInterval: 2s
+  - groups: [db]
+    rateLimit: 4
+  - agents: [web-*]
+    metricsNames: [HeapAlloc]
+`), 0600)
+	require.NoError(t, err)
+
+	src, err := agentconfig.Load(path, tl)
+	require.NoError(t, err)
+	ts := NewTestServer(memStorage, tl, usecase.ServeAgentConfigs(src))
+
+	req, err := http.NewRequest(http.MethodGet, ts.Server.URL+"/api/v1/agents/config", nil)
+	require.NoError(t, err)
+	req.Header.Set(agentHeader, "web-1")
+	req.Header.Set(agentGroupHeader, "db")
+
+	resp, err := ts.Server.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var cfg entity.AgentConfig
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&cfg))
+	assert.Equal(t, entity.AgentConfig{PollInterval: "2s", MetricsNames: []string{"HeapAlloc"}, RateLimit: 4}, cfg)
+
+	statusCode, body := ts.testRequest(t, "GET", "/api/v1/agents/config?name=db-1", nil)
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.JSONEq(t, `{"poll_interval":"2s"}`, string(body))
+}
@@ -13,6 +13,9 @@ import (
 	"net/http"
 	"os"
 	"strings"
+
+	"github.com/vladislaoramos/alemetric/internal/entity"
+	"github.com/vladislaoramos/alemetric/internal/usecase"
 )
 
 type gzipWriter struct {
@@ -24,6 +27,36 @@ func (w gzipWriter) Write(b []byte) (int, error) {
 	return w.Writer.Write(b)
 }
 
+// Flush sends the compressed data to the client, e.g. for streaming responses.
+func (w gzipWriter) Flush() {
+	if gz, ok := w.Writer.(*gzip.Writer); ok {
+		_ = gz.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// agentHandler marks the context of the requests with the agent names in the agent header,
+// so the heartbeats of the agents are recorded with the metrics they store.
+func agentHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.Header.Get(agentHeader)
+		if name == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := usecase.WithAgent(r.Context(), entity.Agent{
+			Name:    name,
+			Version: r.Header.Get(agentVersionHeader),
+			Host:    r.Header.Get(agentHostHeader),
+			Address: r.RemoteAddr,
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
 func gzipReadHandler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if !strings.Contains(r.Header.Get("Content-Encoding"), "gzip") {
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/http/pprof"
 	"runtime/debug"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -15,6 +16,25 @@ import (
 const (
 	Gauge   = "gauge"
 	Counter = "counter"
+
+	// hashHeader carries the sign of the requests without a body, e.g. deletion or reset.
+	// signedAtHeader carries the Unix time the request is signed at, which is covered by the sign.
+	hashHeader     = "Hash"
+	signedAtHeader = "X-Signed-At"
+
+	// agentHeader, agentVersionHeader and agentHostHeader carry the identity of the agent sending the metrics.
+	agentHeader        = "X-Agent-Name"
+	agentVersionHeader = "X-Agent-Version"
+	agentHostHeader    = "X-Agent-Host"
+
+	// agentGroupHeader carries the group of the agent requesting its configuration.
+	agentGroupHeader = "X-Agent-Group"
+
+	// defaultHistoryRange is the range of the history requested without start.
+	defaultHistoryRange = time.Hour
+
+	// defaultCounterWindow is the window of the counter functions requested without it.
+	defaultCounterWindow = time.Minute
 )
 
 func NewRouter(
@@ -43,6 +63,7 @@ func NewRouter(
 	handler.Use(gzipWriteHandler)
 	handler.Use(gzipReadHandler)
 	handler.Use(rsaHandler(privateKeyPath))
+	handler.Use(agentHandler)
 
 	handler.Get("/ping", pingHandler(tool, l))
 
@@ -56,9 +77,32 @@ func NewRouter(
 	})
 
 	// value
+	handler.Post("/values/", getSeveralMetricsHandler(tool, l))
 	handler.Route("/value", func(r chi.Router) {
 		r.Post("/", getSomeMetricsHandler(tool, l))
 		r.Get("/{metricsType}/{metricsName}", getSpecificMetricsHandler(tool, l))
+		r.Delete("/{metricsType}/{metricsName}", deleteMetricsHandler(tool, l))
+	})
+
+	// reset
+	handler.Post("/reset/{metricsType}/{metricsName}", resetMetricsHandler(tool, l))
+
+	// stream
+	handler.Get("/stream", streamHandler(tool, l))
+
+	// api
+	handler.Route("/api/v1", func(r chi.Router) {
+		r.Get("/metrics", listMetricsHandler(tool, l))
+		r.Get("/query", queryMetricsHandler(tool, l))
+		r.Get("/history", getHistoryHandler(tool, l))
+		r.Get("/alerts", getAlertsHandler(tool, l))
+		r.Get("/agents", getAgentsHandler(tool, l))
+		r.Get("/agents/config", getAgentConfigHandler(tool, l))
+	})
+
+	// admin
+	handler.Route("/admin", func(r chi.Router) {
+		r.Get("/stale", getStaleMetricsHandler(tool, l))
 	})
 
 	handler.Route("/debug/pprof/", func(r chi.Router) {
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/vladislaoramos/alemetric/internal/usecase"
+	logger "github.com/vladislaoramos/alemetric/pkg/log"
+)
+
+// streamKeepAlive is the interval of comments that keep an idle stream open.
+const streamKeepAlive = 15 * time.Second
+
+// streamHandler handles a request to stream the accepted metrics as Server-Sent Events.
+// The metrics are filtered by the query parameters type, prefix and glob.
+// Each metrics is sent as the "metrics" event; if a slow client misses metrics,
+// the "dropped" event with their number is sent.
+func streamHandler(tool *usecase.ToolUseCase, l logger.LogInterface) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+			return
+		}
+
+		filter, err := parseFilter(r.URL.Query())
+		if err != nil {
+			http.Error(w, "error parsing filter: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		sub := tool.Subscribe(filter)
+		defer tool.Unsubscribe(sub)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		ticker := time.NewTicker(streamKeepAlive)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-ticker.C:
+				fmt.Fprint(w, ": keep-alive\n\n")
+			case m, ok := <-sub.Events():
+				if !ok {
+					return
+				}
+
+				if dropped := sub.Dropped(); dropped > 0 {
+					fmt.Fprintf(w, "event: dropped\ndata: %d\n\n", dropped)
+				}
+
+				data, err := json.Marshal(m)
+				if err != nil {
+					l.Error(fmt.Sprintf("Handlers - Stream - Error: %s", err.Error()))
+					continue
+				}
+
+				fmt.Fprintf(w, "event: metrics\ndata: %s\n\n", data)
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -1,13 +1,14 @@
 package benchmark
 
 import (
-	"reflect"
-	"strings"
+	"context"
+	"io"
 	"testing"
 
 	"github.com/vladislaoramos/alemetric/internal/app/agent"
 	"github.com/vladislaoramos/alemetric/internal/entity"
 	"github.com/vladislaoramos/alemetric/internal/usecase"
+	logger "github.com/vladislaoramos/alemetric/pkg/log"
 )
 
 var metricsNames = []string{
@@ -45,43 +46,28 @@ var metricsNames = []string{
 	"CPUutilization1",
 }
 
-func BenchmarkCollectMetricsStruct(b *testing.B) {
-	for i := 0; i < b.N; i++ {
-		metrics := agent.NewMetrics()
-		tasks := make([]entity.Metrics, 0, len(metricsNames))
-
-		for _, name := range metricsNames {
-			field := reflect.Indirect(reflect.ValueOf(metrics)).FieldByName(name)
-			if !field.IsValid() {
-				continue
-			}
+func BenchmarkCollectMetricsRegistry(b *testing.B) {
+	registry := agent.NewRegistry(logger.New("error", io.Discard))
+	items, err := agent.NewRuntimeCollector().Collect(context.Background())
+	if err != nil {
+		b.Fatal(err)
+	}
 
-			fieldType := strings.ToLower(field.Type().Name())
+	names := make(map[string]struct{}, len(metricsNames))
+	for _, name := range metricsNames {
+		names[name] = struct{}{}
+	}
 
-			var (
-				valCounter *entity.Counter
-				valGauge   *entity.Gauge
-			)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		registry.Push(items...)
+		tasks := make([]entity.Metrics, 0, len(metricsNames))
 
-			switch fieldType {
-			case usecase.Counter:
-				val := entity.Counter(field.Int())
-				valCounter = &val
-			case usecase.Gauge:
-				val := entity.Gauge(field.Float())
-				valGauge = &val
-			default:
+		for _, m := range registry.Flush() {
+			if _, ok := names[m.ID]; !ok {
 				continue
 			}
-
-			task := entity.Metrics{
-				ID:    name,
-				MType: fieldType,
-				Delta: valCounter,
-				Value: valGauge,
-			}
-
-			tasks = append(tasks, task)
+			tasks = append(tasks, m)
 		}
 
 		_ = tasks
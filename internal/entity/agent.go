@@ -0,0 +1,32 @@
+package entity
+
+import "time"
+
+// Agent statuses.
+const (
+	AgentActive = "active"
+	AgentAbsent = "absent"
+)
+
+// Agent stores the heartbeat of an agent: its identity, the last time it sent metrics to the server,
+// the address it sent them from and the number of metrics received from it.
+// An agent silent for longer than the absence period is absent.
+type Agent struct {
+	Name     string    `json:"name" db:"name"`
+	Version  string    `json:"version" db:"version"`
+	Host     string    `json:"host" db:"host"`
+	Address  string    `json:"address" db:"address"`
+	LastSeen time.Time `json:"last_seen" db:"last_seen"`
+	Metrics  int64     `json:"metrics" db:"metrics"`
+	Status   string    `json:"status" db:"-"`
+}
+
+// AgentConfig is the configuration served by the server to an agent.
+// The intervals are durations, e.g. 10s. An empty field is not configured by the server,
+// and the agent keeps its own value of it.
+type AgentConfig struct {
+	PollInterval   string   `json:"poll_interval,omitempty" yaml:"pollInterval"`
+	ReportInterval string   `json:"report_interval,omitempty" yaml:"reportInterval"`
+	MetricsNames   []string `json:"metrics_names,omitempty" yaml:"metricsNames"`
+	RateLimit      uint     `json:"rate_limit,omitempty" yaml:"rateLimit"`
+}
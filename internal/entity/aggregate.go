@@ -0,0 +1,51 @@
+package entity
+
+// Aggregate stores the aggregates of the values of several metrics.
+// The value of a counter is its delta.
+// Sum, Avg, Min and Max are nil if no metrics were aggregated.
+type Aggregate struct {
+	Count int64    `json:"count" db:"count"`
+	Sum   *float64 `json:"sum,omitempty" db:"sum"`
+	Avg   *float64 `json:"avg,omitempty" db:"avg"`
+	Min   *float64 `json:"min,omitempty" db:"min"`
+	Max   *float64 `json:"max,omitempty" db:"max"`
+}
+
+// NewAggregate computes the aggregates of the values of the metrics.
+// Metrics without values are skipped.
+func NewAggregate(items []Metrics) Aggregate {
+	var (
+		a           Aggregate
+		sum, lo, hi float64
+	)
+
+	for _, m := range items {
+		var v float64
+		switch {
+		case m.Value != nil:
+			v = float64(*m.Value)
+		case m.Delta != nil:
+			v = float64(*m.Delta)
+		default:
+			continue
+		}
+
+		if a.Count == 0 || v < lo {
+			lo = v
+		}
+		if a.Count == 0 || v > hi {
+			hi = v
+		}
+		sum += v
+		a.Count++
+	}
+
+	if a.Count == 0 {
+		return a
+	}
+
+	avg := sum / float64(a.Count)
+	a.Sum, a.Avg, a.Min, a.Max = &sum, &avg, &lo, &hi
+
+	return a
+}
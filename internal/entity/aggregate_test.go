@@ -0,0 +1,31 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAggregate(t *testing.T) {
+	var (
+		v1 Gauge   = 1.5
+		v2 Gauge   = -2
+		d  Counter = 10
+	)
+
+	a := NewAggregate([]Metrics{
+		{ID: "a", MType: "gauge", Value: &v1},
+		{ID: "b", MType: "gauge", Value: &v2},
+		{ID: "c", MType: "counter", Delta: &d},
+		{ID: "d", MType: "gauge"},
+	})
+
+	require.Equal(t, int64(3), a.Count)
+	require.Equal(t, 9.5, *a.Sum)
+	require.InDelta(t, 9.5/3, *a.Avg, 1e-9)
+	require.Equal(t, -2.0, *a.Min)
+	require.Equal(t, 10.0, *a.Max)
+
+	empty := NewAggregate(nil)
+	require.Equal(t, Aggregate{}, empty)
+}
@@ -0,0 +1,26 @@
+package entity
+
+import "time"
+
+// Alert states.
+const (
+	AlertInactive = "inactive"
+	AlertPending  = "pending"
+	AlertFiring   = "firing"
+	AlertResolved = "resolved"
+)
+
+// Alert stores the state of an alerting rule.
+// An alert is pending while the condition of the rule holds for less than its duration,
+// then it is firing until the condition stops holding and the alert becomes resolved.
+type Alert struct {
+	Name       string     `json:"name"`
+	Expr       string     `json:"expr"`
+	For        string     `json:"for,omitempty"`
+	State      string     `json:"state"`
+	Value      *float64   `json:"value,omitempty"` // the last evaluated value of the expression
+	ActiveAt   *time.Time `json:"active_at,omitempty"`
+	FiredAt    *time.Time `json:"fired_at,omitempty"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	ChangedAt  *time.Time `json:"changed_at,omitempty"` // the time of the last change of the state
+}
@@ -0,0 +1,117 @@
+package entity
+
+import (
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Sort keys of Filter.
+const (
+	SortByName    = "name"
+	SortByType    = "type"
+	SortByUpdated = "updated"
+)
+
+// Filter stores the conditions of selecting metrics.
+// Empty attributes don't restrict the selection.
+// Limit equal to zero means no limit.
+type Filter struct {
+	Type   string            // metrics type
+	Prefix string            // prefix of metrics name
+	Glob   string            // shell pattern of metrics name, e.g. Heap*
+	Name   string            // metrics name without labels, e.g. HeapAlloc matches HeapAlloc{agent="a1"}
+	Labels map[string]string // labels the metrics must have
+	SortBy string            // one of the sort keys; metrics are sorted by name by default
+	Desc   bool              // descending order of sorting
+	Limit  int
+	Offset int
+}
+
+// Match checks if a metrics satisfies the conditions of the filter.
+func (f Filter) Match(m Metrics) bool {
+	if f.Type != "" && m.MType != f.Type {
+		return false
+	}
+
+	if f.Prefix != "" && !strings.HasPrefix(m.ID, f.Prefix) {
+		return false
+	}
+
+	if f.Glob != "" {
+		ok, err := path.Match(f.Glob, m.ID)
+		if err != nil || !ok {
+			return false
+		}
+	}
+
+	if f.Name != "" || len(f.Labels) != 0 {
+		name, labels, err := ParseID(m.ID)
+		if err != nil {
+			return false
+		}
+
+		if f.Name != "" && name != f.Name {
+			return false
+		}
+
+		for k, v := range f.Labels {
+			if value, ok := labels[k]; !ok || value != v {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// Apply selects the metrics satisfying the filter, then sorts and paginates them.
+func (f Filter) Apply(items []Metrics) []Metrics {
+	res := make([]Metrics, 0, len(items))
+	for _, m := range items {
+		if f.Match(m) {
+			res = append(res, m)
+		}
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		if f.Desc {
+			return f.less(res[j], res[i])
+		}
+		return f.less(res[i], res[j])
+	})
+
+	if f.Offset >= len(res) {
+		return res[:0]
+	}
+	res = res[f.Offset:]
+
+	if f.Limit > 0 && f.Limit < len(res) {
+		res = res[:f.Limit]
+	}
+
+	return res
+}
+
+func (f Filter) less(a, b Metrics) bool {
+	switch f.SortBy {
+	case SortByType:
+		if a.MType != b.MType {
+			return a.MType < b.MType
+		}
+	case SortByUpdated:
+		at, bt := updated(a), updated(b)
+		if !at.Equal(bt) {
+			return at.Before(bt)
+		}
+	}
+	return a.ID < b.ID
+}
+
+func updated(m Metrics) time.Time {
+	if m.Updated == nil {
+		return time.Time{}
+	}
+	return *m.Updated
+}
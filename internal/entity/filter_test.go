@@ -0,0 +1,132 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilter_Apply(t *testing.T) {
+	var (
+		value Gauge   = 1
+		delta Counter = 1
+		now           = time.Now()
+		later         = now.Add(time.Second)
+	)
+
+	items := []Metrics{
+		{ID: "HeapInuse", MType: "gauge", Value: &value, Updated: &later},
+		{ID: "HeapAlloc", MType: "gauge", Value: &value, Updated: &now},
+		{ID: "PollCount", MType: "counter", Delta: &delta, Updated: &now},
+		{ID: "Alloc", MType: "gauge", Value: &value, Updated: &later},
+	}
+
+	ids := func(items []Metrics) []string {
+		res := make([]string, 0, len(items))
+		for _, m := range items {
+			res = append(res, m.ID)
+		}
+		return res
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{
+			name:   "without conditions",
+			filter: Filter{},
+			want:   []string{"Alloc", "HeapAlloc", "HeapInuse", "PollCount"},
+		},
+		{
+			name:   "by type",
+			filter: Filter{Type: "counter"},
+			want:   []string{"PollCount"},
+		},
+		{
+			name:   "by prefix in descending order",
+			filter: Filter{Prefix: "Heap", Desc: true},
+			want:   []string{"HeapInuse", "HeapAlloc"},
+		},
+		{
+			name:   "by glob",
+			filter: Filter{Glob: "*Alloc"},
+			want:   []string{"Alloc", "HeapAlloc"},
+		},
+		{
+			name:   "sorted by update time",
+			filter: Filter{SortBy: SortByUpdated},
+			want:   []string{"HeapAlloc", "PollCount", "Alloc", "HeapInuse"},
+		},
+		{
+			name:   "sorted by type with pagination",
+			filter: Filter{SortBy: SortByType, Limit: 2, Offset: 1},
+			want:   []string{"Alloc", "HeapAlloc"},
+		},
+		{
+			name:   "with offset out of range",
+			filter: Filter{Offset: 10},
+			want:   []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, ids(tt.filter.Apply(items)))
+		})
+	}
+}
+
+func TestFilter_Match_Labels(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		id     string
+		want   bool
+	}{
+		{
+			name:   "name without labels",
+			filter: Filter{Name: "HeapAlloc"},
+			id:     "HeapAlloc",
+			want:   true,
+		},
+		{
+			name:   "name with labels",
+			filter: Filter{Name: "HeapAlloc"},
+			id:     `HeapAlloc{agent="a1"}`,
+			want:   true,
+		},
+		{
+			name:   "other name",
+			filter: Filter{Name: "Heap"},
+			id:     `HeapAlloc{agent="a1"}`,
+			want:   false,
+		},
+		{
+			name:   "subset of labels",
+			filter: Filter{Labels: map[string]string{"agent": "a1"}},
+			id:     `HeapAlloc{agent="a1",host="h1"}`,
+			want:   true,
+		},
+		{
+			name:   "other label value",
+			filter: Filter{Name: "HeapAlloc", Labels: map[string]string{"agent": "a2"}},
+			id:     `HeapAlloc{agent="a1",host="h1"}`,
+			want:   false,
+		},
+		{
+			name:   "missing label",
+			filter: Filter{Labels: map[string]string{"agent": "a1"}},
+			id:     "HeapAlloc",
+			want:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.filter.Match(Metrics{ID: tt.id, MType: "gauge"}))
+		})
+	}
+}
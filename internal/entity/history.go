@@ -0,0 +1,122 @@
+package entity
+
+import "time"
+
+// Resolutions of the metrics history.
+// The raw history stores every accepted value; the others store the values rolled up into buckets.
+const (
+	ResolutionRaw    = "raw"
+	ResolutionMinute = "1m"
+	ResolutionHour   = "1h"
+	ResolutionDay    = "1d"
+)
+
+// Resolutions lists the resolutions of the history from the finest to the coarsest.
+// The buckets of each resolution are rolled up from the previous one.
+var Resolutions = []string{ResolutionRaw, ResolutionMinute, ResolutionHour, ResolutionDay}
+
+var resolutionSteps = map[string]time.Duration{
+	ResolutionRaw:    0,
+	ResolutionMinute: time.Minute,
+	ResolutionHour:   time.Hour,
+	ResolutionDay:    24 * time.Hour,
+}
+
+// ResolutionStep returns the duration of the buckets of the resolution.
+// The raw resolution has no buckets, so its step is zero.
+func ResolutionStep(resolution string) time.Duration {
+	return resolutionSteps[resolution]
+}
+
+// ValidResolution checks if the resolution is known.
+func ValidResolution(resolution string) bool {
+	_, ok := resolutionSteps[resolution]
+	return ok
+}
+
+// Sample is a value of the metrics accepted at the moment.
+type Sample struct {
+	Name  string    `json:"name" db:"name"`
+	MType string    `json:"type" db:"mtype"`
+	Value float64   `json:"value" db:"value"` // the value of a gauge or the total of a counter
+	Delta float64   `json:"delta" db:"delta"` // the increment of a counter
+	At    time.Time `json:"at" db:"at"`
+}
+
+// Point is the aggregate of the samples of the metrics in the bucket starting at the moment.
+// A raw point is a single sample.
+// Min, Max and Last are taken of the values; Sum is the sum of the values of a gauge
+// or of the increments of a counter.
+type Point struct {
+	At    time.Time `json:"at" db:"bucket"`
+	Count int64     `json:"count" db:"count"`
+	Min   float64   `json:"min" db:"min"`
+	Max   float64   `json:"max" db:"max"`
+	Avg   float64   `json:"avg" db:"-"`
+	Sum   float64   `json:"sum" db:"sum"`
+	Last  float64   `json:"last" db:"last"`
+}
+
+// History is the history of the metrics at the resolution.
+type History struct {
+	Name       string  `json:"name"`
+	Resolution string  `json:"resolution"`
+	Points     []Point `json:"points"`
+}
+
+// NewPoint makes the raw point of the sample.
+func NewPoint(s Sample) Point {
+	p := Point{At: s.At, Count: 1, Min: s.Value, Max: s.Value, Sum: s.Value, Last: s.Value}
+	if s.MType == "counter" {
+		p.Sum = s.Delta
+	}
+	return p
+}
+
+// Merge adds the point of the later moment to the aggregate.
+func (p *Point) Merge(other Point) {
+	if p.Count == 0 {
+		at := p.At
+		*p = other
+		p.At = at
+		return
+	}
+
+	if other.Min < p.Min {
+		p.Min = other.Min
+	}
+	if other.Max > p.Max {
+		p.Max = other.Max
+	}
+	p.Count += other.Count
+	p.Sum += other.Sum
+	p.Last = other.Last
+}
+
+// Rollup aggregates the points sorted by time into the buckets of the step.
+func Rollup(points []Point, step time.Duration) []Point {
+	res := make([]Point, 0)
+	for _, p := range points {
+		bucket := p.At.Truncate(step)
+		if len(res) == 0 || !res[len(res)-1].At.Equal(bucket) {
+			res = append(res, Point{At: bucket})
+		}
+		res[len(res)-1].Merge(p)
+	}
+	return res
+}
+
+// Increase computes the increase of a counter from its successive values sorted by time.
+// A value lower than the previous one means that the counter was reset,
+// so the increase after the reset is counted from zero.
+func Increase(values []float64) float64 {
+	var res float64
+	for i := 1; i < len(values); i++ {
+		if values[i] < values[i-1] {
+			res += values[i]
+			continue
+		}
+		res += values[i] - values[i-1]
+	}
+	return res
+}
@@ -0,0 +1,56 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRollup(t *testing.T) {
+	start := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	points := []Point{
+		NewPoint(Sample{Name: "g", MType: "gauge", Value: 3, At: start.Add(10 * time.Second)}),
+		NewPoint(Sample{Name: "g", MType: "gauge", Value: 1, At: start.Add(20 * time.Second)}),
+		NewPoint(Sample{Name: "g", MType: "gauge", Value: 5, At: start.Add(30 * time.Second)}),
+		NewPoint(Sample{Name: "g", MType: "gauge", Value: 4, At: start.Add(70 * time.Second)}),
+	}
+
+	res := Rollup(points, time.Minute)
+	require.Equal(t, []Point{
+		{At: start, Count: 3, Min: 1, Max: 5, Sum: 9, Last: 5},
+		{At: start.Add(time.Minute), Count: 1, Min: 4, Max: 4, Sum: 4, Last: 4},
+	}, res)
+
+	require.Equal(t, []Point{
+		{At: start, Count: 4, Min: 1, Max: 5, Sum: 13, Last: 4},
+	}, Rollup(res, time.Hour))
+}
+
+func TestNewPoint(t *testing.T) {
+	at := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	p := NewPoint(Sample{Name: "PollCount", MType: "counter", Value: 15, Delta: 5, At: at})
+	require.Equal(t, Point{At: at, Count: 1, Min: 15, Max: 15, Sum: 5, Last: 15}, p)
+}
+
+func TestIncrease(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		want   float64
+	}{
+		{name: "without values", want: 0},
+		{name: "single value", values: []float64{10}, want: 0},
+		{name: "growing counter", values: []float64{10, 15, 15, 30}, want: 20},
+		{name: "reset counter", values: []float64{10, 15, 3, 8}, want: 13},
+		{name: "reset to zero", values: []float64{10, 0, 5}, want: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, Increase(tt.values))
+		})
+	}
+}
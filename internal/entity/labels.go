@@ -0,0 +1,128 @@
+package entity
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidSelector is returned when a selector of metrics cannot be parsed.
+var ErrInvalidSelector = errors.New("invalid selector")
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// FormatID makes the metrics ID from the name and the labels in the form of `name{key="value",...}`.
+// Labels are sorted by key, so the same labels always make the same ID.
+func FormatID(name string, labels map[string]string) string {
+	if len(labels) == 0 {
+		return name
+	}
+
+	var sb strings.Builder
+	sb.WriteString(name)
+	sb.WriteByte('{')
+	for i, k := range sortedKeys(labels) {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(FormatLabel(k, labels[k]))
+	}
+	sb.WriteByte('}')
+
+	return sb.String()
+}
+
+// FormatLabel makes the label in the form it has in the metrics ID.
+func FormatLabel(key, value string) string {
+	return key + `="` + labelValueReplacer.Replace(value) + `"`
+}
+
+// NormalizeID makes the ID with the same labels in the form made by FormatID,
+// e.g. `name{b=2, a="1"}` becomes `name{a="1",b="2"}`. An ID that cannot be parsed is returned as is.
+func NormalizeID(id string) string {
+	name, labels, err := ParseID(id)
+	if err != nil {
+		return id
+	}
+	return FormatID(name, labels)
+}
+
+// ParseID splits the metrics ID into the name and the labels.
+// An ID without labels has nil labels.
+func ParseID(id string) (string, map[string]string, error) {
+	start := strings.IndexByte(id, '{')
+	if start < 0 {
+		return id, nil, nil
+	}
+
+	if !strings.HasSuffix(id, "}") {
+		return "", nil, ErrInvalidSelector
+	}
+
+	labels, err := parseLabels(id[start+1 : len(id)-1])
+	if err != nil {
+		return "", nil, err
+	}
+
+	return id[:start], labels, nil
+}
+
+// parseLabels parses the labels separated by commas.
+// Values may be unquoted if they contain no commas and quotes.
+func parseLabels(s string) (map[string]string, error) {
+	labels := make(map[string]string)
+
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 {
+			return nil, ErrInvalidSelector
+		}
+		key := strings.TrimSpace(s[:eq])
+		s = strings.TrimSpace(s[eq+1:])
+
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			var sb strings.Builder
+			i := 1
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				sb.WriteByte(s[i])
+			}
+			if i == len(s) {
+				return nil, ErrInvalidSelector
+			}
+			value, s = sb.String(), strings.TrimSpace(s[i+1:])
+		} else {
+			end := strings.IndexByte(s, ',')
+			if end < 0 {
+				end = len(s)
+			}
+			value, s = strings.TrimSpace(s[:end]), s[end:]
+		}
+
+		if key == "" || strings.ContainsAny(key, `{}",`) {
+			return nil, ErrInvalidSelector
+		}
+		labels[key] = value
+
+		if s != "" {
+			if s[0] != ',' {
+				return nil, ErrInvalidSelector
+			}
+			s = s[1:]
+		}
+	}
+
+	return labels, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,90 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatID(t *testing.T) {
+	require.Equal(t, "HeapAlloc", FormatID("HeapAlloc", nil))
+	require.Equal(t,
+		`HeapAlloc{agent="a1",path="C:\\dir \"x\""}`,
+		FormatID("HeapAlloc", map[string]string{"path": `C:\dir "x"`, "agent": "a1"}),
+	)
+}
+
+func TestParseID(t *testing.T) {
+	tests := []struct {
+		name       string
+		id         string
+		wantName   string
+		wantLabels map[string]string
+		wantErr    bool
+	}{
+		{
+			name:     "without labels",
+			id:       "HeapAlloc",
+			wantName: "HeapAlloc",
+		},
+		{
+			name:       "quoted values",
+			id:         `HeapAlloc{agent="a1",path="C:\\dir \"x\", y"}`,
+			wantName:   "HeapAlloc",
+			wantLabels: map[string]string{"agent": "a1", "path": `C:\dir "x", y`},
+		},
+		{
+			name:       "unquoted values with spaces",
+			id:         `HeapAlloc{ agent = a1 , host=h1 }`,
+			wantName:   "HeapAlloc",
+			wantLabels: map[string]string{"agent": "a1", "host": "h1"},
+		},
+		{
+			name:       "only labels",
+			id:         `{agent="a1"}`,
+			wantLabels: map[string]string{"agent": "a1"},
+		},
+		{
+			name:    "unclosed braces",
+			id:      `HeapAlloc{agent="a1"`,
+			wantErr: true,
+		},
+		{
+			name:    "unclosed quotes",
+			id:      `HeapAlloc{agent="a1}`,
+			wantErr: true,
+		},
+		{
+			name:    "without value",
+			id:      `HeapAlloc{agent}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, labels, err := ParseID(tt.id)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrInvalidSelector)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantName, name)
+			require.Equal(t, tt.wantLabels, labels)
+		})
+	}
+}
+
+func TestParseID_FormatID(t *testing.T) {
+	labels := map[string]string{"agent": "a,1", "path": `"\`}
+	name, got, err := ParseID(FormatID("HeapAlloc", labels))
+	require.NoError(t, err)
+	require.Equal(t, "HeapAlloc", name)
+	require.Equal(t, labels, got)
+}
+
+func TestNormalizeID(t *testing.T) {
+	require.Equal(t, "HeapAlloc", NormalizeID("HeapAlloc"))
+	require.Equal(t, `HeapAlloc{agent="a1",host="h1"}`, NormalizeID(`HeapAlloc{host=h1, agent="a1"}`))
+	require.Equal(t, `HeapAlloc{agent`, NormalizeID(`HeapAlloc{agent`))
+}
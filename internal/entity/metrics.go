@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"log"
 	"strconv"
+	"time"
 )
 
 type (
@@ -21,14 +22,15 @@ const (
 )
 
 // Metrics stores data of a metrics.
-// It contains such attributes: ID, MType, Delta, Value, Hash.
+// It contains such attributes: ID, MType, Delta, Value, Hash, Updated.
 // A correct metrics must have either Delta or Value.
 type Metrics struct {
-	ID    string   `json:"id" db:"name"`    // metrics name
-	MType string   `json:"type" db:"mtype"` // metrics type: either gauge or counter
-	Delta *Counter `json:"delta,omitempty"` // metrics value if the type is counter
-	Value *Gauge   `json:"value,omitempty"` // metrics value if the type is  gauge
-	Hash  string   `json:"hash,omitempty"`  // a hash function value
+	ID      string     `json:"id" db:"name"`                      // metrics name
+	MType   string     `json:"type" db:"mtype"`                   // metrics type: either gauge or counter
+	Delta   *Counter   `json:"delta,omitempty"`                   // metrics value if the type is counter
+	Value   *Gauge     `json:"value,omitempty"`                   // metrics value if the type is  gauge
+	Hash    string     `json:"hash,omitempty"`                    // a hash function value
+	Updated *time.Time `json:"updated,omitempty" db:"updated_at"` // time of the last update set by the storage
 }
 
 // ParseGaugeMetrics parses Metrics with Gauge type.
@@ -88,3 +90,23 @@ func (m *Metrics) hash(key string) string {
 func (m *Metrics) CheckDataSign(key string) bool {
 	return m.Hash == m.hash(key)
 }
+
+// SignAction signs an action on the metrics, e.g. its deletion or reset, made at the time.
+// Such a sign doesn't depend on the metrics value; the time limits the replay of the signed request.
+func (m *Metrics) SignAction(action, key string, at time.Time) {
+	if key != "" {
+		m.Hash = m.actionHash(action, key, at)
+	}
+}
+
+// CheckActionSign checks if an action on the metrics made at the time is signed.
+func (m *Metrics) CheckActionSign(action, key string, at time.Time) bool {
+	return hmac.Equal([]byte(m.Hash), []byte(m.actionHash(action, key, at)))
+}
+
+func (m *Metrics) actionHash(action, key string, at time.Time) string {
+	h := hmac.New(sha256.New, []byte(key))
+	h.Write([]byte(fmt.Sprintf("%s:%s:%s:%d", action, m.ID, m.MType, at.Unix())))
+
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
@@ -2,6 +2,7 @@ package entity
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -110,3 +111,19 @@ func TestSignData(t *testing.T) {
 	metrics.SignData("TestApp", "")
 	require.NotEqual(t, "", metrics.Hash)
 }
+
+func TestActionSign(t *testing.T) {
+	key := "secretKey"
+	metrics := &Metrics{
+		ID:    "metric1",
+		MType: "counter",
+	}
+
+	at := time.Unix(1700000000, 0)
+	metrics.SignAction("reset", key, at)
+	require.NotEqual(t, "", metrics.Hash)
+	require.True(t, metrics.CheckActionSign("reset", key, at))
+	require.False(t, metrics.CheckActionSign("delete", key, at))
+	require.False(t, metrics.CheckActionSign("reset", "otherKey", at))
+	require.False(t, metrics.CheckActionSign("reset", key, at.Add(time.Second)))
+}
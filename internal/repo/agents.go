@@ -0,0 +1,41 @@
+package repo
+
+import (
+	"context"
+	"sort"
+
+	"github.com/vladislaoramos/alemetric/internal/entity"
+)
+
+// StoreAgent stores the heartbeat of the agent in the in-memory storage.
+// The metrics of the heartbeat are added to the metrics received from the agent before.
+func (r *MetricsRepo) StoreAgent(_ context.Context, a entity.Agent) error {
+	r.agentsMu.Lock()
+	defer r.agentsMu.Unlock()
+
+	if r.agents == nil {
+		r.agents = make(map[string]entity.Agent)
+	}
+
+	a.Metrics += r.agents[a.Name].Metrics
+	r.agents[a.Name] = a
+
+	return nil
+}
+
+// GetAgents gets the heartbeats of all agents sorted by name from the in-memory storage.
+func (r *MetricsRepo) GetAgents(_ context.Context) ([]entity.Agent, error) {
+	r.agentsMu.Lock()
+	defer r.agentsMu.Unlock()
+
+	res := make([]entity.Agent, 0, len(r.agents))
+	for _, a := range r.agents {
+		res = append(res, a)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+
+	return res, nil
+}
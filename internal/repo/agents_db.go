@@ -0,0 +1,50 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/vladislaoramos/alemetric/internal/entity"
+)
+
+// StoreAgent stores the heartbeat of the agent in the database.
+// The metrics of the heartbeat are added to the metrics received from the agent before.
+func (r *PostgresRepo) StoreAgent(ctx context.Context, a entity.Agent) error {
+	q, args, err := r.Builder.
+		Insert("agents").
+		Columns("name", "version", "host", "address", "last_seen", "metrics").
+		Values(a.Name, a.Version, a.Host, a.Address, a.LastSeen, a.Metrics).
+		Suffix(`ON CONFLICT (name) DO UPDATE SET
+version = EXCLUDED.version, host = EXCLUDED.host, address = EXCLUDED.address,
+last_seen = EXCLUDED.last_seen, metrics = agents.metrics + EXCLUDED.metrics`).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("builder error storing agent into db: %w", err)
+	}
+
+	if _, err = r.Pool.Exec(ctx, q, args...); err != nil {
+		return fmt.Errorf("error storing agent into db: %w", err)
+	}
+
+	return nil
+}
+
+// GetAgents gets the heartbeats of all agents sorted by name from the database.
+func (r *PostgresRepo) GetAgents(ctx context.Context) ([]entity.Agent, error) {
+	q, args, err := r.Builder.
+		Select("name", "version", "host", "address", "last_seen", "metrics").
+		From("agents").
+		OrderBy("name").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("builder error getting agents from db: %w", err)
+	}
+
+	res := make([]entity.Agent, 0)
+	if err = pgxscan.Select(ctx, r.Pool, &res, q, args...); err != nil {
+		return nil, fmt.Errorf("error getting agents from db: %w", err)
+	}
+
+	return res, nil
+}
@@ -4,6 +4,7 @@ package repo
 import (
 	"context"
 	"fmt"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/georgysavva/scany/pgxscan"
@@ -37,7 +38,8 @@ func (r *PostgresRepo) GetMetrics(ctx context.Context, name string) (entity.Metr
 			"mtype",
 			"delta",
 			"value",
-			"hash").
+			"hash",
+			"updated_at").
 		From("metrics").
 		Where(sq.Eq{"name": name}).
 		ToSql()
@@ -64,6 +66,7 @@ func (r *PostgresRepo) StoreMetrics(ctx context.Context, metrics entity.Metrics)
 		Set("delta", metrics.Delta).
 		Set("value", metrics.Value).
 		Set("hash", metrics.Hash).
+		Set("updated_at", sq.Expr("now()")).
 		Where(sq.Eq{"name": metrics.ID}).
 		ToSql()
 
@@ -78,13 +81,15 @@ func (r *PostgresRepo) StoreMetrics(ctx context.Context, metrics entity.Metrics)
 			"mtype",
 			"delta",
 			"value",
-			"hash").
+			"hash",
+			"updated_at").
 		Values(
 			metrics.ID,
 			metrics.MType,
 			metrics.Delta,
 			metrics.Value,
-			metrics.Hash).
+			metrics.Hash,
+			sq.Expr("now()")).
 		ToSql()
 	if err != nil {
 		return fmt.Errorf("error inserting metrics into db: %w", err)
@@ -101,6 +106,135 @@ func (r *PostgresRepo) StoreMetrics(ctx context.Context, metrics entity.Metrics)
 	return nil
 }
 
+// GetSeveralMetrics gets the metrics with the names from the database by a single query.
+// Names that are not found are skipped.
+func (r *PostgresRepo) GetSeveralMetrics(ctx context.Context, names []string) ([]entity.Metrics, error) {
+	q, args, err := r.Builder.
+		Select(metricsColumns...).
+		From("metrics").
+		Where("name = ANY(?)", names).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("builder error getting several metrics from db: %w", err)
+	}
+
+	dst := make([]entity.Metrics, 0, len(names))
+	if err = pgxscan.Select(ctx, r.Pool, &dst, q, args...); err != nil {
+		return nil, fmt.Errorf("error selecting several metrics from db: %w", err)
+	}
+
+	return dst, nil
+}
+
+// ListMetrics gets the metrics satisfying the filter from the database.
+func (r *PostgresRepo) ListMetrics(ctx context.Context, filter entity.Filter) ([]entity.Metrics, error) {
+	b := r.Builder.
+		Select(metricsColumns...).
+		From("metrics")
+
+	q, args, err := pageFilter(whereFilter(b, filter), filter).ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("builder error listing metrics from db: %w", err)
+	}
+
+	dst := make([]entity.Metrics, 0)
+	if err = pgxscan.Select(ctx, r.Pool, &dst, q, args...); err != nil {
+		return nil, fmt.Errorf("error listing metrics from db: %w", err)
+	}
+
+	return dst, nil
+}
+
+// AggregateMetrics computes the aggregates of the values of the metrics satisfying the filter in the database.
+// The sorting and the pagination of the filter are ignored.
+func (r *PostgresRepo) AggregateMetrics(ctx context.Context, filter entity.Filter) (entity.Aggregate, error) {
+	b := r.Builder.
+		Select(
+			"count(*) AS count",
+			"sum("+aggregateValue+") AS sum",
+			"avg("+aggregateValue+") AS avg",
+			"min("+aggregateValue+") AS min",
+			"max("+aggregateValue+") AS max",
+		).
+		From("metrics").
+		Where("coalesce(value, delta) IS NOT NULL")
+
+	q, args, err := whereFilter(b, filter).ToSql()
+	if err != nil {
+		return entity.Aggregate{}, fmt.Errorf("builder error aggregating metrics in db: %w", err)
+	}
+
+	var dst entity.Aggregate
+	if err = pgxscan.Get(ctx, r.Pool, &dst, q, args...); err != nil {
+		return entity.Aggregate{}, fmt.Errorf("error aggregating metrics in db: %w", err)
+	}
+
+	return dst, nil
+}
+
+// DeleteMetrics deletes a metrics by its name from the database.
+func (r *PostgresRepo) DeleteMetrics(ctx context.Context, name string) error {
+	q, args, err := r.Builder.
+		Delete("metrics").
+		Where(sq.Eq{"name": name}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("builder error deleting metrics from db: %w", err)
+	}
+
+	tag, err := r.Pool.Exec(ctx, q, args...)
+	if err != nil {
+		return fmt.Errorf("error deleting metrics from db: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// GetStaleMetrics gets all metrics of the type that were last updated before the moment.
+func (r *PostgresRepo) GetStaleMetrics(ctx context.Context, mType string, before time.Time) ([]entity.Metrics, error) {
+	q, args, err := r.Builder.
+		Select(metricsColumns...).
+		From("metrics").
+		Where(sq.Eq{"mtype": mType}).
+		Where(sq.Lt{"updated_at": before}).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("builder error getting stale metrics from db: %w", err)
+	}
+
+	dst := make([]entity.Metrics, 0)
+	if err = pgxscan.Select(ctx, r.Pool, &dst, q, args...); err != nil {
+		return nil, fmt.Errorf("error selecting stale metrics from db: %w", err)
+	}
+
+	return dst, nil
+}
+
+// DeleteStaleMetrics deletes all metrics of the type that were last updated before the moment.
+// It returns the names of the deleted metrics.
+func (r *PostgresRepo) DeleteStaleMetrics(ctx context.Context, mType string, before time.Time) ([]string, error) {
+	q, args, err := r.Builder.
+		Delete("metrics").
+		Where(sq.Eq{"mtype": mType}).
+		Where(sq.Lt{"updated_at": before}).
+		Suffix("RETURNING name").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("builder error deleting stale metrics from db: %w", err)
+	}
+
+	res := make([]string, 0)
+	if err = pgxscan.Select(ctx, r.Pool, &res, q, args...); err != nil {
+		return nil, fmt.Errorf("error deleting stale metrics from db: %w", err)
+	}
+
+	return res, nil
+}
+
 func (r *PostgresRepo) StoreAll() error {
 	return nil
 }
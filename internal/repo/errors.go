@@ -2,4 +2,7 @@ package repo
 
 import "errors"
 
-var ErrNotFound = errors.New("not found")
+var (
+	ErrNotFound          = errors.New("not found")
+	ErrUnknownResolution = errors.New("unknown history resolution")
+)
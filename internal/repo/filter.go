@@ -0,0 +1,135 @@
+package repo
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/vladislaoramos/alemetric/internal/entity"
+)
+
+var (
+	metricsColumns = []string{
+		"name",
+		"mtype",
+		"delta",
+		"value",
+		"hash",
+		"updated_at",
+	}
+
+	sortColumns = map[string]string{
+		entity.SortByName:    "name",
+		entity.SortByType:    "mtype",
+		entity.SortByUpdated: "updated_at",
+	}
+
+	// aggregateValue is the value of a metrics for the aggregates: the value of a gauge or the delta of a counter.
+	aggregateValue = "coalesce(value, delta::double precision)"
+
+	likeReplacer = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+)
+
+// whereFilter adds the conditions of the filter to the query.
+func whereFilter(b sq.SelectBuilder, f entity.Filter) sq.SelectBuilder {
+	if f.Type != "" {
+		b = b.Where(sq.Eq{"mtype": f.Type})
+	}
+
+	if f.Prefix != "" {
+		b = b.Where(sq.Like{"name": likeReplacer.Replace(f.Prefix) + "%"})
+	}
+
+	if f.Glob != "" {
+		b = b.Where("name ~ ?", globToRegexp(f.Glob))
+	}
+
+	if f.Name != "" {
+		b = b.Where(sq.Or{
+			sq.Eq{"name": f.Name},
+			sq.Like{"name": likeReplacer.Replace(f.Name) + "{%"},
+		})
+	}
+
+	for _, k := range sortedKeys(f.Labels) {
+		b = b.Where("name ~ ?", labelToRegexp(k, f.Labels[k]))
+	}
+
+	return b
+}
+
+// pageFilter adds the sorting and the pagination of the filter to the query.
+func pageFilter(b sq.SelectBuilder, f entity.Filter) sq.SelectBuilder {
+	order := "ASC"
+	if f.Desc {
+		order = "DESC"
+	}
+
+	column, ok := sortColumns[f.SortBy]
+	if ok && column != "name" {
+		b = b.OrderBy(column+" "+order, "name "+order)
+	} else {
+		b = b.OrderBy("name " + order)
+	}
+
+	if f.Limit > 0 {
+		b = b.Limit(uint64(f.Limit))
+	}
+
+	if f.Offset > 0 {
+		b = b.Offset(uint64(f.Offset))
+	}
+
+	return b
+}
+
+// labelToRegexp makes the POSIX regular expression matching the IDs having the label.
+// The stored IDs are normalized, so their labels have the form made by entity.FormatLabel.
+func labelToRegexp(key, value string) string {
+	return `[{,]` + regexp.QuoteMeta(entity.FormatLabel(key, value)) + `[,}]`
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// globToRegexp converts a shell pattern into the equivalent POSIX regular expression.
+// As in path.Match, the wildcards don't match the slash.
+func globToRegexp(glob string) string {
+	var sb strings.Builder
+	sb.WriteByte('^')
+
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			sb.WriteString("[^/]*")
+		case '?':
+			sb.WriteString("[^/]")
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				sb.WriteString(regexp.QuoteMeta(string(glob[i])))
+			}
+		case '[':
+			end := strings.IndexByte(glob[i:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			// character classes have the same syntax in both cases
+			sb.WriteString(glob[i : i+end+1])
+			i += end
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	sb.WriteByte('$')
+	return sb.String()
+}
@@ -0,0 +1,107 @@
+package repo
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/stretchr/testify/require"
+	"github.com/vladislaoramos/alemetric/internal/entity"
+)
+
+func TestGlobToRegexp(t *testing.T) {
+	names := []string{
+		"Heap", "HeapAlloc", "Alloc", "CPUutilization1", "CPUutilization10", "a.b", "axb", "m1", "mx",
+		"Disk/sda", "Disk/sda/used", `Disk{path="/var"}`,
+	}
+
+	tests := []struct {
+		glob string
+		want []string
+	}{
+		{glob: "Heap*", want: []string{"Heap", "HeapAlloc"}},
+		{glob: "CPUutilization?", want: []string{"CPUutilization1"}},
+		{glob: "a.b", want: []string{"a.b"}},
+		{glob: "m[0-9]", want: []string{"m1"}},
+		{glob: "m[^0-9]", want: []string{"mx"}},
+		{glob: "Disk*", want: nil},
+		{glob: "Disk/*", want: []string{"Disk/sda"}},
+		{glob: "Disk/*/used", want: []string{"Disk/sda/used"}},
+		{glob: "Disk?sda", want: nil},
+	}
+
+	memRepo, err := NewMetricsRepo()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	var value entity.Gauge = 1
+	for _, name := range names {
+		require.NoError(t, memRepo.StoreMetrics(ctx, entity.Metrics{ID: name, MType: "gauge", Value: &value}))
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.glob, func(t *testing.T) {
+			// the memory repo matches the names with path.Match
+			items, err := memRepo.ListMetrics(ctx, entity.Filter{Glob: tt.glob})
+			require.NoError(t, err)
+			memNames := make([]string, 0, len(items))
+			for _, m := range items {
+				memNames = append(memNames, m.ID)
+			}
+
+			// the database repo matches the names with the regular expression
+			re := regexp.MustCompile(globToRegexp(tt.glob))
+			dbNames := make([]string, 0, len(names))
+			for _, name := range names {
+				if re.MatchString(name) {
+					dbNames = append(dbNames, name)
+				}
+			}
+
+			require.ElementsMatch(t, tt.want, memNames)
+			require.ElementsMatch(t, tt.want, dbNames)
+		})
+	}
+}
+
+func TestFilterQuery(t *testing.T) {
+	b := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).Select("name").From("metrics")
+	filter := entity.Filter{
+		Type:   "gauge",
+		Prefix: "Heap_",
+		Glob:   "*Alloc",
+		SortBy: entity.SortByUpdated,
+		Desc:   true,
+		Limit:  10,
+		Offset: 20,
+	}
+
+	q, args, err := pageFilter(whereFilter(b, filter), filter).ToSql()
+	require.NoError(t, err)
+	require.Equal(t,
+		"SELECT name FROM metrics WHERE mtype = $1 AND name LIKE $2 AND name ~ $3 "+
+			"ORDER BY updated_at DESC, name DESC LIMIT 10 OFFSET 20",
+		q)
+	require.Equal(t, []interface{}{"gauge", `Heap\_%`, "^[^/]*Alloc$"}, args)
+}
+
+func TestFilterQuery_Labels(t *testing.T) {
+	b := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).Select("name").From("metrics")
+	filter := entity.Filter{
+		Name:   "Heap_Alloc",
+		Labels: map[string]string{"host": "h1", "agent": "a.1"},
+	}
+
+	q, args, err := whereFilter(b, filter).ToSql()
+	require.NoError(t, err)
+	require.Equal(t,
+		"SELECT name FROM metrics WHERE (name = $1 OR name LIKE $2) AND name ~ $3 AND name ~ $4",
+		q)
+	require.Equal(t, []interface{}{"Heap_Alloc", `Heap\_Alloc{%`, `[{,]agent="a\.1"[,}]`, `[{,]host="h1"[,}]`}, args)
+
+	re := regexp.MustCompile(args[2].(string))
+	require.True(t, re.MatchString(`Heap_Alloc{agent="a.1",host="h1"}`))
+	require.False(t, re.MatchString(`Heap_Alloc{agent="a.10"}`))
+	require.False(t, re.MatchString(`Heap_Alloc{xagent="a.1"}`))
+}
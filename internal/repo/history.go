@@ -0,0 +1,164 @@
+package repo
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/vladislaoramos/alemetric/internal/entity"
+)
+
+// StoreSample appends the sample to the raw history in the in-memory storage.
+func (r *MetricsRepo) StoreSample(_ context.Context, s entity.Sample) error {
+	r.historyMu.Lock()
+	defer r.historyMu.Unlock()
+
+	points := r.historyPoints(entity.ResolutionRaw)
+	points[s.Name] = insertPoint(points[s.Name], entity.NewPoint(s))
+
+	return nil
+}
+
+// Rollup aggregates the history of the previous resolution between from and to
+// into the buckets of the resolution in the in-memory storage.
+// The buckets that already exist are replaced.
+func (r *MetricsRepo) Rollup(_ context.Context, resolution string, from, to time.Time) error {
+	source, err := rollupSource(resolution)
+	if err != nil {
+		return err
+	}
+	step := entity.ResolutionStep(resolution)
+
+	r.historyMu.Lock()
+	defer r.historyMu.Unlock()
+
+	dst := r.historyPoints(resolution)
+	for name, points := range r.historyPoints(source) {
+		for _, p := range entity.Rollup(pointsBetween(points, from, to), step) {
+			dst[name] = insertPoint(dst[name], p)
+		}
+	}
+
+	return nil
+}
+
+// DeleteHistory deletes the history of the resolution older than the moment from the in-memory storage.
+func (r *MetricsRepo) DeleteHistory(_ context.Context, resolution string, before time.Time) error {
+	if !entity.ValidResolution(resolution) {
+		return ErrUnknownResolution
+	}
+
+	r.historyMu.Lock()
+	defer r.historyMu.Unlock()
+
+	history := r.historyPoints(resolution)
+	for name, points := range history {
+		i := sort.Search(len(points), func(i int) bool {
+			return !points[i].At.Before(before)
+		})
+		if i == len(points) {
+			delete(history, name)
+			continue
+		}
+		history[name] = append([]entity.Point(nil), points[i:]...)
+	}
+
+	return nil
+}
+
+// GetHistory gets the history of the metrics at the resolution between from and to
+// from the in-memory storage.
+func (r *MetricsRepo) GetHistory(
+	_ context.Context, name, resolution string, from, to time.Time,
+) ([]entity.Point, error) {
+	if !entity.ValidResolution(resolution) {
+		return nil, ErrUnknownResolution
+	}
+
+	r.historyMu.Lock()
+	defer r.historyMu.Unlock()
+
+	points := pointsBetween(r.historyPoints(resolution)[name], from, to)
+	return append(make([]entity.Point, 0, len(points)), points...), nil
+}
+
+// GetSeveralHistory gets the history of the metrics with the names at the resolution between from and to
+// from the in-memory storage. Names without history are skipped.
+func (r *MetricsRepo) GetSeveralHistory(
+	_ context.Context, names []string, resolution string, from, to time.Time,
+) (map[string][]entity.Point, error) {
+	if !entity.ValidResolution(resolution) {
+		return nil, ErrUnknownResolution
+	}
+
+	r.historyMu.Lock()
+	defer r.historyMu.Unlock()
+
+	history := r.historyPoints(resolution)
+	res := make(map[string][]entity.Point, len(names))
+	for _, name := range names {
+		if points := pointsBetween(history[name], from, to); len(points) > 0 {
+			res[name] = append(make([]entity.Point, 0, len(points)), points...)
+		}
+	}
+
+	return res, nil
+}
+
+func (r *MetricsRepo) historyPoints(resolution string) map[string][]entity.Point {
+	if r.history == nil {
+		r.history = make(map[string]map[string][]entity.Point)
+	}
+
+	points, ok := r.history[resolution]
+	if !ok {
+		points = make(map[string][]entity.Point)
+		r.history[resolution] = points
+	}
+
+	return points
+}
+
+// insertPoint inserts the point into the points sorted by time
+// replacing the point of the same moment.
+func insertPoint(points []entity.Point, p entity.Point) []entity.Point {
+	i := sort.Search(len(points), func(i int) bool {
+		return !points[i].At.Before(p.At)
+	})
+
+	if i < len(points) && points[i].At.Equal(p.At) {
+		points[i] = p
+		return points
+	}
+
+	points = append(points, entity.Point{})
+	copy(points[i+1:], points[i:])
+	points[i] = p
+
+	return points
+}
+
+// pointsBetween returns the points sorted by time in [from, to).
+func pointsBetween(points []entity.Point, from, to time.Time) []entity.Point {
+	start := sort.Search(len(points), func(i int) bool {
+		return !points[i].At.Before(from)
+	})
+	end := sort.Search(len(points), func(i int) bool {
+		return !points[i].At.Before(to)
+	})
+
+	if start >= end {
+		return nil
+	}
+	return points[start:end]
+}
+
+// rollupSource returns the resolution the buckets of the resolution are rolled up from.
+func rollupSource(resolution string) (string, error) {
+	for i := 1; i < len(entity.Resolutions); i++ {
+		if entity.Resolutions[i] == resolution {
+			return entity.Resolutions[i-1], nil
+		}
+	}
+	return "", ErrUnknownResolution
+}
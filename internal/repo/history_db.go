@@ -0,0 +1,187 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/vladislaoramos/alemetric/internal/entity"
+)
+
+const (
+	// rollupSamplesQuery aggregates the raw samples into the buckets of a resolution.
+	rollupSamplesQuery = `
+INSERT INTO metrics_rollups (name, resolution, bucket, count, min, max, sum, last)
+SELECT name, $1, to_timestamp(floor(extract(epoch FROM at)::double precision / $2) * $2) AS b,
+       count(*), min(value), max(value),
+       sum(CASE WHEN mtype = 'counter' THEN delta ELSE value END),
+       (array_agg(value ORDER BY at DESC))[1]
+FROM metrics_samples
+WHERE at >= $3 AND at < $4
+GROUP BY name, b
+ON CONFLICT (resolution, name, bucket) DO UPDATE
+SET count = EXCLUDED.count, min = EXCLUDED.min, max = EXCLUDED.max, sum = EXCLUDED.sum, last = EXCLUDED.last`
+
+	// rollupBucketsQuery aggregates the buckets of a resolution into the buckets of a coarser one.
+	rollupBucketsQuery = `
+INSERT INTO metrics_rollups (name, resolution, bucket, count, min, max, sum, last)
+SELECT name, $1, to_timestamp(floor(extract(epoch FROM bucket)::double precision / $2) * $2) AS b,
+       sum(count), min(min), max(max), sum(sum),
+       (array_agg(last ORDER BY bucket DESC))[1]
+FROM metrics_rollups
+WHERE resolution = $5 AND bucket >= $3 AND bucket < $4
+GROUP BY name, b
+ON CONFLICT (resolution, name, bucket) DO UPDATE
+SET count = EXCLUDED.count, min = EXCLUDED.min, max = EXCLUDED.max, sum = EXCLUDED.sum, last = EXCLUDED.last`
+)
+
+// StoreSample inserts the sample into the raw history in the database.
+func (r *PostgresRepo) StoreSample(ctx context.Context, s entity.Sample) error {
+	q, args, err := r.Builder.
+		Insert("metrics_samples").
+		Columns("name", "mtype", "value", "delta", "at").
+		Values(s.Name, s.MType, s.Value, s.Delta, s.At).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("builder error storing sample into db: %w", err)
+	}
+
+	if _, err = r.Pool.Exec(ctx, q, args...); err != nil {
+		return fmt.Errorf("error storing sample into db: %w", err)
+	}
+
+	return nil
+}
+
+// Rollup aggregates the history of the previous resolution between from and to
+// into the buckets of the resolution in the database.
+// The buckets that already exist are replaced.
+func (r *PostgresRepo) Rollup(ctx context.Context, resolution string, from, to time.Time) error {
+	source, err := rollupSource(resolution)
+	if err != nil {
+		return err
+	}
+	step := entity.ResolutionStep(resolution).Seconds()
+
+	if source == entity.ResolutionRaw {
+		_, err = r.Pool.Exec(ctx, rollupSamplesQuery, resolution, step, from, to)
+	} else {
+		_, err = r.Pool.Exec(ctx, rollupBucketsQuery, resolution, step, from, to, source)
+	}
+	if err != nil {
+		return fmt.Errorf("error rolling up history into %s buckets in db: %w", resolution, err)
+	}
+
+	return nil
+}
+
+// DeleteHistory deletes the history of the resolution older than the moment from the database.
+func (r *PostgresRepo) DeleteHistory(ctx context.Context, resolution string, before time.Time) error {
+	if !entity.ValidResolution(resolution) {
+		return ErrUnknownResolution
+	}
+
+	b := r.Builder.Delete("metrics_rollups").Where(sq.Eq{"resolution": resolution}, sq.Lt{"bucket": before})
+	if resolution == entity.ResolutionRaw {
+		b = r.Builder.Delete("metrics_samples").Where(sq.Lt{"at": before})
+	}
+
+	q, args, err := b.ToSql()
+	if err != nil {
+		return fmt.Errorf("builder error deleting history from db: %w", err)
+	}
+
+	if _, err = r.Pool.Exec(ctx, q, args...); err != nil {
+		return fmt.Errorf("error deleting %s history from db: %w", resolution, err)
+	}
+
+	return nil
+}
+
+// GetHistory gets the history of the metrics at the resolution between from and to from the database.
+func (r *PostgresRepo) GetHistory(
+	ctx context.Context, name, resolution string, from, to time.Time,
+) ([]entity.Point, error) {
+	history, err := r.GetSeveralHistory(ctx, []string{name}, resolution, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	if history[name] == nil {
+		return make([]entity.Point, 0), nil
+	}
+	return history[name], nil
+}
+
+// namedPoint is a point of the history of the metrics with the name.
+type namedPoint struct {
+	Name string `db:"name"`
+	entity.Point
+}
+
+// GetSeveralHistory gets the history of the metrics with the names at the resolution between from and to
+// from the database by a single query. Names without history are skipped.
+func (r *PostgresRepo) GetSeveralHistory(
+	ctx context.Context, names []string, resolution string, from, to time.Time,
+) (map[string][]entity.Point, error) {
+	if !entity.ValidResolution(resolution) {
+		return nil, ErrUnknownResolution
+	}
+
+	if resolution == entity.ResolutionRaw {
+		return r.getSamples(ctx, names, from, to)
+	}
+
+	q, args, err := r.Builder.
+		Select("name", "bucket", "count", "min", "max", "sum", "last").
+		From("metrics_rollups").
+		Where(sq.Eq{"resolution": resolution}).
+		Where("name = ANY(?)", names).
+		Where(sq.GtOrEq{"bucket": from}, sq.Lt{"bucket": to}).
+		OrderBy("name", "bucket").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("builder error getting history from db: %w", err)
+	}
+
+	dst := make([]namedPoint, 0)
+	if err = pgxscan.Select(ctx, r.Pool, &dst, q, args...); err != nil {
+		return nil, fmt.Errorf("error getting %s history from db: %w", resolution, err)
+	}
+
+	res := make(map[string][]entity.Point, len(names))
+	for _, p := range dst {
+		res[p.Name] = append(res[p.Name], p.Point)
+	}
+
+	return res, nil
+}
+
+func (r *PostgresRepo) getSamples(
+	ctx context.Context, names []string, from, to time.Time,
+) (map[string][]entity.Point, error) {
+	q, args, err := r.Builder.
+		Select("name", "mtype", "value", "delta", "at").
+		From("metrics_samples").
+		Where("name = ANY(?)", names).
+		Where(sq.GtOrEq{"at": from}, sq.Lt{"at": to}).
+		OrderBy("name", "at").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("builder error getting samples from db: %w", err)
+	}
+
+	samples := make([]entity.Sample, 0)
+	if err = pgxscan.Select(ctx, r.Pool, &samples, q, args...); err != nil {
+		return nil, fmt.Errorf("error getting samples from db: %w", err)
+	}
+
+	res := make(map[string][]entity.Point, len(names))
+	for _, s := range samples {
+		res[s.Name] = append(res[s.Name], entity.NewPoint(s))
+	}
+
+	return res, nil
+}
@@ -0,0 +1,73 @@
+package repo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vladislaoramos/alemetric/internal/entity"
+)
+
+func TestMetricsRepo_History(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	r, err := NewMetricsRepo()
+	require.NoError(t, err)
+
+	// the samples are stored out of order
+	for _, s := range []entity.Sample{
+		{Name: "PollCount", MType: "counter", Value: 15, Delta: 5, At: start.Add(90 * time.Second)},
+		{Name: "PollCount", MType: "counter", Value: 5, Delta: 5, At: start.Add(10 * time.Second)},
+		{Name: "PollCount", MType: "counter", Value: 10, Delta: 5, At: start.Add(50 * time.Second)},
+		{Name: "Alloc", MType: "gauge", Value: 1, At: start.Add(10 * time.Second)},
+	} {
+		require.NoError(t, r.StoreSample(ctx, s))
+	}
+
+	raw, err := r.GetHistory(ctx, "PollCount", entity.ResolutionRaw, start, start.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, raw, 3)
+	require.Equal(t, []float64{5, 10, 15}, []float64{raw[0].Last, raw[1].Last, raw[2].Last})
+
+	several, err := r.GetSeveralHistory(ctx, []string{"PollCount", "Alloc", "Unknown"}, entity.ResolutionRaw,
+		start, start.Add(time.Minute))
+	require.NoError(t, err)
+	require.Len(t, several, 2)
+	require.Equal(t, raw[:2], several["PollCount"])
+	require.Len(t, several["Alloc"], 1)
+
+	require.NoError(t, r.Rollup(ctx, entity.ResolutionMinute, start, start.Add(2*time.Minute)))
+	require.NoError(t, r.Rollup(ctx, entity.ResolutionHour, start, start.Add(time.Hour)))
+
+	minutes, err := r.GetHistory(ctx, "PollCount", entity.ResolutionMinute, start, start.Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, []entity.Point{
+		{At: start, Count: 2, Min: 5, Max: 10, Sum: 10, Last: 10},
+		{At: start.Add(time.Minute), Count: 1, Min: 15, Max: 15, Sum: 5, Last: 15},
+	}, minutes)
+
+	hours, err := r.GetHistory(ctx, "PollCount", entity.ResolutionHour, start, start.Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, []entity.Point{{At: start, Count: 3, Min: 5, Max: 15, Sum: 15, Last: 15}}, hours)
+
+	// a repeated rollup replaces the buckets
+	require.NoError(t, r.Rollup(ctx, entity.ResolutionMinute, start, start.Add(2*time.Minute)))
+	minutes, err = r.GetHistory(ctx, "PollCount", entity.ResolutionMinute, start, start.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, minutes, 2)
+	require.Equal(t, int64(2), minutes[0].Count)
+
+	require.NoError(t, r.DeleteHistory(ctx, entity.ResolutionRaw, start.Add(time.Minute)))
+	raw, err = r.GetHistory(ctx, "PollCount", entity.ResolutionRaw, start, start.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, raw, 1)
+	raw, err = r.GetHistory(ctx, "Alloc", entity.ResolutionRaw, start, start.Add(time.Hour))
+	require.NoError(t, err)
+	require.Empty(t, raw)
+
+	require.ErrorIs(t, r.Rollup(ctx, entity.ResolutionRaw, start, start), ErrUnknownResolution)
+	_, err = r.GetHistory(ctx, "PollCount", "1w", start, start)
+	require.ErrorIs(t, err, ErrUnknownResolution)
+}
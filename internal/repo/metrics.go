@@ -9,6 +9,7 @@ import (
 	"io"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/vladislaoramos/alemetric/internal/entity"
 )
@@ -19,6 +20,18 @@ type MetricsRepo struct {
 	Mu            *sync.Mutex
 	StoreFilePath string
 	Restore       bool
+
+	// restoredAt is used as the update time of the metrics
+	// restored from a store file written without timestamps.
+	restoredAt time.Time
+
+	// history stores the points of the metrics history by resolution and name.
+	historyMu sync.Mutex
+	history   map[string]map[string][]entity.Point
+
+	// agents stores the heartbeats of the agents by name.
+	agentsMu sync.Mutex
+	agents   map[string]entity.Agent
 }
 
 // NewMetricsRepo creates the in-memory storage object.
@@ -54,7 +67,11 @@ func (r *MetricsRepo) GetMetricsNames(_ context.Context) []string {
 }
 
 // StoreMetrics stores a metrics into the in-memory storage.
+// The update time of the metrics is set to the current time.
 func (r *MetricsRepo) StoreMetrics(_ context.Context, metrics entity.Metrics) error {
+	now := time.Now()
+	metrics.Updated = &now
+
 	r.Mu.Lock()
 	r.storage[metrics.ID] = metrics
 	r.Mu.Unlock()
@@ -72,6 +89,58 @@ func (r *MetricsRepo) GetMetrics(_ context.Context, name string) (entity.Metrics
 	return value, nil
 }
 
+// GetSeveralMetrics gets the metrics with the names from the in-memory storage.
+// Names that are not found are skipped.
+func (r *MetricsRepo) GetSeveralMetrics(_ context.Context, names []string) ([]entity.Metrics, error) {
+	res := make([]entity.Metrics, 0, len(names))
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	for _, name := range names {
+		if value, ok := r.storage[name]; ok {
+			res = append(res, value)
+		}
+	}
+	return res, nil
+}
+
+// ListMetrics gets the metrics satisfying the filter from the in-memory storage.
+func (r *MetricsRepo) ListMetrics(_ context.Context, filter entity.Filter) ([]entity.Metrics, error) {
+	r.Mu.Lock()
+	items := make([]entity.Metrics, 0, len(r.storage))
+	for _, m := range r.storage {
+		items = append(items, m)
+	}
+	r.Mu.Unlock()
+
+	return filter.Apply(items), nil
+}
+
+// AggregateMetrics computes the aggregates of the values of the metrics satisfying the filter
+// by scanning the in-memory storage.
+func (r *MetricsRepo) AggregateMetrics(_ context.Context, filter entity.Filter) (entity.Aggregate, error) {
+	r.Mu.Lock()
+	items := make([]entity.Metrics, 0, len(r.storage))
+	for _, m := range r.storage {
+		if filter.Match(m) {
+			items = append(items, m)
+		}
+	}
+	r.Mu.Unlock()
+
+	return entity.NewAggregate(items), nil
+}
+
+// DeleteMetrics deletes a metrics from the in-memory storage.
+func (r *MetricsRepo) DeleteMetrics(_ context.Context, name string) error {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	if _, ok := r.storage[name]; !ok {
+		return ErrNotFound
+	}
+	delete(r.storage, name)
+	return nil
+}
+
 // StoreAll stores all metrics from the in-memory storage to the store file.
 func (r *MetricsRepo) StoreAll() error {
 	file, err := os.OpenFile(r.StoreFilePath, os.O_WRONLY|os.O_CREATE, 0777)
@@ -127,9 +196,46 @@ func (r *MetricsRepo) Upload(_ context.Context) error {
 		return fmt.Errorf("error unmarshalling file with metrics: %w", err)
 	}
 
+	r.restoredAt = time.Now()
+
 	return nil
 }
 
+// GetStaleMetrics gets all metrics of the type that were last updated before the moment.
+func (r *MetricsRepo) GetStaleMetrics(_ context.Context, mType string, before time.Time) ([]entity.Metrics, error) {
+	res := make([]entity.Metrics, 0)
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	for _, m := range r.storage {
+		if m.MType == mType && r.updated(m).Before(before) {
+			res = append(res, m)
+		}
+	}
+	return res, nil
+}
+
+// DeleteStaleMetrics deletes all metrics of the type that were last updated before the moment.
+// It returns the names of the deleted metrics.
+func (r *MetricsRepo) DeleteStaleMetrics(_ context.Context, mType string, before time.Time) ([]string, error) {
+	res := make([]string, 0)
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	for name, m := range r.storage {
+		if m.MType == mType && r.updated(m).Before(before) {
+			delete(r.storage, name)
+			res = append(res, name)
+		}
+	}
+	return res, nil
+}
+
+func (r *MetricsRepo) updated(m entity.Metrics) time.Time {
+	if m.Updated == nil {
+		return r.restoredAt
+	}
+	return *m.Updated
+}
+
 func (r *MetricsRepo) Ping(_ context.Context) error {
 	return nil
 }
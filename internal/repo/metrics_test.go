@@ -8,6 +8,7 @@ import (
 	"sort"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"github.com/vladislaoramos/alemetric/internal/entity"
@@ -199,3 +200,102 @@ func TestNewMetricsRepo(t *testing.T) {
 	require.Empty(t, repo.storage)
 	require.True(t, repo.Restore)
 }
+
+func TestMetricsRepo_StaleMetrics(t *testing.T) {
+	metricsRepo := &MetricsRepo{
+		Mu:         &sync.Mutex{},
+		storage:    make(map[string]entity.Metrics),
+		restoredAt: time.Now().Add(-time.Hour),
+	}
+
+	ctx := context.Background()
+
+	var value entity.Gauge = 100.500
+	err := metricsRepo.StoreMetrics(ctx, entity.Metrics{ID: "Alloc", MType: "gauge", Value: &value})
+	require.NoError(t, err)
+
+	// restored from a store file without the update time
+	metricsRepo.storage["Frees"] = entity.Metrics{ID: "Frees", MType: "gauge", Value: &value}
+
+	var delta entity.Counter = 1
+	metricsRepo.storage["PollCount"] = entity.Metrics{ID: "PollCount", MType: "counter", Delta: &delta}
+
+	before := time.Now().Add(-time.Minute)
+
+	stale, err := metricsRepo.GetStaleMetrics(ctx, "gauge", before)
+	require.NoError(t, err)
+	require.Len(t, stale, 1)
+	require.Equal(t, "Frees", stale[0].ID)
+
+	names, err := metricsRepo.DeleteStaleMetrics(ctx, "gauge", before)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Frees"}, names)
+
+	_, err = metricsRepo.GetMetrics(ctx, "Frees")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	for _, name := range []string{"Alloc", "PollCount"} {
+		_, err = metricsRepo.GetMetrics(ctx, name)
+		require.NoError(t, err)
+	}
+}
+
+func TestMetricsRepo_DeleteMetrics(t *testing.T) {
+	metricsRepo := &MetricsRepo{
+		Mu:      &sync.Mutex{},
+		storage: make(map[string]entity.Metrics),
+	}
+
+	var value entity.Gauge = 100.500
+	metricsRepo.storage["Frees"] = entity.Metrics{
+		ID:    "Frees",
+		MType: "gauge",
+		Value: &value,
+	}
+
+	ctx := context.Background()
+
+	err := metricsRepo.DeleteMetrics(ctx, "Frees")
+	require.NoError(t, err)
+	require.Empty(t, metricsRepo.storage)
+
+	err = metricsRepo.DeleteMetrics(ctx, "Frees")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestMetricsRepo_ListMetrics(t *testing.T) {
+	metricsRepo, err := NewMetricsRepo()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	var value entity.Gauge = 100.500
+	for _, name := range []string{"HeapAlloc", "Alloc", "Frees"} {
+		err = metricsRepo.StoreMetrics(ctx, entity.Metrics{ID: name, MType: "gauge", Value: &value})
+		require.NoError(t, err)
+	}
+
+	items, err := metricsRepo.ListMetrics(ctx, entity.Filter{Glob: "*Alloc"})
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	require.Equal(t, "Alloc", items[0].ID)
+	require.Equal(t, "HeapAlloc", items[1].ID)
+}
+
+func TestMetricsRepo_GetSeveralMetrics(t *testing.T) {
+	metricsRepo := &MetricsRepo{
+		Mu:      &sync.Mutex{},
+		storage: make(map[string]entity.Metrics),
+	}
+
+	var value entity.Gauge = 100.500
+	for _, name := range []string{"Frees", "Alloc"} {
+		metricsRepo.storage[name] = entity.Metrics{ID: name, MType: "gauge", Value: &value}
+	}
+
+	items, err := metricsRepo.GetSeveralMetrics(context.Background(), []string{"Alloc", "some name", "Frees"})
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	require.Equal(t, "Alloc", items[0].ID)
+	require.Equal(t, "Frees", items[1].ID)
+}
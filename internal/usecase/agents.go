@@ -0,0 +1,65 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/vladislaoramos/alemetric/internal/entity"
+)
+
+type agentKey struct{}
+
+// WithAgent marks the context of the request as sent by the agent,
+// so the heartbeat of the agent is recorded once the metrics of the request are stored with the context.
+func WithAgent(ctx context.Context, agent entity.Agent) context.Context {
+	return context.WithValue(ctx, agentKey{}, agent)
+}
+
+// agentSeen records the heartbeat of the agent that sent the metrics stored with the context made by WithAgent,
+// counting the stored metrics.
+func (mt *ToolUseCase) agentSeen(ctx context.Context, at time.Time, metrics int) {
+	agent, ok := ctx.Value(agentKey{}).(entity.Agent)
+	if !ok || mt.agentRepo == nil || metrics == 0 {
+		return
+	}
+
+	agent.LastSeen = at
+	agent.Metrics = int64(metrics)
+
+	if err := mt.agentRepo.StoreAgent(ctx, agent); err != nil {
+		mt.logger.Error(fmt.Sprintf("error storing agent %s: %s", agent.Name, err))
+	}
+}
+
+// GetAgents gets the heartbeats of the agents with their statuses.
+// An agent silent for longer than the absence period is absent.
+func (mt *ToolUseCase) GetAgents(ctx context.Context) ([]entity.Agent, error) {
+	if mt.agentRepo == nil {
+		return nil, ErrNotImplemented
+	}
+
+	agents, err := mt.agentRepo.GetAgents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting agents: %w", err)
+	}
+
+	now := time.Now()
+	for i := range agents {
+		agents[i].Status = entity.AgentActive
+		if mt.absentAfter > 0 && now.Sub(agents[i].LastSeen) > mt.absentAfter {
+			agents[i].Status = entity.AgentAbsent
+		}
+	}
+
+	return agents, nil
+}
+
+// GetAgentConfig gets the configuration served to the agent with the name and the group.
+func (mt *ToolUseCase) GetAgentConfig(name, group string) (entity.AgentConfig, error) {
+	if mt.agentConfigs == nil {
+		return entity.AgentConfig{}, ErrNotImplemented
+	}
+
+	return mt.agentConfigs.Config(name, group), nil
+}
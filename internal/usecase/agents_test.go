@@ -0,0 +1,69 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vladislaoramos/alemetric/internal/entity"
+	"github.com/vladislaoramos/alemetric/internal/repo"
+)
+
+// countingAgentRepo counts the heartbeats stored into the repo.
+type countingAgentRepo struct {
+	AgentRepo
+	stored int
+}
+
+func (r *countingAgentRepo) StoreAgent(ctx context.Context, a entity.Agent) error {
+	r.stored++
+	return r.AgentRepo.StoreAgent(ctx, a)
+}
+
+func TestAgents(t *testing.T) {
+	r, err := repo.NewMetricsRepo()
+	require.NoError(t, err)
+
+	_, err = NewMetricsTool(r, testLogger()).GetAgents(context.Background())
+	require.ErrorIs(t, err, ErrNotImplemented)
+
+	counting := &countingAgentRepo{AgentRepo: r}
+	tool := NewMetricsTool(r, testLogger(), AgentInventory(counting, time.Minute))
+	now := time.Now()
+
+	// the heartbeat of an agent silent for longer than the absence period
+	require.NoError(t, r.StoreAgent(context.Background(), entity.Agent{Name: "web-1", LastSeen: now.Add(-2 * time.Minute), Metrics: 1}))
+
+	var value entity.Gauge = 1
+	alloc := entity.Metrics{ID: "Alloc", MType: Gauge, Value: &value}
+
+	// one heartbeat is recorded for a batch
+	ctx := WithAgent(context.Background(), entity.Agent{Name: "web-2", Host: "web-2.local"})
+	require.NoError(t, tool.StoreSeveralMetrics(ctx, []entity.Metrics{alloc, alloc}))
+	require.Equal(t, 1, counting.stored)
+
+	require.NoError(t, tool.StoreMetrics(ctx, alloc))
+	require.Equal(t, 2, counting.stored)
+
+	// the rejected metrics and the metrics without an agent are not recorded
+	ctx = WithAgent(context.Background(), entity.Agent{Name: "web-3"})
+	require.Error(t, tool.StoreMetrics(ctx, entity.Metrics{ID: "Alloc", MType: "summary", Value: &value}))
+	require.Error(t, tool.StoreSeveralMetrics(ctx, []entity.Metrics{alloc, {ID: "Alloc", MType: "summary", Value: &value}}))
+	require.NoError(t, tool.StoreMetrics(context.Background(), alloc))
+	require.Equal(t, 2, counting.stored)
+
+	agents, err := tool.GetAgents(context.Background())
+	require.NoError(t, err)
+	require.Len(t, agents, 2)
+
+	require.Equal(t, "web-1", agents[0].Name)
+	require.Equal(t, entity.AgentAbsent, agents[0].Status)
+	require.Equal(t, int64(1), agents[0].Metrics)
+
+	require.Equal(t, "web-2", agents[1].Name)
+	require.Equal(t, "web-2.local", agents[1].Host)
+	require.Equal(t, entity.AgentActive, agents[1].Status)
+	require.Equal(t, int64(3), agents[1].Metrics)
+	require.False(t, agents[1].LastSeen.Before(now))
+}
@@ -6,4 +6,5 @@ var (
 	ErrNotImplemented   = errors.New("not implemented")
 	ErrNotFound         = errors.New("not found")
 	ErrDataSignNotEqual = errors.New("data sign not equal")
+	ErrStoreModeChanged = errors.New("store mode cannot be changed without restart")
 )
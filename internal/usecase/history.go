@@ -0,0 +1,198 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/vladislaoramos/alemetric/internal/entity"
+)
+
+// Functions of counters computed from the raw history.
+const (
+	FnRate     = "rate"
+	FnIncrease = "increase"
+)
+
+const defaultHistoryPoints = 1000
+
+// RollupHistory rolls up the complete buckets of every resolution before the moment
+// from the previous resolution, then deletes the history older than the retention of its resolution.
+func (mt *ToolUseCase) RollupHistory(ctx context.Context, now time.Time) error {
+	if mt.history == nil {
+		return ErrNotImplemented
+	}
+
+	mt.rollupMu.Lock()
+	defer mt.rollupMu.Unlock()
+
+	if mt.rolledUp == nil {
+		mt.rolledUp = make(map[string]time.Time)
+	}
+
+	for i, resolution := range entity.Resolutions[1:] {
+		step := entity.ResolutionStep(resolution)
+		from, ok := mt.rolledUp[resolution]
+		if retention := mt.retention[entity.Resolutions[i]]; !ok && retention > 0 {
+			// the first bucket after the retention of the source is the first complete one
+			from = now.Add(-retention).Truncate(step).Add(step)
+		}
+
+		to := now.Truncate(step)
+		if !to.After(from) {
+			continue
+		}
+
+		if err := mt.history.Rollup(ctx, resolution, from, to); err != nil {
+			return fmt.Errorf("error rolling up history: %w", err)
+		}
+		mt.rolledUp[resolution] = to
+	}
+
+	for _, resolution := range entity.Resolutions {
+		retention, ok := mt.retention[resolution]
+		if !ok || retention <= 0 {
+			continue
+		}
+
+		if err := mt.history.DeleteHistory(ctx, resolution, now.Add(-retention)); err != nil {
+			return fmt.Errorf("error deleting history: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (mt *ToolUseCase) rollupHistory() {
+	ticker := time.NewTicker(mt.rollupInterval)
+	for {
+		<-ticker.C
+		if err := mt.RollupHistory(context.Background(), time.Now()); err != nil {
+			mt.logger.Error(fmt.Sprintf("error while rolling up history: %s", err))
+		}
+	}
+}
+
+// GetHistory gets the history of the metrics between from and to.
+// The resolution is the coarsest one with the step that fits the requested step;
+// without the step it is the range divided by the number of points.
+// If the history of the resolution is not kept since from, a coarser resolution is used.
+func (mt *ToolUseCase) GetHistory(
+	ctx context.Context, name string, from, to time.Time, step time.Duration, points int,
+) (entity.History, error) {
+	if mt.history == nil {
+		return entity.History{}, ErrNotImplemented
+	}
+
+	if points <= 0 {
+		points = defaultHistoryPoints
+	}
+	if step <= 0 {
+		step = to.Sub(from) / time.Duration(points)
+	}
+
+	name = entity.NormalizeID(name)
+	resolution := mt.pickResolution(from, time.Now(), step)
+	res, err := mt.history.GetHistory(ctx, name, resolution, from, to)
+	if err != nil {
+		return entity.History{}, fmt.Errorf("error getting history: %w", err)
+	}
+
+	for i := range res {
+		if res[i].Count > 0 {
+			res[i].Avg = res[i].Sum / float64(res[i].Count)
+		}
+	}
+
+	return entity.History{Name: name, Resolution: resolution, Points: res}, nil
+}
+
+func (mt *ToolUseCase) pickResolution(from, now time.Time, step time.Duration) string {
+	i := 0
+	for i+1 < len(entity.Resolutions) && entity.ResolutionStep(entity.Resolutions[i+1]) <= step {
+		i++
+	}
+
+	for ; i+1 < len(entity.Resolutions); i++ {
+		retention, ok := mt.retention[entity.Resolutions[i]]
+		if !ok || retention <= 0 || !from.Before(now.Add(-retention)) {
+			break
+		}
+	}
+
+	return entity.Resolutions[i]
+}
+
+// CounterFunc computes the function of the counter over the window ending now:
+// the increase of the counter or its per-second rate.
+// The increase is computed from the successive values of the counter in the raw history
+// starting from the last value before the window, if it is kept; the resets of the counter are detected.
+func (mt *ToolUseCase) CounterFunc(ctx context.Context, name, fn string, window time.Duration) (float64, error) {
+	if mt.history == nil || (fn != FnRate && fn != FnIncrease) || window <= 0 {
+		return 0, ErrNotImplemented
+	}
+
+	m, err := mt.GetMetrics(ctx, entity.Metrics{ID: name, MType: Counter})
+	if err != nil {
+		return 0, err
+	}
+	if m.MType != Counter {
+		return 0, ErrNotImplemented
+	}
+
+	res, err := mt.CounterFuncs(ctx, []string{name}, fn, window)
+	if err != nil {
+		return 0, err
+	}
+
+	return res[name], nil
+}
+
+// CounterFuncs computes the function of several counters over the window ending now like CounterFunc
+// getting their history at once. The counters are not checked; the function of a name without history is zero.
+func (mt *ToolUseCase) CounterFuncs(
+	ctx context.Context, names []string, fn string, window time.Duration,
+) (map[string]float64, error) {
+	if mt.history == nil || (fn != FnRate && fn != FnIncrease) || window <= 0 {
+		return nil, ErrNotImplemented
+	}
+
+	ids := make([]string, 0, len(names))
+	for _, name := range names {
+		ids = append(ids, entity.NormalizeID(name))
+	}
+
+	now := time.Now()
+	start := now.Add(-window)
+	history, err := mt.history.GetSeveralHistory(
+		ctx, ids, entity.ResolutionRaw, start.Add(-window), now.Add(time.Nanosecond))
+	if err != nil {
+		return nil, fmt.Errorf("error getting history: %w", err)
+	}
+
+	res := make(map[string]float64, len(names))
+	for i, name := range names {
+		increase := increaseSince(history[ids[i]], start)
+		if fn == FnRate {
+			res[name] = increase / window.Seconds()
+		} else {
+			res[name] = increase
+		}
+	}
+
+	return res, nil
+}
+
+// increaseSince computes the increase of the counter from its raw points
+// starting from the last value before the start.
+func increaseSince(points []entity.Point, start time.Time) float64 {
+	values := make([]float64, 0, len(points))
+	for i, p := range points {
+		if p.At.Before(start) && i+1 < len(points) && points[i+1].At.Before(start) {
+			continue
+		}
+		values = append(values, p.Last)
+	}
+
+	return entity.Increase(values)
+}
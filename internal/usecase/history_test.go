@@ -0,0 +1,159 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vladislaoramos/alemetric/internal/entity"
+	"github.com/vladislaoramos/alemetric/internal/repo"
+)
+
+func historyTool(t *testing.T, retention map[string]time.Duration) (*ToolUseCase, *repo.MetricsRepo) {
+	r, err := repo.NewMetricsRepo()
+	require.NoError(t, err)
+	return NewMetricsTool(r, testLogger(), History(r, 0, retention)), r
+}
+
+func TestStoreMetrics_History(t *testing.T) {
+	tool, r := historyTool(t, nil)
+	ctx := context.Background()
+
+	var (
+		delta entity.Counter = 5
+		value entity.Gauge   = 1.5
+	)
+	require.NoError(t, tool.StoreMetrics(ctx, entity.Metrics{ID: "PollCount", MType: Counter, Delta: &delta}))
+	require.NoError(t, tool.StoreMetrics(ctx, entity.Metrics{ID: "PollCount", MType: Counter, Delta: &delta}))
+	require.NoError(t, tool.StoreMetrics(ctx, entity.Metrics{ID: "Alloc", MType: Gauge, Value: &value}))
+
+	now := time.Now()
+	points, err := r.GetHistory(ctx, "PollCount", entity.ResolutionRaw, now.Add(-time.Minute), now)
+	require.NoError(t, err)
+	require.Len(t, points, 2)
+	require.Equal(t, 10.0, points[1].Last)
+	require.Equal(t, 5.0, points[1].Sum)
+
+	points, err = r.GetHistory(ctx, "Alloc", entity.ResolutionRaw, now.Add(-time.Minute), now)
+	require.NoError(t, err)
+	require.Len(t, points, 1)
+	require.Equal(t, 1.5, points[0].Last)
+}
+
+func TestRollupHistory(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Minute).Add(30 * time.Second)
+	retention := map[string]time.Duration{
+		entity.ResolutionRaw:    time.Hour,
+		entity.ResolutionMinute: 24 * time.Hour,
+	}
+	tool, r := historyTool(t, retention)
+
+	for _, s := range []entity.Sample{
+		{Name: "Alloc", MType: Gauge, Value: 1, At: now.Add(-2 * time.Hour)}, // out of the raw retention
+		{Name: "Alloc", MType: Gauge, Value: 2, At: now.Add(-90 * time.Second)},
+		{Name: "Alloc", MType: Gauge, Value: 4, At: now.Add(-80 * time.Second)},
+		{Name: "Alloc", MType: Gauge, Value: 8, At: now.Add(-10 * time.Second)}, // in the incomplete bucket
+	} {
+		require.NoError(t, r.StoreSample(ctx, s))
+	}
+
+	require.NoError(t, tool.RollupHistory(ctx, now))
+
+	minutes, err := r.GetHistory(ctx, "Alloc", entity.ResolutionMinute, now.Add(-3*time.Hour), now)
+	require.NoError(t, err)
+	require.Equal(t, []entity.Point{
+		{At: now.Truncate(time.Minute).Add(-time.Minute), Count: 2, Min: 2, Max: 4, Sum: 6, Last: 4},
+	}, minutes)
+
+	raw, err := r.GetHistory(ctx, "Alloc", entity.ResolutionRaw, now.Add(-3*time.Hour), now)
+	require.NoError(t, err)
+	require.Len(t, raw, 3)
+
+	// the next rollup completes the current bucket
+	require.NoError(t, tool.RollupHistory(ctx, now.Add(time.Minute)))
+	minutes, err = r.GetHistory(ctx, "Alloc", entity.ResolutionMinute, now.Add(-3*time.Hour), now.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, minutes, 2)
+	require.Equal(t, 8.0, minutes[1].Last)
+
+	history, err := tool.GetHistory(ctx, "Alloc", now.Add(-time.Hour), now.Add(time.Hour), time.Minute, 0)
+	require.NoError(t, err)
+	require.Equal(t, entity.ResolutionMinute, history.Resolution)
+	require.Equal(t, 3.0, history.Points[0].Avg)
+}
+
+func TestPickResolution(t *testing.T) {
+	now := time.Now()
+	tool := &ToolUseCase{retention: map[string]time.Duration{
+		entity.ResolutionRaw:    time.Hour,
+		entity.ResolutionMinute: 24 * time.Hour,
+	}}
+
+	tests := []struct {
+		name string
+		from time.Time
+		step time.Duration
+		want string
+	}{
+		{name: "fine step", from: now.Add(-time.Minute), step: time.Second, want: entity.ResolutionRaw},
+		{name: "step between resolutions", from: now.Add(-time.Minute), step: 30 * time.Minute, want: entity.ResolutionMinute},
+		{name: "coarse step", from: now.Add(-time.Minute), step: 7 * 24 * time.Hour, want: entity.ResolutionDay},
+		{name: "out of raw retention", from: now.Add(-2 * time.Hour), step: time.Second, want: entity.ResolutionMinute},
+		{name: "out of all retentions", from: now.Add(-48 * time.Hour), step: time.Second, want: entity.ResolutionHour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, tool.pickResolution(tt.from, now, tt.step))
+		})
+	}
+}
+
+func TestGetHistory_WithoutHistory(t *testing.T) {
+	tool, _ := metricsTool(t)
+	_, err := tool.GetHistory(context.Background(), "Alloc", time.Now().Add(-time.Hour), time.Now(), 0, 0)
+	require.ErrorIs(t, err, ErrNotImplemented)
+}
+
+func TestCounterFunc(t *testing.T) {
+	tool, r := historyTool(t, nil)
+	ctx := context.Background()
+
+	now := time.Now()
+	for _, s := range []entity.Sample{
+		{Name: "PollCount", MType: Counter, Value: 50, At: now.Add(-100 * time.Second)},
+		{Name: "PollCount", MType: Counter, Value: 100, At: now.Add(-90 * time.Second)}, // the base of the window
+		{Name: "PollCount", MType: Counter, Value: 110, At: now.Add(-30 * time.Second)},
+		{Name: "PollCount", MType: Counter, Value: 2, At: now.Add(-20 * time.Second)}, // the counter was reset
+	} {
+		require.NoError(t, r.StoreSample(ctx, s))
+	}
+
+	var delta entity.Counter = 5
+	require.NoError(t, tool.StoreMetrics(ctx, entity.Metrics{ID: "PollCount", MType: Counter, Delta: &delta}))
+
+	increase, err := tool.CounterFunc(ctx, "PollCount", FnIncrease, time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, 15.0, increase)
+
+	rate, err := tool.CounterFunc(ctx, "PollCount", FnRate, time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, 0.25, rate)
+
+	_, err = tool.CounterFunc(ctx, "Unknown", FnRate, time.Minute)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	rates, err := tool.CounterFuncs(ctx, []string{"PollCount", "Unknown"}, FnRate, time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, map[string]float64{"PollCount": 0.25, "Unknown": 0}, rates)
+
+	_, err = tool.CounterFunc(ctx, "PollCount", "avg", time.Minute)
+	require.ErrorIs(t, err, ErrNotImplemented)
+
+	var value entity.Gauge = 1
+	require.NoError(t, tool.StoreMetrics(ctx, entity.Metrics{ID: "Alloc", MType: Gauge, Value: &value}))
+	_, err = tool.CounterFunc(ctx, "Alloc", FnRate, time.Minute)
+	require.ErrorIs(t, err, ErrNotImplemented)
+}
@@ -2,6 +2,7 @@ package usecase
 
 import (
 	"context"
+	"time"
 
 	"github.com/vladislaoramos/alemetric/internal/entity"
 )
@@ -11,7 +12,14 @@ type MetricsTool interface {
 	GetMetricsNames(context.Context) ([]string, error)
 	StoreMetrics(context.Context, entity.Metrics) error
 	GetMetrics(context.Context, entity.Metrics) (entity.Metrics, error)
+	GetSeveralMetrics(context.Context, []entity.Metrics) ([]entity.Metrics, error)
+	ListMetrics(context.Context, entity.Filter) ([]entity.Metrics, error)
+	AggregateMetrics(context.Context, entity.Filter) (entity.Aggregate, error)
+	DeleteMetrics(context.Context, entity.Metrics, time.Time) error
+	ResetMetrics(context.Context, entity.Metrics, time.Time) (entity.Metrics, error)
 	PingRepo(context.Context) error
+	GetStaleMetrics(context.Context) ([]entity.Metrics, error)
+	EvictStaleMetrics(context.Context) ([]string, error)
 }
 
 // MetricsRepo defines the interface of interaction between the tool and the repository storage.
@@ -19,7 +27,43 @@ type MetricsRepo interface {
 	StoreMetrics(context.Context, entity.Metrics) error
 	GetMetrics(context.Context, string) (entity.Metrics, error)
 	GetMetricsNames(ctx context.Context) []string
+	GetSeveralMetrics(context.Context, []string) ([]entity.Metrics, error)
+	ListMetrics(context.Context, entity.Filter) ([]entity.Metrics, error)
+	AggregateMetrics(context.Context, entity.Filter) (entity.Aggregate, error)
+	DeleteMetrics(context.Context, string) error
 	StoreAll() error
 	Upload(context.Context) error
 	Ping(context.Context) error
+	GetStaleMetrics(context.Context, string, time.Time) ([]entity.Metrics, error)
+	DeleteStaleMetrics(context.Context, string, time.Time) ([]string, error)
+}
+
+// HistoryRepo defines the interface of interaction between the tool and the storage of the metrics history.
+// The raw history stores every accepted value; it is rolled up into the buckets of the coarser resolutions.
+type HistoryRepo interface {
+	StoreSample(context.Context, entity.Sample) error
+	Rollup(ctx context.Context, resolution string, from, to time.Time) error
+	DeleteHistory(ctx context.Context, resolution string, before time.Time) error
+	GetHistory(ctx context.Context, name, resolution string, from, to time.Time) ([]entity.Point, error)
+	GetSeveralHistory(
+		ctx context.Context, names []string, resolution string, from, to time.Time,
+	) (map[string][]entity.Point, error)
+}
+
+// AlertEngine defines the interface of interaction between the tool and the alerting rules engine.
+// The engine observes every metrics accepted by the tool.
+type AlertEngine interface {
+	Observe(entity.Metrics)
+	Alerts() []entity.Alert
+}
+
+// AgentRepo defines the interface of interaction between the tool and the storage of the heartbeats of the agents.
+type AgentRepo interface {
+	StoreAgent(context.Context, entity.Agent) error
+	GetAgents(context.Context) ([]entity.Agent, error)
+}
+
+// AgentConfigSource defines the interface of interaction between the tool and the configurations served to the agents.
+type AgentConfigSource interface {
+	Config(name, group string) entity.AgentConfig
 }
@@ -4,6 +4,7 @@ package mocks
 
 import (
 	context "context"
+	time "time"
 
 	mock "github.com/stretchr/testify/mock"
 	entity "github.com/vladislaoramos/alemetric/internal/entity"
@@ -22,6 +23,137 @@ func (_m *MetricsRepo) EXPECT() *MetricsRepo_Expecter {
 	return &MetricsRepo_Expecter{mock: &_m.Mock}
 }
 
+// AggregateMetrics provides a mock function with given fields: _a0, _a1
+func (_m *MetricsRepo) AggregateMetrics(_a0 context.Context, _a1 entity.Filter) (entity.Aggregate, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 entity.Aggregate
+	if rf, ok := ret.Get(0).(func(context.Context, entity.Filter) entity.Aggregate); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Get(0).(entity.Aggregate)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, entity.Filter) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MetricsRepo_AggregateMetrics_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AggregateMetrics'
+type MetricsRepo_AggregateMetrics_Call struct {
+	*mock.Call
+}
+
+// AggregateMetrics is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 entity.Filter
+func (_e *MetricsRepo_Expecter) AggregateMetrics(_a0 interface{}, _a1 interface{}) *MetricsRepo_AggregateMetrics_Call {
+	return &MetricsRepo_AggregateMetrics_Call{Call: _e.mock.On("AggregateMetrics", _a0, _a1)}
+}
+
+func (_c *MetricsRepo_AggregateMetrics_Call) Run(run func(_a0 context.Context, _a1 entity.Filter)) *MetricsRepo_AggregateMetrics_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(entity.Filter))
+	})
+	return _c
+}
+
+func (_c *MetricsRepo_AggregateMetrics_Call) Return(_a0 entity.Aggregate, _a1 error) *MetricsRepo_AggregateMetrics_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+// DeleteMetrics provides a mock function with given fields: _a0, _a1
+func (_m *MetricsRepo) DeleteMetrics(_a0 context.Context, _a1 string) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MetricsRepo_DeleteMetrics_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteMetrics'
+type MetricsRepo_DeleteMetrics_Call struct {
+	*mock.Call
+}
+
+// DeleteMetrics is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 string
+func (_e *MetricsRepo_Expecter) DeleteMetrics(_a0 interface{}, _a1 interface{}) *MetricsRepo_DeleteMetrics_Call {
+	return &MetricsRepo_DeleteMetrics_Call{Call: _e.mock.On("DeleteMetrics", _a0, _a1)}
+}
+
+func (_c *MetricsRepo_DeleteMetrics_Call) Run(run func(_a0 context.Context, _a1 string)) *MetricsRepo_DeleteMetrics_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *MetricsRepo_DeleteMetrics_Call) Return(_a0 error) *MetricsRepo_DeleteMetrics_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+// DeleteStaleMetrics provides a mock function with given fields: _a0, _a1, _a2
+func (_m *MetricsRepo) DeleteStaleMetrics(_a0 context.Context, _a1 string, _a2 time.Time) ([]string, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) []string); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, time.Time) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MetricsRepo_DeleteStaleMetrics_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteStaleMetrics'
+type MetricsRepo_DeleteStaleMetrics_Call struct {
+	*mock.Call
+}
+
+// DeleteStaleMetrics is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 string
+//   - _a2 time.Time
+func (_e *MetricsRepo_Expecter) DeleteStaleMetrics(_a0 interface{}, _a1 interface{}, _a2 interface{}) *MetricsRepo_DeleteStaleMetrics_Call {
+	return &MetricsRepo_DeleteStaleMetrics_Call{Call: _e.mock.On("DeleteStaleMetrics", _a0, _a1, _a2)}
+}
+
+func (_c *MetricsRepo_DeleteStaleMetrics_Call) Run(run func(_a0 context.Context, _a1 string, _a2 time.Time)) *MetricsRepo_DeleteStaleMetrics_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(time.Time))
+	})
+	return _c
+}
+
+func (_c *MetricsRepo_DeleteStaleMetrics_Call) Return(_a0 []string, _a1 error) *MetricsRepo_DeleteStaleMetrics_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
 // GetMetrics provides a mock function with given fields: _a0, _a1
 func (_m *MetricsRepo) GetMetrics(_a0 context.Context, _a1 string) (entity.Metrics, error) {
 	ret := _m.Called(_a0, _a1)
@@ -106,6 +238,148 @@ func (_c *MetricsRepo_GetMetricsNames_Call) Return(_a0 []string) *MetricsRepo_Ge
 	return _c
 }
 
+// GetSeveralMetrics provides a mock function with given fields: _a0, _a1
+func (_m *MetricsRepo) GetSeveralMetrics(_a0 context.Context, _a1 []string) ([]entity.Metrics, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 []entity.Metrics
+	if rf, ok := ret.Get(0).(func(context.Context, []string) []entity.Metrics); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.Metrics)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MetricsRepo_GetSeveralMetrics_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetSeveralMetrics'
+type MetricsRepo_GetSeveralMetrics_Call struct {
+	*mock.Call
+}
+
+// GetSeveralMetrics is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 []string
+func (_e *MetricsRepo_Expecter) GetSeveralMetrics(_a0 interface{}, _a1 interface{}) *MetricsRepo_GetSeveralMetrics_Call {
+	return &MetricsRepo_GetSeveralMetrics_Call{Call: _e.mock.On("GetSeveralMetrics", _a0, _a1)}
+}
+
+func (_c *MetricsRepo_GetSeveralMetrics_Call) Run(run func(_a0 context.Context, _a1 []string)) *MetricsRepo_GetSeveralMetrics_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].([]string))
+	})
+	return _c
+}
+
+func (_c *MetricsRepo_GetSeveralMetrics_Call) Return(_a0 []entity.Metrics, _a1 error) *MetricsRepo_GetSeveralMetrics_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+// GetStaleMetrics provides a mock function with given fields: _a0, _a1, _a2
+func (_m *MetricsRepo) GetStaleMetrics(_a0 context.Context, _a1 string, _a2 time.Time) ([]entity.Metrics, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 []entity.Metrics
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) []entity.Metrics); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.Metrics)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, time.Time) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MetricsRepo_GetStaleMetrics_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetStaleMetrics'
+type MetricsRepo_GetStaleMetrics_Call struct {
+	*mock.Call
+}
+
+// GetStaleMetrics is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 string
+//   - _a2 time.Time
+func (_e *MetricsRepo_Expecter) GetStaleMetrics(_a0 interface{}, _a1 interface{}, _a2 interface{}) *MetricsRepo_GetStaleMetrics_Call {
+	return &MetricsRepo_GetStaleMetrics_Call{Call: _e.mock.On("GetStaleMetrics", _a0, _a1, _a2)}
+}
+
+func (_c *MetricsRepo_GetStaleMetrics_Call) Run(run func(_a0 context.Context, _a1 string, _a2 time.Time)) *MetricsRepo_GetStaleMetrics_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(time.Time))
+	})
+	return _c
+}
+
+func (_c *MetricsRepo_GetStaleMetrics_Call) Return(_a0 []entity.Metrics, _a1 error) *MetricsRepo_GetStaleMetrics_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+// ListMetrics provides a mock function with given fields: _a0, _a1
+func (_m *MetricsRepo) ListMetrics(_a0 context.Context, _a1 entity.Filter) ([]entity.Metrics, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 []entity.Metrics
+	if rf, ok := ret.Get(0).(func(context.Context, entity.Filter) []entity.Metrics); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.Metrics)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, entity.Filter) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MetricsRepo_ListMetrics_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListMetrics'
+type MetricsRepo_ListMetrics_Call struct {
+	*mock.Call
+}
+
+// ListMetrics is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 entity.Filter
+func (_e *MetricsRepo_Expecter) ListMetrics(_a0 interface{}, _a1 interface{}) *MetricsRepo_ListMetrics_Call {
+	return &MetricsRepo_ListMetrics_Call{Call: _e.mock.On("ListMetrics", _a0, _a1)}
+}
+
+func (_c *MetricsRepo_ListMetrics_Call) Run(run func(_a0 context.Context, _a1 entity.Filter)) *MetricsRepo_ListMetrics_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(entity.Filter))
+	})
+	return _c
+}
+
+func (_c *MetricsRepo_ListMetrics_Call) Return(_a0 []entity.Metrics, _a1 error) *MetricsRepo_ListMetrics_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
 // Ping provides a mock function with given fields: _a0
 func (_m *MetricsRepo) Ping(_a0 context.Context) error {
 	ret := _m.Called(_a0)
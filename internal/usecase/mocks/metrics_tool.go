@@ -4,6 +4,7 @@ package mocks
 
 import (
 	context "context"
+	time "time"
 
 	mock "github.com/stretchr/testify/mock"
 	entity "github.com/vladislaoramos/alemetric/internal/entity"
@@ -22,6 +23,136 @@ func (_m *MetricsTool) EXPECT() *MetricsTool_Expecter {
 	return &MetricsTool_Expecter{mock: &_m.Mock}
 }
 
+// AggregateMetrics provides a mock function with given fields: _a0, _a1
+func (_m *MetricsTool) AggregateMetrics(_a0 context.Context, _a1 entity.Filter) (entity.Aggregate, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 entity.Aggregate
+	if rf, ok := ret.Get(0).(func(context.Context, entity.Filter) entity.Aggregate); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Get(0).(entity.Aggregate)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, entity.Filter) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MetricsTool_AggregateMetrics_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AggregateMetrics'
+type MetricsTool_AggregateMetrics_Call struct {
+	*mock.Call
+}
+
+// AggregateMetrics is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 entity.Filter
+func (_e *MetricsTool_Expecter) AggregateMetrics(_a0 interface{}, _a1 interface{}) *MetricsTool_AggregateMetrics_Call {
+	return &MetricsTool_AggregateMetrics_Call{Call: _e.mock.On("AggregateMetrics", _a0, _a1)}
+}
+
+func (_c *MetricsTool_AggregateMetrics_Call) Run(run func(_a0 context.Context, _a1 entity.Filter)) *MetricsTool_AggregateMetrics_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(entity.Filter))
+	})
+	return _c
+}
+
+func (_c *MetricsTool_AggregateMetrics_Call) Return(_a0 entity.Aggregate, _a1 error) *MetricsTool_AggregateMetrics_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+// DeleteMetrics provides a mock function with given fields: _a0, _a1, _a2
+func (_m *MetricsTool) DeleteMetrics(_a0 context.Context, _a1 entity.Metrics, _a2 time.Time) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.Metrics, time.Time) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MetricsTool_DeleteMetrics_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteMetrics'
+type MetricsTool_DeleteMetrics_Call struct {
+	*mock.Call
+}
+
+// DeleteMetrics is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 entity.Metrics
+//   - _a2 time.Time
+func (_e *MetricsTool_Expecter) DeleteMetrics(_a0 interface{}, _a1 interface{}, _a2 interface{}) *MetricsTool_DeleteMetrics_Call {
+	return &MetricsTool_DeleteMetrics_Call{Call: _e.mock.On("DeleteMetrics", _a0, _a1, _a2)}
+}
+
+func (_c *MetricsTool_DeleteMetrics_Call) Run(run func(_a0 context.Context, _a1 entity.Metrics, _a2 time.Time)) *MetricsTool_DeleteMetrics_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(entity.Metrics), args[2].(time.Time))
+	})
+	return _c
+}
+
+func (_c *MetricsTool_DeleteMetrics_Call) Return(_a0 error) *MetricsTool_DeleteMetrics_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+// EvictStaleMetrics provides a mock function with given fields: _a0
+func (_m *MetricsTool) EvictStaleMetrics(_a0 context.Context) ([]string, error) {
+	ret := _m.Called(_a0)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(context.Context) []string); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MetricsTool_EvictStaleMetrics_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'EvictStaleMetrics'
+type MetricsTool_EvictStaleMetrics_Call struct {
+	*mock.Call
+}
+
+// EvictStaleMetrics is a helper method to define mock.On call
+//   - _a0 context.Context
+func (_e *MetricsTool_Expecter) EvictStaleMetrics(_a0 interface{}) *MetricsTool_EvictStaleMetrics_Call {
+	return &MetricsTool_EvictStaleMetrics_Call{Call: _e.mock.On("EvictStaleMetrics", _a0)}
+}
+
+func (_c *MetricsTool_EvictStaleMetrics_Call) Run(run func(_a0 context.Context)) *MetricsTool_EvictStaleMetrics_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *MetricsTool_EvictStaleMetrics_Call) Return(_a0 []string, _a1 error) *MetricsTool_EvictStaleMetrics_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
 // GetMetrics provides a mock function with given fields: _a0, _a1
 func (_m *MetricsTool) GetMetrics(_a0 context.Context, _a1 entity.Metrics) (entity.Metrics, error) {
 	ret := _m.Called(_a0, _a1)
@@ -113,6 +244,146 @@ func (_c *MetricsTool_GetMetricsNames_Call) Return(_a0 []string, _a1 error) *Met
 	return _c
 }
 
+// GetSeveralMetrics provides a mock function with given fields: _a0, _a1
+func (_m *MetricsTool) GetSeveralMetrics(_a0 context.Context, _a1 []entity.Metrics) ([]entity.Metrics, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 []entity.Metrics
+	if rf, ok := ret.Get(0).(func(context.Context, []entity.Metrics) []entity.Metrics); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.Metrics)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, []entity.Metrics) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MetricsTool_GetSeveralMetrics_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetSeveralMetrics'
+type MetricsTool_GetSeveralMetrics_Call struct {
+	*mock.Call
+}
+
+// GetSeveralMetrics is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 []entity.Metrics
+func (_e *MetricsTool_Expecter) GetSeveralMetrics(_a0 interface{}, _a1 interface{}) *MetricsTool_GetSeveralMetrics_Call {
+	return &MetricsTool_GetSeveralMetrics_Call{Call: _e.mock.On("GetSeveralMetrics", _a0, _a1)}
+}
+
+func (_c *MetricsTool_GetSeveralMetrics_Call) Run(run func(_a0 context.Context, _a1 []entity.Metrics)) *MetricsTool_GetSeveralMetrics_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].([]entity.Metrics))
+	})
+	return _c
+}
+
+func (_c *MetricsTool_GetSeveralMetrics_Call) Return(_a0 []entity.Metrics, _a1 error) *MetricsTool_GetSeveralMetrics_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+// GetStaleMetrics provides a mock function with given fields: _a0
+func (_m *MetricsTool) GetStaleMetrics(_a0 context.Context) ([]entity.Metrics, error) {
+	ret := _m.Called(_a0)
+
+	var r0 []entity.Metrics
+	if rf, ok := ret.Get(0).(func(context.Context) []entity.Metrics); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.Metrics)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MetricsTool_GetStaleMetrics_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetStaleMetrics'
+type MetricsTool_GetStaleMetrics_Call struct {
+	*mock.Call
+}
+
+// GetStaleMetrics is a helper method to define mock.On call
+//   - _a0 context.Context
+func (_e *MetricsTool_Expecter) GetStaleMetrics(_a0 interface{}) *MetricsTool_GetStaleMetrics_Call {
+	return &MetricsTool_GetStaleMetrics_Call{Call: _e.mock.On("GetStaleMetrics", _a0)}
+}
+
+func (_c *MetricsTool_GetStaleMetrics_Call) Run(run func(_a0 context.Context)) *MetricsTool_GetStaleMetrics_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *MetricsTool_GetStaleMetrics_Call) Return(_a0 []entity.Metrics, _a1 error) *MetricsTool_GetStaleMetrics_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+// ListMetrics provides a mock function with given fields: _a0, _a1
+func (_m *MetricsTool) ListMetrics(_a0 context.Context, _a1 entity.Filter) ([]entity.Metrics, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 []entity.Metrics
+	if rf, ok := ret.Get(0).(func(context.Context, entity.Filter) []entity.Metrics); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.Metrics)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, entity.Filter) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MetricsTool_ListMetrics_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListMetrics'
+type MetricsTool_ListMetrics_Call struct {
+	*mock.Call
+}
+
+// ListMetrics is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 entity.Filter
+func (_e *MetricsTool_Expecter) ListMetrics(_a0 interface{}, _a1 interface{}) *MetricsTool_ListMetrics_Call {
+	return &MetricsTool_ListMetrics_Call{Call: _e.mock.On("ListMetrics", _a0, _a1)}
+}
+
+func (_c *MetricsTool_ListMetrics_Call) Run(run func(_a0 context.Context, _a1 entity.Filter)) *MetricsTool_ListMetrics_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(entity.Filter))
+	})
+	return _c
+}
+
+func (_c *MetricsTool_ListMetrics_Call) Return(_a0 []entity.Metrics, _a1 error) *MetricsTool_ListMetrics_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
 // PingRepo provides a mock function with given fields: _a0
 func (_m *MetricsTool) PingRepo(_a0 context.Context) error {
 	ret := _m.Called(_a0)
@@ -150,6 +421,52 @@ func (_c *MetricsTool_PingRepo_Call) Return(_a0 error) *MetricsTool_PingRepo_Cal
 	return _c
 }
 
+// ResetMetrics provides a mock function with given fields: _a0, _a1, _a2
+func (_m *MetricsTool) ResetMetrics(_a0 context.Context, _a1 entity.Metrics, _a2 time.Time) (entity.Metrics, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 entity.Metrics
+	if rf, ok := ret.Get(0).(func(context.Context, entity.Metrics, time.Time) entity.Metrics); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Get(0).(entity.Metrics)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, entity.Metrics, time.Time) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MetricsTool_ResetMetrics_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ResetMetrics'
+type MetricsTool_ResetMetrics_Call struct {
+	*mock.Call
+}
+
+// ResetMetrics is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 entity.Metrics
+//   - _a2 time.Time
+func (_e *MetricsTool_Expecter) ResetMetrics(_a0 interface{}, _a1 interface{}, _a2 interface{}) *MetricsTool_ResetMetrics_Call {
+	return &MetricsTool_ResetMetrics_Call{Call: _e.mock.On("ResetMetrics", _a0, _a1, _a2)}
+}
+
+func (_c *MetricsTool_ResetMetrics_Call) Run(run func(_a0 context.Context, _a1 entity.Metrics, _a2 time.Time)) *MetricsTool_ResetMetrics_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(entity.Metrics), args[2].(time.Time))
+	})
+	return _c
+}
+
+func (_c *MetricsTool_ResetMetrics_Call) Return(_a0 entity.Metrics, _a1 error) *MetricsTool_ResetMetrics_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
 // StoreMetrics provides a mock function with given fields: _a0, _a1
 func (_m *MetricsTool) StoreMetrics(_a0 context.Context, _a1 entity.Metrics) error {
 	ret := _m.Called(_a0, _a1)
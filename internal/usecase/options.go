@@ -26,3 +26,57 @@ func CheckDataSign(key string) OptionFunc {
 		mt.checkDataSign = true
 	}
 }
+
+// EvictStaleMetrics sets the eviction of the metrics that have not been updated
+// for longer than the TTL of their type. The metrics types without TTL are never evicted.
+// The stale metrics are searched for and evicted every interval.
+func EvictStaleMetrics(interval time.Duration, ttls map[string]time.Duration) OptionFunc {
+	return func(mt *ToolUseCase) {
+		mt.sweepInterval = interval
+		mt.ttls = ttls
+	}
+}
+
+// StreamBuffer sets the number of metrics buffered for each subscription.
+// The size less than one is ignored: a subscription buffers at least one metrics.
+func StreamBuffer(size int) OptionFunc {
+	return func(mt *ToolUseCase) {
+		if size < 1 {
+			return
+		}
+		mt.streamBuffer = size
+	}
+}
+
+// Alerting sets the engine evaluating the alerting rules against the accepted metrics.
+func Alerting(engine AlertEngine) OptionFunc {
+	return func(mt *ToolUseCase) {
+		mt.alerts = engine
+	}
+}
+
+// History sets the storing of the metrics history with rollups into the coarser resolutions every interval.
+// The history of each resolution is kept for its retention; a resolution without retention is kept forever.
+func History(h HistoryRepo, interval time.Duration, retention map[string]time.Duration) OptionFunc {
+	return func(mt *ToolUseCase) {
+		mt.history = h
+		mt.rollupInterval = interval
+		mt.retention = retention
+	}
+}
+
+// AgentInventory sets the storing of the heartbeats of the agents sending metrics.
+// An agent silent for longer than absentAfter is reported as absent.
+func AgentInventory(a AgentRepo, absentAfter time.Duration) OptionFunc {
+	return func(mt *ToolUseCase) {
+		mt.agentRepo = a
+		mt.absentAfter = absentAfter
+	}
+}
+
+// ServeAgentConfigs sets the source of the configurations served to the agents.
+func ServeAgentConfigs(src AgentConfigSource) OptionFunc {
+	return func(mt *ToolUseCase) {
+		mt.agentConfigs = src
+	}
+}
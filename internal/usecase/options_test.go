@@ -5,6 +5,9 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/require"
+	"github.com/vladislaoramos/alemetric/internal/alert"
+	"github.com/vladislaoramos/alemetric/internal/entity"
+	"github.com/vladislaoramos/alemetric/internal/repo"
 )
 
 func TestWriteFileWithDuration(t *testing.T) {
@@ -31,3 +34,46 @@ func TestCheckDataSign(t *testing.T) {
 	require.Equal(t, "string", mt.encryptionKey)
 	require.True(t, mt.checkDataSign)
 }
+
+func TestEvictStaleMetrics(t *testing.T) {
+	mt := &ToolUseCase{}
+	ttls := map[string]time.Duration{Gauge: time.Hour}
+	op := EvictStaleMetrics(time.Minute, ttls)
+	op(mt)
+	require.Equal(t, time.Minute, mt.sweepInterval)
+	require.Equal(t, ttls, mt.ttls)
+}
+
+func TestStreamBuffer(t *testing.T) {
+	mt := &ToolUseCase{}
+	op := StreamBuffer(10)
+	op(mt)
+	require.Equal(t, 10, mt.streamBuffer)
+
+	for _, size := range []int{0, -1} {
+		StreamBuffer(size)(mt)
+		require.Equal(t, 10, mt.streamBuffer)
+	}
+}
+
+func TestAlerting(t *testing.T) {
+	mt := &ToolUseCase{}
+	require.Empty(t, mt.GetAlerts())
+
+	engine := alert.NewEngine(nil)
+	op := Alerting(engine)
+	op(mt)
+	require.Equal(t, engine, mt.alerts)
+}
+
+func TestHistory(t *testing.T) {
+	mt := &ToolUseCase{}
+	h, err := repo.NewMetricsRepo()
+	require.NoError(t, err)
+	retention := map[string]time.Duration{entity.ResolutionRaw: time.Hour}
+	op := History(h, time.Minute, retention)
+	op(mt)
+	require.Equal(t, h, mt.history)
+	require.Equal(t, time.Minute, mt.rollupInterval)
+	require.Equal(t, retention, mt.retention)
+}
@@ -0,0 +1,99 @@
+package usecase
+
+import (
+	"sync"
+
+	"github.com/vladislaoramos/alemetric/internal/entity"
+)
+
+const defaultStreamBuffer = 100
+
+// Subscription receives the metrics accepted by the tool that satisfy its filter.
+// Each subscription has its own buffer; when the buffer is full, the oldest metrics are dropped.
+type Subscription struct {
+	filter  entity.Filter
+	events  chan entity.Metrics
+	mu      sync.Mutex
+	dropped int
+}
+
+// Events returns the channel of the accepted metrics.
+// The channel is closed when the subscription is cancelled.
+func (s *Subscription) Events() <-chan entity.Metrics {
+	return s.events
+}
+
+// Dropped returns the number of metrics dropped since the previous call.
+func (s *Subscription) Dropped() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := s.dropped
+	s.dropped = 0
+	return n
+}
+
+func (s *Subscription) publish(m entity.Metrics) {
+	if !s.filter.Match(m) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for {
+		select {
+		case s.events <- m:
+			return
+		default:
+		}
+
+		select {
+		case <-s.events:
+			s.dropped++
+		default:
+		}
+	}
+}
+
+// broker delivers the accepted metrics to all subscriptions.
+type broker struct {
+	mu            sync.RWMutex
+	bufferSize    int
+	subscriptions map[*Subscription]struct{}
+}
+
+func newBroker(bufferSize int) *broker {
+	return &broker{
+		bufferSize:    bufferSize,
+		subscriptions: make(map[*Subscription]struct{}),
+	}
+}
+
+func (b *broker) subscribe(filter entity.Filter) *Subscription {
+	s := &Subscription{
+		filter: filter,
+		events: make(chan entity.Metrics, b.bufferSize),
+	}
+
+	b.mu.Lock()
+	b.subscriptions[s] = struct{}{}
+	b.mu.Unlock()
+
+	return s
+}
+
+func (b *broker) unsubscribe(s *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subscriptions[s]; ok {
+		delete(b.subscriptions, s)
+		close(s.events)
+	}
+}
+
+func (b *broker) publish(m entity.Metrics) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for s := range b.subscriptions {
+		s.publish(m)
+	}
+}
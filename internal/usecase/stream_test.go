@@ -0,0 +1,55 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vladislaoramos/alemetric/internal/entity"
+)
+
+func TestBroker(t *testing.T) {
+	b := newBroker(2)
+	all := b.subscribe(entity.Filter{})
+	counters := b.subscribe(entity.Filter{Type: Counter})
+
+	for _, id := range []string{"a", "b", "c"} {
+		b.publish(entity.Metrics{ID: id, MType: Gauge})
+	}
+
+	// the oldest metrics is dropped
+	require.Equal(t, 1, all.Dropped())
+	require.Equal(t, 0, all.Dropped())
+	require.Equal(t, "b", (<-all.Events()).ID)
+	require.Equal(t, "c", (<-all.Events()).ID)
+
+	require.Empty(t, counters.Events())
+
+	b.unsubscribe(all)
+	_, ok := <-all.Events()
+	require.False(t, ok)
+
+	b.publish(entity.Metrics{ID: "d", MType: Counter})
+	require.Equal(t, "d", (<-counters.Events()).ID)
+}
+
+func TestSubscribe(t *testing.T) {
+	tool, repoMock := metricsTool(t)
+	ctx := context.Background()
+
+	sub := tool.Subscribe(entity.Filter{Glob: "Heap*"})
+	defer tool.Unsubscribe(sub)
+
+	var value entity.Gauge = 1
+	for _, id := range []string{"Alloc", "HeapAlloc"} {
+		metrics := entity.Metrics{ID: id, MType: Gauge, Value: &value}
+		repoMock.On("StoreMetrics", ctx, metrics).Return(nil)
+		err := tool.StoreMetrics(ctx, metrics)
+		require.NoError(t, err)
+	}
+
+	require.Len(t, sub.Events(), 1)
+	m := <-sub.Events()
+	require.Equal(t, "HeapAlloc", m.ID)
+	require.NotNil(t, m.Updated)
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/vladislaoramos/alemetric/internal/entity"
@@ -14,6 +17,12 @@ import (
 const (
 	Counter = "counter"
 	Gauge   = "gauge"
+
+	ActionDelete = "delete"
+	ActionReset  = "reset"
+
+	// ActionSignWindow is the maximal difference between the time an action is signed at and the time it is made.
+	ActionSignWindow = time.Minute
 )
 
 // ToolUseCase stores the tool object.
@@ -23,27 +32,54 @@ type ToolUseCase struct {
 
 	writeFileDuration       time.Duration
 	writeToFileWithDuration bool
+	storeTicker             *time.Ticker
 	syncWriteFile           bool
 	asyncWriteFile          bool
 	C                       chan struct{}
 
+	keyMu         sync.RWMutex
 	checkDataSign bool
 	encryptionKey string
+
+	// usedSigns stores the signs of the actions made within the sign window with the times they are signed at.
+	signsMu   sync.Mutex
+	usedSigns map[string]time.Time
+
+	sweepInterval time.Duration
+	ttls          map[string]time.Duration
+
+	streamBuffer int
+	broker       *broker
+
+	alerts AlertEngine
+
+	history        HistoryRepo
+	rollupInterval time.Duration
+	retention      map[string]time.Duration
+	rollupMu       sync.Mutex
+	rolledUp       map[string]time.Time
+
+	agentRepo   AgentRepo
+	absentAfter time.Duration
+
+	agentConfigs AgentConfigSource
 }
 
 // NewMetricsTool creates a tool object.
 func NewMetricsTool(repo MetricsRepo, l logger.LogInterface, options ...OptionFunc) *ToolUseCase {
-	useCase := &ToolUseCase{repo: repo, logger: l}
+	useCase := &ToolUseCase{repo: repo, logger: l, streamBuffer: defaultStreamBuffer}
 
 	for _, o := range options {
 		o(useCase)
 	}
 
+	useCase.broker = newBroker(useCase.streamBuffer)
+
 	if useCase.writeToFileWithDuration {
+		useCase.storeTicker = time.NewTicker(useCase.writeFileDuration)
 		go func() {
-			ticker := time.NewTicker(useCase.writeFileDuration)
 			for {
-				<-ticker.C
+				<-useCase.storeTicker.C
 				useCase.C <- struct{}{}
 			}
 		}()
@@ -54,6 +90,14 @@ func NewMetricsTool(repo MetricsRepo, l logger.LogInterface, options ...OptionFu
 		go useCase.saveStorage()
 	}
 
+	if useCase.sweepInterval > 0 && len(useCase.ttls) > 0 {
+		go useCase.sweepStaleMetrics()
+	}
+
+	if useCase.history != nil && useCase.rollupInterval > 0 {
+		go useCase.rollupHistory()
+	}
+
 	return useCase
 }
 
@@ -69,6 +113,34 @@ func (mt *ToolUseCase) saveStorage() {
 	}
 }
 
+// SetStoreInterval changes the interval of writing the metrics to the file.
+// The synchronous writing cannot be changed to the periodic one and back without the restart.
+func (mt *ToolUseCase) SetStoreInterval(interval time.Duration) error {
+	if !mt.writeToFileWithDuration || interval <= 0 {
+		return ErrStoreModeChanged
+	}
+
+	mt.storeTicker.Reset(interval)
+	return nil
+}
+
+// SetDataSignKey changes the key of the data signing.
+// With the empty key the data is neither signed nor checked.
+func (mt *ToolUseCase) SetDataSignKey(key string) {
+	mt.keyMu.Lock()
+	defer mt.keyMu.Unlock()
+
+	mt.encryptionKey = key
+	mt.checkDataSign = key != ""
+}
+
+func (mt *ToolUseCase) dataSignKey() (string, bool) {
+	mt.keyMu.RLock()
+	defer mt.keyMu.RUnlock()
+
+	return mt.encryptionKey, mt.checkDataSign
+}
+
 // GetMetricsNames gets all metrics names from the tool.
 func (mt *ToolUseCase) GetMetricsNames(ctx context.Context) ([]string, error) {
 	names := mt.repo.GetMetricsNames(ctx)
@@ -76,13 +148,49 @@ func (mt *ToolUseCase) GetMetricsNames(ctx context.Context) ([]string, error) {
 }
 
 // StoreMetrics stores a metrics into the tool.
+// The labels of the metrics ID are normalized, so that the same labels always make the same ID.
 func (mt *ToolUseCase) StoreMetrics(ctx context.Context, metrics entity.Metrics) error {
-	if mt.checkDataSign && !metrics.CheckDataSign(mt.encryptionKey) {
+	if err := mt.storeMetrics(ctx, metrics); err != nil {
+		return err
+	}
+
+	mt.agentSeen(ctx, time.Now(), 1)
+	return nil
+}
+
+// StoreSeveralMetrics stores several metrics into the tool like StoreMetrics, stopping at the first error.
+func (mt *ToolUseCase) StoreSeveralMetrics(ctx context.Context, items []entity.Metrics) error {
+	for _, metrics := range items {
+		if err := mt.storeMetrics(ctx, metrics); err != nil {
+			return err
+		}
+	}
+
+	mt.agentSeen(ctx, time.Now(), len(items))
+	return nil
+}
+
+func (mt *ToolUseCase) storeMetrics(ctx context.Context, metrics entity.Metrics) error {
+	key, check := mt.dataSignKey()
+	if check && !metrics.CheckDataSign(key) {
 		return ErrDataSignNotEqual
 	}
 
+	if id := entity.NormalizeID(metrics.ID); id != metrics.ID {
+		metrics.ID, metrics.Hash = id, ""
+		if metrics.MType == Gauge && metrics.Value != nil {
+			metrics.SignData("server", key)
+		}
+	}
+
+	sample := entity.Sample{Name: metrics.ID, MType: metrics.MType}
+
 	switch metrics.MType {
 	case Gauge:
+		if metrics.Value != nil {
+			sample.Value = float64(*metrics.Value)
+		}
+
 		if err := mt.repo.StoreMetrics(ctx, metrics); err != nil {
 			if errors.Is(err, repo.ErrNotFound) {
 				return fmt.Errorf("store metrics: %w", ErrNotFound)
@@ -90,6 +198,8 @@ func (mt *ToolUseCase) StoreMetrics(ctx context.Context, metrics entity.Metrics)
 			return fmt.Errorf("error store metrics: %w", err)
 		}
 	case Counter:
+		sample.Delta = float64(*metrics.Delta)
+
 		oldMetric, err := mt.repo.GetMetrics(ctx, metrics.ID)
 		if err != nil && !errors.Is(err, repo.ErrNotFound) {
 			return fmt.Errorf("error getting metrics: %w", err)
@@ -102,7 +212,8 @@ func (mt *ToolUseCase) StoreMetrics(ctx context.Context, metrics entity.Metrics)
 			metrics.Delta = &delta
 		}
 
-		metrics.SignData("server", mt.encryptionKey)
+		sample.Value = float64(*metrics.Delta)
+		metrics.SignData("server", key)
 
 		if err := mt.repo.StoreMetrics(ctx, metrics); err != nil {
 			return fmt.Errorf("error storing metrics: %w", err)
@@ -111,6 +222,49 @@ func (mt *ToolUseCase) StoreMetrics(ctx context.Context, metrics entity.Metrics)
 	default:
 		return ErrNotImplemented
 	}
+
+	now := time.Now()
+	if mt.broker != nil {
+		metrics.Updated = &now
+		mt.broker.publish(metrics)
+	}
+
+	if mt.history != nil {
+		sample.At = now
+		if err := mt.history.StoreSample(ctx, sample); err != nil {
+			mt.logger.Error(fmt.Sprintf("error storing sample of metrics %s: %s", metrics.ID, err))
+		}
+	}
+
+	if mt.alerts != nil {
+		mt.alerts.Observe(metrics)
+	}
+
+	return mt.saveChanges()
+}
+
+// GetAlerts gets the states of the alerting rules.
+// Without the alerting engine there are no alerts.
+func (mt *ToolUseCase) GetAlerts() []entity.Alert {
+	if mt.alerts == nil {
+		return make([]entity.Alert, 0)
+	}
+	return mt.alerts.Alerts()
+}
+
+// Subscribe creates a subscription to the metrics accepted by the tool that satisfy the filter.
+// The subscription must be cancelled by Unsubscribe.
+func (mt *ToolUseCase) Subscribe(filter entity.Filter) *Subscription {
+	return mt.broker.subscribe(filter)
+}
+
+// Unsubscribe cancels the subscription.
+func (mt *ToolUseCase) Unsubscribe(s *Subscription) {
+	mt.broker.unsubscribe(s)
+}
+
+// saveChanges writes the storage to the store file according to the store mode of the tool.
+func (mt *ToolUseCase) saveChanges() error {
 	if mt.asyncWriteFile {
 		mt.C <- struct{}{}
 	}
@@ -125,7 +279,7 @@ func (mt *ToolUseCase) StoreMetrics(ctx context.Context, metrics entity.Metrics)
 
 // GetMetrics gets a metrics from the tool.
 func (mt *ToolUseCase) GetMetrics(ctx context.Context, metrics entity.Metrics) (entity.Metrics, error) {
-	res, err := mt.repo.GetMetrics(ctx, metrics.ID)
+	res, err := mt.repo.GetMetrics(ctx, entity.NormalizeID(metrics.ID))
 	if err != nil {
 		if errors.Is(err, repo.ErrNotFound) {
 			return res, ErrNotFound
@@ -133,12 +287,254 @@ func (mt *ToolUseCase) GetMetrics(ctx context.Context, metrics entity.Metrics) (
 		return res, fmt.Errorf("error getting metrics: %w", err)
 	}
 
-	if mt.encryptionKey != "" && res.Hash == "" {
-		res.SignData("server", mt.encryptionKey)
+	if key, _ := mt.dataSignKey(); key != "" && res.Hash == "" {
+		res.SignData("server", key)
+	}
+	return res, nil
+}
+
+// GetSeveralMetrics gets several metrics from the tool at once.
+// It returns only the found metrics; a metrics with a type other than the requested one is not found.
+func (mt *ToolUseCase) GetSeveralMetrics(ctx context.Context, items []entity.Metrics) ([]entity.Metrics, error) {
+	names := make([]string, 0, len(items))
+	requested := make(map[requestedMetrics]struct{}, len(items))
+	for _, item := range items {
+		id := entity.NormalizeID(item.ID)
+		names = append(names, id)
+		requested[requestedMetrics{id: id, mType: item.MType}] = struct{}{}
+	}
+
+	found, err := mt.repo.GetSeveralMetrics(ctx, names)
+	if err != nil {
+		return nil, fmt.Errorf("error getting several metrics: %w", err)
+	}
+
+	key, _ := mt.dataSignKey()
+	res := make([]entity.Metrics, 0, len(found))
+	for _, m := range found {
+		_, anyType := requested[requestedMetrics{id: m.ID}]
+		_, sameType := requested[requestedMetrics{id: m.ID, mType: m.MType}]
+		if !anyType && !sameType {
+			continue
+		}
+		if key != "" && m.Hash == "" {
+			m.SignData("server", key)
+		}
+		res = append(res, m)
 	}
 	return res, nil
 }
 
+// requestedMetrics is the ID and the type of a requested metrics, the type is empty for any type.
+type requestedMetrics struct {
+	id    string
+	mType string
+}
+
+// ListMetrics gets the metrics satisfying the filter from the tool.
+func (mt *ToolUseCase) ListMetrics(ctx context.Context, filter entity.Filter) ([]entity.Metrics, error) {
+	res, err := mt.repo.ListMetrics(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("error listing metrics: %w", err)
+	}
+
+	if key, _ := mt.dataSignKey(); key != "" {
+		for i := range res {
+			if res[i].Hash == "" {
+				res[i].SignData("server", key)
+			}
+		}
+	}
+	return res, nil
+}
+
+// AggregateMetrics computes the sum, the average, the minimum, the maximum and the count
+// of the values of the metrics satisfying the filter. The sorting and the pagination of the filter are ignored.
+func (mt *ToolUseCase) AggregateMetrics(ctx context.Context, filter entity.Filter) (entity.Aggregate, error) {
+	filter.SortBy, filter.Desc, filter.Limit, filter.Offset = "", false, 0, 0
+
+	res, err := mt.repo.AggregateMetrics(ctx, filter)
+	if err != nil {
+		return entity.Aggregate{}, fmt.Errorf("error aggregating metrics: %w", err)
+	}
+
+	return res, nil
+}
+
+// DeleteMetrics deletes a metrics from the tool.
+// The metrics must have the same type as the stored one.
+// If the tool checks the data sign, the action must be signed at signedAt.
+func (mt *ToolUseCase) DeleteMetrics(ctx context.Context, metrics entity.Metrics, signedAt time.Time) error {
+	target, err := mt.getActionTarget(ctx, ActionDelete, metrics, signedAt)
+	if err != nil {
+		return err
+	}
+
+	if err = mt.repo.DeleteMetrics(ctx, target.ID); err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			return fmt.Errorf("delete metrics: %w", ErrNotFound)
+		}
+		return fmt.Errorf("error deleting metrics: %w", err)
+	}
+
+	return mt.saveChanges()
+}
+
+// ResetMetrics sets the value of a metrics in the tool to zero.
+// The metrics must have the same type as the stored one.
+// If the tool checks the data sign, the action must be signed at signedAt.
+func (mt *ToolUseCase) ResetMetrics(ctx context.Context, metrics entity.Metrics, signedAt time.Time) (entity.Metrics, error) {
+	res, err := mt.getActionTarget(ctx, ActionReset, metrics, signedAt)
+	if err != nil {
+		return res, err
+	}
+
+	switch res.MType {
+	case Gauge:
+		var value entity.Gauge
+		res.Value = &value
+	case Counter:
+		var delta entity.Counter
+		res.Delta = &delta
+	}
+
+	key, _ := mt.dataSignKey()
+	res.Hash = ""
+	res.SignData("server", key)
+
+	if err = mt.repo.StoreMetrics(ctx, res); err != nil {
+		return res, fmt.Errorf("error storing metrics: %w", err)
+	}
+
+	return res, mt.saveChanges()
+}
+
+// getActionTarget checks the sign of an action on a metrics and gets the stored metrics.
+func (mt *ToolUseCase) getActionTarget(
+	ctx context.Context,
+	action string,
+	metrics entity.Metrics,
+	signedAt time.Time,
+) (entity.Metrics, error) {
+	if metrics.MType != Gauge && metrics.MType != Counter {
+		return entity.Metrics{}, ErrNotImplemented
+	}
+
+	if err := mt.checkActionSign(action, metrics, signedAt); err != nil {
+		return entity.Metrics{}, err
+	}
+
+	res, err := mt.repo.GetMetrics(ctx, entity.NormalizeID(metrics.ID))
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			return res, ErrNotFound
+		}
+		return res, fmt.Errorf("error getting metrics: %w", err)
+	}
+
+	if res.MType != metrics.MType {
+		return res, fmt.Errorf("%s metrics %s: %w", metrics.MType, metrics.ID, ErrNotFound)
+	}
+
+	return res, nil
+}
+
+// checkActionSign checks the sign of an action on a metrics if the tool checks the data sign.
+// The action must be signed within the sign window, and its sign must not be used before,
+// so that a signed request cannot be replayed.
+func (mt *ToolUseCase) checkActionSign(action string, metrics entity.Metrics, signedAt time.Time) error {
+	key, check := mt.dataSignKey()
+	if !check {
+		return nil
+	}
+
+	now := time.Now()
+	if signedAt.Before(now.Add(-ActionSignWindow)) || signedAt.After(now.Add(ActionSignWindow)) {
+		return fmt.Errorf("%w: %s of %s is signed out of the time window", ErrDataSignNotEqual, action, metrics.ID)
+	}
+
+	if !metrics.CheckActionSign(action, key, signedAt) {
+		return ErrDataSignNotEqual
+	}
+
+	mt.signsMu.Lock()
+	defer mt.signsMu.Unlock()
+
+	if mt.usedSigns == nil {
+		mt.usedSigns = make(map[string]time.Time)
+	}
+	for sign, at := range mt.usedSigns {
+		// the expired signs are rejected by the time window
+		if at.Before(now.Add(-ActionSignWindow)) {
+			delete(mt.usedSigns, sign)
+		}
+	}
+
+	if _, ok := mt.usedSigns[metrics.Hash]; ok {
+		return fmt.Errorf("%w: %s of %s is replayed", ErrDataSignNotEqual, action, metrics.ID)
+	}
+	mt.usedSigns[metrics.Hash] = signedAt
+
+	return nil
+}
+
 func (mt *ToolUseCase) PingRepo(ctx context.Context) error {
 	return mt.repo.Ping(ctx)
 }
+
+// GetStaleMetrics gets the metrics that have not been updated for longer than the TTL of their type.
+// These metrics will be evicted by the next sweep.
+func (mt *ToolUseCase) GetStaleMetrics(ctx context.Context) ([]entity.Metrics, error) {
+	res := make([]entity.Metrics, 0)
+	now := time.Now()
+	for mType, ttl := range mt.ttls {
+		items, err := mt.repo.GetStaleMetrics(ctx, mType, now.Add(-ttl))
+		if err != nil {
+			return nil, fmt.Errorf("error getting stale metrics: %w", err)
+		}
+		res = append(res, items...)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ID < res[j].ID
+	})
+
+	return res, nil
+}
+
+// EvictStaleMetrics deletes the metrics that have not been updated for longer than the TTL of their type.
+// It returns the names of the evicted metrics.
+func (mt *ToolUseCase) EvictStaleMetrics(ctx context.Context) ([]string, error) {
+	res := make([]string, 0)
+	now := time.Now()
+	for mType, ttl := range mt.ttls {
+		names, err := mt.repo.DeleteStaleMetrics(ctx, mType, now.Add(-ttl))
+		if err != nil {
+			return res, fmt.Errorf("error deleting stale metrics: %w", err)
+		}
+		res = append(res, names...)
+	}
+
+	if len(res) == 0 {
+		return res, nil
+	}
+
+	sort.Strings(res)
+
+	return res, mt.saveChanges()
+}
+
+func (mt *ToolUseCase) sweepStaleMetrics() {
+	ticker := time.NewTicker(mt.sweepInterval)
+	for {
+		<-ticker.C
+		names, err := mt.EvictStaleMetrics(context.Background())
+		if err != nil {
+			mt.logger.Error(fmt.Sprintf("error while evicting stale metrics: %s", err))
+			continue
+		}
+		if len(names) > 0 {
+			mt.logger.Info(fmt.Sprintf("evicted stale metrics: %s", strings.Join(names, ", ")))
+		}
+	}
+}
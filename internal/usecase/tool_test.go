@@ -5,6 +5,7 @@ import (
 	"errors"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/mock"
@@ -125,6 +126,16 @@ func TestStoreMetrics(t *testing.T) {
 		require.NoError(t, err)
 	})
 
+	t.Run("gauge with labels", func(t *testing.T) {
+		tool, repoMock := metricsTool(t)
+		ctx := context.Background()
+		metricsGauge := entity.Metrics{ID: `id{host=h1, agent="a1"}`, MType: Gauge}
+		stored := entity.Metrics{ID: `id{agent="a1",host="h1"}`, MType: Gauge}
+		repoMock.On("StoreMetrics", ctx, stored).Return(nil)
+		err := tool.StoreMetrics(ctx, metricsGauge)
+		require.NoError(t, err)
+	})
+
 	t.Run("gauge with some error", func(t *testing.T) {
 		tool, repoMock := metricsTool(t)
 		ctx := context.Background()
@@ -199,3 +210,244 @@ func TestStoreMetrics(t *testing.T) {
 		require.Error(t, err)
 	})
 }
+
+func TestStaleMetrics(t *testing.T) {
+	t.Run("get stale metrics", func(t *testing.T) {
+		tool, repoMock := metricsTool(t)
+		tool.ttls = map[string]time.Duration{Gauge: time.Hour}
+		ctx := context.Background()
+		stale := []entity.Metrics{{ID: "b", MType: Gauge}, {ID: "a", MType: Gauge}}
+		repoMock.On("GetStaleMetrics", ctx, Gauge, mock.AnythingOfType("time.Time")).Return(stale, nil)
+		res, err := tool.GetStaleMetrics(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "a", res[0].ID)
+		require.Equal(t, "b", res[1].ID)
+	})
+
+	t.Run("evict stale metrics", func(t *testing.T) {
+		tool, repoMock := metricsTool(t)
+		tool.ttls = map[string]time.Duration{Counter: time.Hour}
+		tool.syncWriteFile = true
+		ctx := context.Background()
+		repoMock.On("DeleteStaleMetrics", ctx, Counter, mock.AnythingOfType("time.Time")).Return([]string{"PollCount"}, nil)
+		repoMock.On("StoreAll").Return(nil)
+		names, err := tool.EvictStaleMetrics(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []string{"PollCount"}, names)
+	})
+
+	t.Run("evict with error", func(t *testing.T) {
+		tool, repoMock := metricsTool(t)
+		tool.ttls = map[string]time.Duration{Counter: time.Hour}
+		ctx := context.Background()
+		repoMock.On("DeleteStaleMetrics", ctx, Counter, mock.AnythingOfType("time.Time")).Return(nil, errors.New("some error"))
+		_, err := tool.EvictStaleMetrics(ctx)
+		require.Error(t, err)
+	})
+}
+
+func TestDeleteMetrics(t *testing.T) {
+	t.Run("without error", func(t *testing.T) {
+		tool, repoMock := metricsTool(t)
+		ctx := context.Background()
+		metrics := entity.Metrics{ID: "id", MType: Gauge}
+		repoMock.On("GetMetrics", ctx, metrics.ID).Return(entity.Metrics{ID: "id", MType: Gauge}, nil)
+		repoMock.On("DeleteMetrics", ctx, metrics.ID).Return(nil)
+		err := tool.DeleteMetrics(ctx, metrics, time.Time{})
+		require.NoError(t, err)
+	})
+
+	t.Run("with another type", func(t *testing.T) {
+		tool, repoMock := metricsTool(t)
+		ctx := context.Background()
+		metrics := entity.Metrics{ID: "id", MType: Counter}
+		repoMock.On("GetMetrics", ctx, metrics.ID).Return(entity.Metrics{ID: "id", MType: Gauge}, nil)
+		err := tool.DeleteMetrics(ctx, metrics, time.Time{})
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("with wrong sign", func(t *testing.T) {
+		tool, _ := metricsTool(t)
+		tool.checkDataSign = true
+		tool.encryptionKey = "key"
+		metrics := entity.Metrics{ID: "id", MType: Gauge}
+		now := time.Now()
+		metrics.SignAction(ActionReset, "key", now)
+		err := tool.DeleteMetrics(context.Background(), metrics, now)
+		require.ErrorIs(t, err, ErrDataSignNotEqual)
+	})
+
+	t.Run("with expired sign", func(t *testing.T) {
+		tool, _ := metricsTool(t)
+		tool.checkDataSign = true
+		tool.encryptionKey = "key"
+		metrics := entity.Metrics{ID: "id", MType: Gauge}
+		signedAt := time.Now().Add(-2 * ActionSignWindow)
+		metrics.SignAction(ActionDelete, "key", signedAt)
+		err := tool.DeleteMetrics(context.Background(), metrics, signedAt)
+		require.ErrorIs(t, err, ErrDataSignNotEqual)
+	})
+
+	t.Run("with replayed sign", func(t *testing.T) {
+		tool, repoMock := metricsTool(t)
+		tool.checkDataSign = true
+		tool.encryptionKey = "key"
+		ctx := context.Background()
+		metrics := entity.Metrics{ID: "id", MType: Gauge}
+		now := time.Now()
+		metrics.SignAction(ActionDelete, "key", now)
+		repoMock.On("GetMetrics", ctx, metrics.ID).Return(entity.Metrics{ID: "id", MType: Gauge}, nil)
+		repoMock.On("DeleteMetrics", ctx, metrics.ID).Return(nil)
+		require.NoError(t, tool.DeleteMetrics(ctx, metrics, now))
+
+		err := tool.DeleteMetrics(ctx, metrics, now)
+		require.ErrorIs(t, err, ErrDataSignNotEqual)
+	})
+
+	t.Run("with not implemented type", func(t *testing.T) {
+		tool, _ := metricsTool(t)
+		err := tool.DeleteMetrics(context.Background(), entity.Metrics{ID: "id", MType: "some type"}, time.Time{})
+		require.ErrorIs(t, err, ErrNotImplemented)
+	})
+}
+
+func TestResetMetrics(t *testing.T) {
+	t.Run("counter with sign", func(t *testing.T) {
+		tool, repoMock := metricsTool(t)
+		tool.checkDataSign = true
+		tool.encryptionKey = "key"
+		ctx := context.Background()
+
+		metrics := entity.Metrics{ID: "id", MType: Counter}
+		now := time.Now()
+		metrics.SignAction(ActionReset, "key", now)
+
+		var old entity.Counter = 10
+		repoMock.On("GetMetrics", ctx, metrics.ID).Return(entity.Metrics{ID: "id", MType: Counter, Delta: &old}, nil)
+		repoMock.On("StoreMetrics", ctx, mock.AnythingOfType("entity.Metrics")).Return(nil)
+
+		res, err := tool.ResetMetrics(ctx, metrics, now)
+		require.NoError(t, err)
+		require.Equal(t, entity.Counter(0), *res.Delta)
+		require.True(t, res.CheckDataSign("key"))
+	})
+
+	t.Run("gauge with error not found", func(t *testing.T) {
+		tool, repoMock := metricsTool(t)
+		ctx := context.Background()
+		metrics := entity.Metrics{ID: "id", MType: Gauge}
+		repoMock.On("GetMetrics", ctx, metrics.ID).Return(entity.Metrics{}, repo.ErrNotFound)
+		_, err := tool.ResetMetrics(ctx, metrics, time.Time{})
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestListMetrics(t *testing.T) {
+	t.Run("with encryption key", func(t *testing.T) {
+		tool, repoMock := metricsTool(t)
+		tool.encryptionKey = "key"
+		ctx := context.Background()
+		filter := entity.Filter{Type: Gauge}
+		var value entity.Gauge = 1
+		repoMock.On("ListMetrics", ctx, filter).Return([]entity.Metrics{{ID: "id", MType: Gauge, Value: &value}}, nil)
+		res, err := tool.ListMetrics(ctx, filter)
+		require.NoError(t, err)
+		require.Len(t, res, 1)
+		require.True(t, res[0].CheckDataSign("key"))
+	})
+
+	t.Run("with error", func(t *testing.T) {
+		tool, repoMock := metricsTool(t)
+		ctx := context.Background()
+		repoMock.On("ListMetrics", ctx, entity.Filter{}).Return(nil, errors.New("some error"))
+		_, err := tool.ListMetrics(ctx, entity.Filter{})
+		require.Error(t, err)
+	})
+}
+
+func TestAggregateMetrics(t *testing.T) {
+	t.Run("without pagination", func(t *testing.T) {
+		tool, repoMock := metricsTool(t)
+		ctx := context.Background()
+		sum := 10.0
+		repoMock.On("AggregateMetrics", ctx, entity.Filter{Name: "HeapAlloc"}).
+			Return(entity.Aggregate{Count: 2, Sum: &sum}, nil)
+		res, err := tool.AggregateMetrics(ctx, entity.Filter{Name: "HeapAlloc", SortBy: entity.SortByType, Limit: 1})
+		require.NoError(t, err)
+		require.Equal(t, int64(2), res.Count)
+		require.Equal(t, sum, *res.Sum)
+	})
+
+	t.Run("with error", func(t *testing.T) {
+		tool, repoMock := metricsTool(t)
+		ctx := context.Background()
+		repoMock.On("AggregateMetrics", ctx, entity.Filter{}).Return(entity.Aggregate{}, errors.New("some error"))
+		_, err := tool.AggregateMetrics(ctx, entity.Filter{})
+		require.Error(t, err)
+	})
+}
+
+func TestGetSeveralMetrics(t *testing.T) {
+	t.Run("with another type", func(t *testing.T) {
+		tool, repoMock := metricsTool(t)
+		ctx := context.Background()
+		var value entity.Gauge = 1
+		requested := []entity.Metrics{{ID: "a", MType: Gauge}, {ID: "b", MType: Counter}}
+		repoMock.On("GetSeveralMetrics", ctx, []string{"a", "b"}).Return([]entity.Metrics{
+			{ID: "a", MType: Gauge, Value: &value},
+			{ID: "b", MType: Gauge, Value: &value},
+		}, nil)
+		res, err := tool.GetSeveralMetrics(ctx, requested)
+		require.NoError(t, err)
+		require.Len(t, res, 1)
+		require.Equal(t, "a", res[0].ID)
+	})
+
+	t.Run("with the same ID of two types", func(t *testing.T) {
+		tool, repoMock := metricsTool(t)
+		ctx := context.Background()
+		var value entity.Gauge = 1
+		requested := []entity.Metrics{{ID: "a", MType: Gauge}, {ID: "a", MType: Counter}}
+		repoMock.On("GetSeveralMetrics", ctx, []string{"a", "a"}).Return([]entity.Metrics{
+			{ID: "a", MType: Gauge, Value: &value},
+		}, nil)
+		res, err := tool.GetSeveralMetrics(ctx, requested)
+		require.NoError(t, err)
+		require.Len(t, res, 1)
+		require.Equal(t, Gauge, res[0].MType)
+	})
+
+	t.Run("with error", func(t *testing.T) {
+		tool, repoMock := metricsTool(t)
+		ctx := context.Background()
+		repoMock.On("GetSeveralMetrics", ctx, []string{"a"}).Return(nil, errors.New("some error"))
+		_, err := tool.GetSeveralMetrics(ctx, []entity.Metrics{{ID: "a"}})
+		require.Error(t, err)
+	})
+}
+
+func TestSetDataSignKey(t *testing.T) {
+	tool, repoMock := metricsTool(t)
+	ctx := context.Background()
+
+	value := entity.Gauge(1)
+	metrics := entity.Metrics{ID: "id", MType: Gauge, Value: &value}
+	metrics.SignData("agent", "old")
+
+	tool.SetDataSignKey("new")
+	require.ErrorIs(t, tool.StoreMetrics(ctx, metrics), ErrDataSignNotEqual)
+
+	// the data is not checked without the key
+	tool.SetDataSignKey("")
+	repoMock.On("StoreMetrics", ctx, metrics).Return(nil)
+	require.NoError(t, tool.StoreMetrics(ctx, metrics))
+}
+
+func TestSetStoreInterval(t *testing.T) {
+	tool, _ := metricsTool(t)
+	require.ErrorIs(t, tool.SetStoreInterval(time.Second), ErrStoreModeChanged)
+
+	periodic := NewMetricsTool(nil, testLogger(), WriteFileWithDuration(time.Hour))
+	require.NoError(t, periodic.SetStoreInterval(time.Minute))
+	require.ErrorIs(t, periodic.SetStoreInterval(0), ErrStoreModeChanged)
+}
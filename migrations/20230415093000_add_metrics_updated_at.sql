@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+
+ALTER TABLE public.metrics
+    ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+CREATE INDEX IF NOT EXISTS metrics_mtype_updated_at_idx ON public.metrics (mtype, updated_at);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP INDEX IF EXISTS metrics_mtype_updated_at_idx;
+ALTER TABLE public.metrics DROP COLUMN IF EXISTS updated_at;
+-- +goose StatementEnd
@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+
+CREATE TABLE IF NOT EXISTS public.metrics_samples(
+    name VARCHAR(255) NOT NULL,
+    mtype metric_types NOT NULL,
+    value DOUBLE PRECISION NOT NULL,
+    delta DOUBLE PRECISION NOT NULL DEFAULT 0,
+    at TIMESTAMPTZ NOT NULL
+    );
+
+CREATE INDEX IF NOT EXISTS metrics_samples_name_at_idx ON public.metrics_samples (name, at);
+CREATE INDEX IF NOT EXISTS metrics_samples_at_idx ON public.metrics_samples (at);
+
+CREATE TABLE IF NOT EXISTS public.metrics_rollups(
+    name VARCHAR(255) NOT NULL,
+    resolution VARCHAR(8) NOT NULL,
+    bucket TIMESTAMPTZ NOT NULL,
+    count BIGINT NOT NULL,
+    min DOUBLE PRECISION NOT NULL,
+    max DOUBLE PRECISION NOT NULL,
+    sum DOUBLE PRECISION NOT NULL,
+    last DOUBLE PRECISION NOT NULL,
+    CONSTRAINT metrics_rollups_pkey PRIMARY KEY (resolution, name, bucket)
+    );
+
+CREATE INDEX IF NOT EXISTS metrics_rollups_resolution_bucket_idx ON public.metrics_rollups (resolution, bucket);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP TABLE public.metrics_rollups;
+DROP TABLE public.metrics_samples;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+
+CREATE TABLE IF NOT EXISTS public.agents(
+    name VARCHAR(255) NOT NULL,
+    version VARCHAR(255) NOT NULL DEFAULT '',
+    host VARCHAR(255) NOT NULL DEFAULT '',
+    address VARCHAR(255) NOT NULL DEFAULT '',
+    last_seen TIMESTAMPTZ NOT NULL,
+    metrics BIGINT NOT NULL DEFAULT 0,
+    CONSTRAINT agents_pkey PRIMARY KEY (name)
+    );
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+DROP TABLE public.agents;
+-- +goose StatementEnd
@@ -25,27 +25,32 @@ type Logger struct {
 // New creates an object of Logger.
 // The logger allows to specify the level and the output mode.
 func New(level string, output io.Writer) *Logger {
-	var l logrus.Level
+	logger := logrus.New()
+	logger.SetLevel(parseLevel(level))
+	logger.SetOutput(output)
+
+	return &Logger{
+		logger: logger,
+	}
+}
+
+// SetLevel changes the level of the logger, e.g. on the config reload.
+func (l *Logger) SetLevel(level string) {
+	l.logger.SetLevel(parseLevel(level))
+}
 
+func parseLevel(level string) logrus.Level {
 	switch strings.ToLower(level) {
 	case "debug":
-		l = logrus.DebugLevel
+		return logrus.DebugLevel
 	case "warn":
-		l = logrus.WarnLevel
+		return logrus.WarnLevel
 	case "error":
-		l = logrus.ErrorLevel
+		return logrus.ErrorLevel
 	case "info":
-		l = logrus.InfoLevel
+		return logrus.InfoLevel
 	default:
-		l = logrus.InfoLevel
-	}
-
-	logger := logrus.New()
-	logger.SetLevel(l)
-	logger.SetOutput(output)
-
-	return &Logger{
-		logger: logger,
+		return logrus.InfoLevel
 	}
 }
 
@@ -66,4 +66,16 @@ func TestLogger(t *testing.T) {
 		logger.Info("info message")
 		require.True(t, strings.HasSuffix(buf.String(), "info message\"\n"))
 	})
+
+	t.Run("set level", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New("error", &buf)
+
+		logger.Info("info message")
+		require.Empty(t, buf.String())
+
+		logger.SetLevel("info")
+		logger.Info("info message")
+		require.True(t, strings.HasSuffix(buf.String(), "info message\"\n"))
+	})
 }